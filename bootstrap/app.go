@@ -0,0 +1,106 @@
+package bootstrap
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"github.com/DiansSopandi/goride_be/db"
+	"github.com/DiansSopandi/goride_be/dto"
+	handler "github.com/DiansSopandi/goride_be/http/handler/v1"
+	"github.com/DiansSopandi/goride_be/middlewares"
+	"github.com/DiansSopandi/goride_be/models"
+	"github.com/DiansSopandi/goride_be/pkg"
+	"github.com/DiansSopandi/goride_be/pkg/events"
+	"github.com/DiansSopandi/goride_be/repository"
+	service "github.com/DiansSopandi/goride_be/services"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	defaultQueryTimeout  = 5 * time.Second
+	settingsAuditTimeout = 5 * time.Second
+)
+
+// App is the dependency container of the server. It owns the connections and builds the services,
+// middlewares and handlers on top of them once, so nothing below reaches for a global pool.
+type App struct {
+	Config      pkg.Config
+	DB          *sql.DB
+	Replica     *sql.DB // nil without database.replica_dsn
+	Redis       *redis.Client
+	Services    *service.Services
+	Middlewares *middlewares.Middlewares
+	Handlers    *handler.Handlers
+	Events      *events.Bus
+	OutboxRelay *service.OutboxRelay // nil with outbox.disabled
+
+	stopPoolStats func()
+}
+
+// NewApp connects Postgres and Redis and wires everything else on them with cfg, the services,
+// middlewares and handlers read the configuration of the container instead of pkg.Cfg.
+func NewApp(cfg pkg.Config) *App {
+	database := db.InitDatabase()
+	replica := db.ConnectReplica()
+	repository.SetQueryTimeout(pkg.ConfigDuration("database.query_timeout", cfg.Database.QueryTimeout, defaultQueryTimeout))
+
+	pools := map[string]*sql.DB{"primary": database}
+	var reader repository.DBTX
+	if replica != nil {
+		pools["replica"] = replica
+		reader = replica
+	}
+
+	services := service.NewServices(database, reader)
+	mw := middlewares.NewMiddlewares(cfg, database, services)
+
+	bus := events.NewBus()
+	service.NewNotificationService().Subscribe(bus)
+
+	return &App{
+		Config:        cfg,
+		DB:            database,
+		Replica:       replica,
+		Redis:         pkg.GetRedisClient(),
+		Services:      services,
+		Middlewares:   mw,
+		Handlers:      handler.NewHandlers(cfg, database, replica, services, mw),
+		Events:        bus,
+		OutboxRelay:   newOutboxRelay(cfg.Outbox, database, pkg.GetRedisClient(), bus),
+		stopPoolStats: db.LogPoolStats(pkg.ConfigDuration("database.pool_stats_interval", cfg.Database.PoolStatsInterval, 0), pools),
+	}
+}
+
+// RecordSettingsChange writes a reload of the runtime settings to the audit trail.
+func (a *App) RecordSettingsChange(before, after pkg.RuntimeSettings) {
+	ctx, cancel := context.WithTimeout(context.Background(), settingsAuditTimeout)
+	defer cancel()
+
+	actor := dto.AuditActor{Type: models.AuditActorSystem}
+	if err := a.Services.Audit.Record(ctx, actor, "settings.reload", "settings", "runtime", before, after); err != nil {
+		slog.Error("failed to record the runtime settings reload", "error", err)
+	}
+}
+
+// CloseDatabase stops the pool stats and closes the replica, then the primary pool.
+func (a *App) CloseDatabase() error {
+	a.stopPoolStats()
+	if a.Replica != nil {
+		if err := a.Replica.Close(); err != nil {
+			slog.Error("failed to close the read replica", "error", err)
+		}
+	}
+	return a.DB.Close()
+}
+
+// Close releases the connections of a container that never served, e.g. the one of a command.
+func (a *App) Close() {
+	if err := a.Redis.Close(); err != nil {
+		slog.Error("failed to close redis", "error", err)
+	}
+	if err := a.CloseDatabase(); err != nil {
+		slog.Error("failed to close the database", "error", err)
+	}
+}
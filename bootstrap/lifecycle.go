@@ -0,0 +1,122 @@
+package bootstrap
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/DiansSopandi/goride_be/pkg"
+	"github.com/DiansSopandi/goride_be/pkg/health"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	ExitOK          = 0
+	ExitError       = 1
+	ExitForced      = 2
+	defaultShutdown = 30 * time.Second
+)
+
+type shutdownStep struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// Lifecycle serves the app until SIGINT or SIGTERM, then shuts down in order: readiness flips to
+// not ready, the listener stops and in-flight requests drain, then the steps registered with
+// OnShutdown run in registration order and the logs are flushed last.
+type Lifecycle struct {
+	steps   []shutdownStep
+	delay   time.Duration
+	timeout time.Duration
+}
+
+func NewLifecycle(cfg pkg.ApplicationConfig) *Lifecycle {
+	return &Lifecycle{
+		delay:   pkg.ConfigDuration("application.shutdown_delay", cfg.ShutdownDelay, 0),
+		timeout: pkg.ConfigDuration("application.shutdown_timeout", cfg.ShutdownTimeout, defaultShutdown),
+	}
+}
+
+// OnShutdown registers a step that runs after the requests drained, e.g. closing a connection pool.
+func (l *Lifecycle) OnShutdown(name string, fn func(ctx context.Context) error) {
+	l.steps = append(l.steps, shutdownStep{name: name, fn: fn})
+}
+
+// Run listens on addr and blocks until the server stopped, it returns the process exit code.
+// A second signal during shutdown exits right away with ExitForced.
+func (l *Lifecycle) Run(app *fiber.App, addr string) int {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	listenErr := make(chan error, 1)
+	go func() {
+		listenErr <- app.Listen(addr)
+	}()
+
+	code := ExitOK
+	select {
+	case err := <-listenErr:
+		// the listener never came up or died, nothing left to drain
+		slog.Error("server stopped unexpectedly", "error", err)
+		health.SetShuttingDown()
+		l.runSteps()
+		return l.finish(ExitError)
+	case sig := <-signals:
+		slog.Info("shutdown signal received", "signal", sig.String(), "delay", l.delay, "timeout", l.timeout)
+	}
+
+	go func() {
+		sig := <-signals
+		slog.Error("second signal received, forcing exit", "signal", sig.String())
+		pkg.CloseLogger()
+		os.Exit(ExitForced)
+	}()
+
+	health.SetShuttingDown()
+	if l.delay > 0 {
+		time.Sleep(l.delay)
+	}
+
+	if err := app.ShutdownWithTimeout(l.timeout); err != nil {
+		slog.Error("in-flight requests did not finish in time", "timeout", l.timeout, "error", err)
+		code = ExitError
+	}
+	if err := <-listenErr; err != nil {
+		slog.Error("server stopped with error", "error", err)
+		code = ExitError
+	}
+
+	if l.runSteps() != ExitOK {
+		code = ExitError
+	}
+	return l.finish(code)
+}
+
+func (l *Lifecycle) runSteps() int {
+	ctx, cancel := context.WithTimeout(context.Background(), l.timeout)
+	defer cancel()
+
+	code := ExitOK
+	for _, step := range l.steps {
+		start := time.Now()
+		if err := step.fn(ctx); err != nil {
+			slog.Error("shutdown step failed", "step", step.name, "error", err)
+			code = ExitError
+			continue
+		}
+		slog.Info("shutdown step done", "step", step.name, "duration", time.Since(start))
+	}
+	return code
+}
+
+// finish flushes the log file, the last thing to go so every step above is logged.
+func (l *Lifecycle) finish(code int) int {
+	slog.Info("server stopped", "exit_code", code)
+	pkg.CloseLogger()
+	return code
+}
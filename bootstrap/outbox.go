@@ -0,0 +1,61 @@
+package bootstrap
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/DiansSopandi/goride_be/pkg"
+	"github.com/DiansSopandi/goride_be/pkg/events"
+	"github.com/DiansSopandi/goride_be/repository"
+	service "github.com/DiansSopandi/goride_be/services"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	defaultOutboxPollInterval    = time.Second
+	defaultOutboxBatchSize       = 100
+	defaultOutboxMaxAttempts     = 10
+	defaultOutboxRetryBackoff    = 5 * time.Second
+	defaultOutboxMaxRetryBackoff = 10 * time.Minute
+	defaultOutboxRetention       = 7 * 24 * time.Hour
+	defaultOutboxStream          = "goride:events"
+	// outboxLease is far longer than publishing a batch to the subscribers and redis
+	outboxLease = time.Minute
+)
+
+// newOutboxRelay publishes the outbox events to bus and to the outbox.stream redis stream, nil with outbox.disabled.
+func newOutboxRelay(cfg pkg.OutboxConfig, database *sql.DB, rdb *redis.Client, bus *events.Bus) *service.OutboxRelay {
+	if cfg.Disabled {
+		return nil
+	}
+
+	relayCfg := service.OutboxRelayConfig{
+		PollInterval:    pkg.ConfigDuration("outbox.poll_interval", cfg.PollInterval, defaultOutboxPollInterval),
+		BatchSize:       cfg.BatchSize,
+		MaxAttempts:     cfg.MaxAttempts,
+		RetryBackoff:    pkg.ConfigDuration("outbox.retry_backoff", cfg.RetryBackoff, defaultOutboxRetryBackoff),
+		MaxRetryBackoff: pkg.ConfigDuration("outbox.max_retry_backoff", cfg.MaxRetryBackoff, defaultOutboxMaxRetryBackoff),
+		Lease:           outboxLease,
+		Retention:       pkg.ConfigDuration("outbox.retention", cfg.Retention, defaultOutboxRetention),
+	}
+	if relayCfg.PollInterval <= 0 {
+		relayCfg.PollInterval = defaultOutboxPollInterval
+	}
+	if relayCfg.BatchSize <= 0 {
+		relayCfg.BatchSize = defaultOutboxBatchSize
+	}
+	if relayCfg.MaxAttempts <= 0 {
+		relayCfg.MaxAttempts = defaultOutboxMaxAttempts
+	}
+
+	stream := cfg.Stream
+	if stream == "" {
+		stream = defaultOutboxStream
+	}
+
+	// the names are stored with the deliveries, keep them when changing the publishers
+	return service.NewOutboxRelay(repository.NewOutboxRepository(database, nil), relayCfg, map[string]events.Publisher{
+		"subscribers": bus,
+		"stream":      events.NewStream(rdb, stream, int64(cfg.StreamMaxLen)),
+	})
+}
@@ -1,85 +1,120 @@
 package bootstrap
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
-	"os/signal"
-	"strings"
-	"syscall"
 	"time"
 
-	"github.com/DiansSopandi/goride_be/db"
 	"github.com/DiansSopandi/goride_be/docs"
 	"github.com/DiansSopandi/goride_be/middlewares"
 	"github.com/DiansSopandi/goride_be/pkg"
 	"github.com/DiansSopandi/goride_be/routes"
 	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/swagger"
-	"golang.org/x/oauth2"
 )
 
-var (
-	oauthConfig     *oauth2.Config
-	appJWTSecret    []byte
-	frontendURL     string
-	appJWTExpiresIn time.Duration
-)
+const defaultRequestTimeout = 30 * time.Second
+
+// ServerInitialize serves the API until the process is stopped, src is watched for runtime settings changes.
+func ServerInitialize(src pkg.ConfigSource) {
+	// tracer before the database, so the instrumented driver picks up the provider
+	pkg.InitTracer()
+	container := NewApp(pkg.Cfg)
+
+	// Setup graceful shutdown, steps run in this order after the in-flight requests drained
+	lifecycle := NewLifecycle(container.Config.Application)
+	if stopWatch, err := pkg.WatchConfig(src, container.Config, container.RecordSettingsChange); err != nil {
+		slog.Warn("runtime settings reload disabled", "error", err)
+	} else {
+		lifecycle.OnShutdown("config watcher", func(context.Context) error {
+			stopWatch()
+			return nil
+		})
+	}
+	lifecycle.OnShutdown("background tasks", middlewares.WaitBackgroundTasks)
+	if container.OutboxRelay != nil {
+		// publishes while the requests drain, stopped before redis and postgres close
+		container.OutboxRelay.Start()
+		lifecycle.OnShutdown("outbox relay", container.OutboxRelay.Stop)
+	}
+	lifecycle.OnShutdown("tracer", func(context.Context) error {
+		pkg.ShutdownTracer()
+		return nil
+	})
+	lifecycle.OnShutdown("redis", func(context.Context) error {
+		return container.Redis.Close()
+	})
+	lifecycle.OnShutdown("postgres", func(context.Context) error {
+		slog.Info("🔌 closing database connection...")
+		return container.CloseDatabase()
+	})
+
+	app := newServer(container)
+
+	// port := pkg.GetEnv("APP_PORT")
+	port := container.Config.Application.AppPort
+
+	slog.Info("server starting", "port", port, "database_connected", container.DB != nil)
+
+	os.Exit(lifecycle.Run(app, fmt.Sprintf(":%d", port)))
+}
 
-func ServerInitialize() {
-	database := db.InitDatabase()
-	// defer database.Close()
-
-	// Setup graceful shutdown
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
-
-	go func() {
-		<-c
-		fmt.Println("\n🔌 Closing database connection...")
-		db.CloseDB()
-		pkg.CloseRedis()
-		os.Exit(0)
-	}()
-
-	expires := pkg.Cfg.Application.AppJWTAccessExpiresIn
-	if expires <= 0 {
-		expires = 3600
+// serverConfig is the fiber configuration of the api. Without enable_trusted_proxy_check the proxy header
+// is ignored and c.IP() is the peer address, a header any client can set must never choose the ip that
+// the rate limits and the audit trail key on.
+func serverConfig(cfg pkg.ApplicationConfig, mw *middlewares.Middlewares) fiber.Config {
+	config := fiber.Config{
+		ErrorHandler: mw.ErrorHandler,
+	}
+	if cfg.EnableTrustedProxyCheck {
+		config.EnableTrustedProxyCheck = true
+		config.TrustedProxies = cfg.TrustedProxies
+		config.ProxyHeader = cfg.ProxyHeader
+		if config.ProxyHeader == "" {
+			config.ProxyHeader = fiber.HeaderXForwardedFor
+		}
+		// first valid ip of a forwarded list instead of the raw header
+		config.EnableIPValidation = true
 	}
-	appJWTExpiresIn = time.Duration(expires) * time.Second
+	return config
+}
 
+// newServer builds the fiber app of the container with the global middlewares and the routes.
+func newServer(container *App) *fiber.App {
 	// global error handler
-	app := fiber.New(fiber.Config{
-		ErrorHandler: middlewares.ErrorHandler,
-	})
+	cfg := container.Config.Application
+	app := fiber.New(serverConfig(cfg, container.Middlewares))
+
+	// request id first, so every log line and error response of the request carries it
+	app.Use(middlewares.RequestID)
+	app.Use(middlewares.Tracing)
+	app.Use(middlewares.HTTPMetrics)
+	// inside tracing and metrics, so they record the 503/504 of a timed out request
+	app.Use(middlewares.RequestTimeout(pkg.ConfigDuration("application.request_timeout", cfg.RequestTimeout, defaultRequestTimeout)))
 
 	// global middleware panic handler
 	app.Use(middlewares.GlobalRecoveryMiddleware)
 
+	// before the guard, preflight requests carry no token and are answered here
+	app.Use(middlewares.CORS())
+
 	// global guard JWT authentication middleware
-	app.Use(middlewares.JwtAuthGuard)
-
-	allowed := strings.Split(pkg.Cfg.Application.CorsOrigins, ",")
-	app.Use(cors.New(cors.Config{
-		// AllowOrigins: "http://example.com, http://localhost:3000",
-		AllowOrigins:     strings.Join(allowed, ","),
-		AllowCredentials: true,
-		AllowHeaders:     "*",
-		AllowMethods:     "GET,POST,PUT,PATCH,DELETE",
-	}))
-
-	// middlewares.InitRateLimiter()
-	pkg.InitRedis()
-	// apply global rate limit middleware all routes
-	// duration := time.Minute
-	// app.Use(middlewares.RateLimitMiddleware(&pkg.Cfg.Application.DefaultMaxRequestPerMinute, &duration))
+	app.Use(container.Middlewares.JwtAuthGuard)
+
+	// after the guard, so the policies know the user or API key of the request
+	limiter := middlewares.NewRateLimiter()
+	// per API key rate limit, JWT requests are only limited per route
+	app.Use(limiter.ApiKeyRateLimitMiddleware())
+	// rate_limit.policies: per ip, user, API key, route group or global
+	app.Use(limiter.Policies())
 
 	docs.SwaggerInfo.Title = "Swagger Example API"
 	docs.SwaggerInfo.Description = "This is a sample server for Go Ride API"
 	docs.SwaggerInfo.Version = "1.0.0"
 	// docs.SwaggerInfo.Host = pkg.GetEnv("APP_URL")
-	docs.SwaggerInfo.Host = pkg.Cfg.Application.AppUrl
+	docs.SwaggerInfo.Host = cfg.AppUrl
 	docs.SwaggerInfo.BasePath = "/"
 	docs.SwaggerInfo.Schemes = []string{"http", "https"}
 
@@ -93,15 +128,7 @@ func ServerInitialize() {
 
 	// Set up API root routes
 	// app.Get("/", handlers.RootHandler)
-	routes.SetupRoutes(app)
-
-	// port := pkg.GetEnv("APP_PORT")
-	port := pkg.Cfg.Application.AppPort
+	routes.SetupRoutes(app, container.Config, container.Handlers, container.Middlewares)
 
-	log.Println("Server started successfully on port:", port)
-	log.Printf("📊 Database connection status: %v", database != nil)
-
-	if err := app.Listen(fmt.Sprintf(":%d", port)); err != nil {
-		log.Fatalf("Error starting server: %v", err)
-	}
+	return app
 }
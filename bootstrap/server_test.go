@@ -0,0 +1,87 @@
+package bootstrap
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	handler "github.com/DiansSopandi/goride_be/http/handler/v1"
+	"github.com/DiansSopandi/goride_be/internal/redistest"
+	"github.com/DiansSopandi/goride_be/middlewares"
+	"github.com/DiansSopandi/goride_be/pkg"
+	service "github.com/DiansSopandi/goride_be/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestPreflightOnProtectedRoute(t *testing.T) {
+	pkg.Cfg.Application.CorsOrigins = "https://app.example.com"
+	redistest.Start(t)
+
+	var cfg pkg.Config
+	cfg.Application.AppPath = "/v1"
+	services := &service.Services{}
+	mw := middlewares.NewMiddlewares(cfg, nil, services)
+	app := newServer(&App{
+		Config:      cfg,
+		Services:    services,
+		Middlewares: mw,
+		Handlers:    handler.NewHandlers(cfg, nil, nil, services, mw),
+	})
+
+	req := httptest.NewRequest(fiber.MethodOptions, "/v1/me/sessions", nil)
+	req.Header.Set(fiber.HeaderOrigin, "https://app.example.com")
+	req.Header.Set(fiber.HeaderAccessControlRequestMethod, fiber.MethodDelete)
+	res, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.StatusCode != fiber.StatusNoContent {
+		t.Errorf("got status %d, want %d", res.StatusCode, fiber.StatusNoContent)
+	}
+	if got := res.Header.Get(fiber.HeaderAccessControlAllowOrigin); got != "https://app.example.com" {
+		t.Errorf("got %s %q, want the origin", fiber.HeaderAccessControlAllowOrigin, got)
+	}
+}
+
+func TestRateLimitIgnoresForwardedForOfUntrustedPeers(t *testing.T) {
+	tests := []struct {
+		name        string
+		trusted     []string
+		wantLimited bool
+	}{
+		// app.Test connects from 0.0.0.0
+		{name: "untrusted peer", trusted: []string{"10.0.0.1"}, wantLimited: true},
+		{name: "trusted proxy", trusted: []string{"0.0.0.0"}, wantLimited: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pkg.SetRuntime(pkg.RuntimeSettings{RateLimitPolicies: []pkg.RateLimitPolicy{
+				{Name: "login " + tt.name, Key: "ip", Requests: 2, Period: "1m", Routes: []string{"/v1/auth/login"}},
+			}})
+			t.Cleanup(func() { pkg.SetRuntime(pkg.RuntimeSettings{}) })
+
+			cfg := pkg.ApplicationConfig{EnableTrustedProxyCheck: true, TrustedProxies: tt.trusted}
+			app := fiber.New(serverConfig(cfg, middlewares.NewMiddlewares(pkg.Config{}, nil, &service.Services{})))
+			app.Use((&middlewares.RateLimiter{}).Policies())
+			app.Post("/v1/auth/login", func(c *fiber.Ctx) error {
+				return c.SendStatus(fiber.StatusNoContent)
+			})
+
+			limited := false
+			for _, ip := range []string{"203.0.113.1", "203.0.113.2", "203.0.113.3"} {
+				req := httptest.NewRequest(fiber.MethodPost, "/v1/auth/login", nil)
+				req.Header.Set(fiber.HeaderXForwardedFor, ip)
+				res, err := app.Test(req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				limited = limited || res.StatusCode == fiber.StatusTooManyRequests
+			}
+
+			if limited != tt.wantLimited {
+				t.Errorf("limited %v, want %v", limited, tt.wantLimited)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/DiansSopandi/goride_be/pkg"
+	"github.com/pelletier/go-toml/v2"
+	"github.com/spf13/cobra"
+)
+
+var printFormat string
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the effective configuration",
+	Long:  `Inspect the configuration resulting from the config file, the profile file, the ` + pkg.EnvPrefix + `_* environment variables and the --set flags.`,
+	// The configuration is loaded by the subcommands, an invalid one must be reported instead of stopping here.
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {},
+}
+
+var configPrintCmd = &cobra.Command{
+	Use:   "print",
+	Short: "Print the effective configuration with the secrets redacted",
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := pkg.LoadConfig(configSource())
+		var cfgErr *pkg.ConfigError
+		if err != nil && !stderrors.As(err, &cfgErr) {
+			log.Fatalf("Error loading config, %v", err)
+		}
+
+		var out []byte
+		var marshalErr error
+		switch printFormat {
+		case "toml":
+			out, marshalErr = toml.Marshal(cfg.Redacted())
+		case "json":
+			out, marshalErr = json.MarshalIndent(cfg.Redacted(), "", "  ")
+		default:
+			log.Fatalf("--format must be toml or json")
+		}
+		if marshalErr != nil {
+			log.Fatalf("Error printing config, %v", marshalErr)
+		}
+		fmt.Println(string(out))
+
+		if cfgErr != nil {
+			fmt.Fprintln(os.Stderr, cfgErr)
+			os.Exit(1)
+		}
+	},
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Report every missing or invalid setting",
+	Run: func(cmd *cobra.Command, args []string) {
+		if _, err := pkg.LoadConfig(configSource()); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Println("configuration is valid")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configPrintCmd, configValidateCmd)
+
+	configPrintCmd.Flags().StringVar(&printFormat, "format", "toml", "toml or json")
+}
@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/DiansSopandi/goride_be/pkg"
+	"github.com/DiansSopandi/goride_be/pkg/jwks"
+	"github.com/spf13/cobra"
+)
+
+var keysDir string
+var keyAlg string
+var keepKeys int
+
+var jwtKeysCmd = &cobra.Command{
+	Use:   "jwt-keys",
+	Short: "Manage the JWT signing keys",
+	Long:  `Generate, rotate, list and prune the RS256/EdDSA keys used to sign access tokens. Defaults come from the [jwt] section of the configuration file.`,
+}
+
+var jwtKeysGenerateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "Generate a new key without activating it",
+	Long:  `Generate a new key without activating it. Publish it on the JWKS endpoint first, then activate it with rotate --kid.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		key, err := jwks.GenerateKey(jwtKeysDir(), jwtKeysAlg())
+		if err != nil {
+			log.Fatalf("Error generating key, %v", err)
+		}
+		fmt.Printf("generated %s key %s\n", key.Algorithm, key.ID)
+	},
+}
+
+var rotateKid string
+var jwtKeysRotateCmd = &cobra.Command{
+	Use:   "rotate",
+	Short: "Generate a new key and make it the active signing key",
+	Long:  `Generate a new key and make it the active signing key. Older keys stay available for verification until they are pruned.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		dir := jwtKeysDir()
+
+		kid := rotateKid
+		if kid == "" {
+			key, err := jwks.GenerateKey(dir, jwtKeysAlg())
+			if err != nil {
+				log.Fatalf("Error generating key, %v", err)
+			}
+			kid = key.ID
+		}
+
+		if err := jwks.SetActive(dir, kid); err != nil {
+			log.Fatalf("Error activating key, %v", err)
+		}
+		fmt.Printf("active key is now %s\n", kid)
+	},
+}
+
+var jwtKeysListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the keys of the key directory",
+	Run: func(cmd *cobra.Command, args []string) {
+		keys, active, err := jwks.ListKeys(jwtKeysDir())
+		if err != nil {
+			log.Fatalf("Error listing keys, %v", err)
+		}
+
+		for _, key := range keys {
+			marker := " "
+			if key.ID == active {
+				marker = "*"
+			}
+			fmt.Printf("%s %s\t%s\t%s\n", marker, key.ID, key.Algorithm, key.CreatedAt.Format("2006-01-02 15:04:05"))
+		}
+	},
+}
+
+var jwtKeysPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Delete the oldest inactive keys",
+	Long:  `Delete the oldest inactive keys. Only prune after the refresh token lifetime has passed since the last rotation, tokens signed by a removed key are rejected.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if keepKeys < 1 {
+			log.Fatalf("--keep must be at least 1")
+		}
+
+		removed, err := jwks.PruneKeys(jwtKeysDir(), keepKeys)
+		if err != nil {
+			log.Fatalf("Error pruning keys, %v", err)
+		}
+		for _, kid := range removed {
+			fmt.Printf("removed %s\n", kid)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(jwtKeysCmd)
+	jwtKeysCmd.AddCommand(jwtKeysGenerateCmd, jwtKeysRotateCmd, jwtKeysListCmd, jwtKeysPruneCmd)
+
+	jwtKeysCmd.PersistentFlags().StringVar(&keysDir, "dir", "", "key directory (default jwt.keys_dir)")
+	jwtKeysCmd.PersistentFlags().StringVar(&keyAlg, "alg", "", "RS256 or EdDSA (default jwt.algorithm)")
+	jwtKeysRotateCmd.Flags().StringVar(&rotateKid, "kid", "", "activate an already generated key instead of generating one")
+	jwtKeysPruneCmd.Flags().IntVar(&keepKeys, "keep", 2, "number of keys to keep, including the active one")
+}
+
+func jwtKeysDir() string {
+	if keysDir != "" {
+		return keysDir
+	}
+	if pkg.Cfg.Jwt.KeysDir == "" {
+		log.Fatalf("--dir or jwt.keys_dir is required")
+	}
+	return pkg.Cfg.Jwt.KeysDir
+}
+
+func jwtKeysAlg() string {
+	if keyAlg != "" {
+		return keyAlg
+	}
+	if alg := jwks.Algorithm(); alg != jwks.AlgHS256 {
+		return alg
+	}
+	return jwks.AlgRS256
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/DiansSopandi/goride_be/bootstrap"
+	"github.com/DiansSopandi/goride_be/pkg"
+	"github.com/spf13/cobra"
+)
+
+var deadLetterType string
+var deadLetterLimit int
+var replayAll bool
+
+var outboxCmd = &cobra.Command{
+	Use:   "outbox",
+	Short: "Inspect and replay the outbox dead letters",
+	Long:  `Inspect and replay the domain events the outbox relay gave up on after outbox.max_attempts deliveries.`,
+}
+
+var outboxDeadLettersCmd = &cobra.Command{
+	Use:   "dead-letters",
+	Short: "List the dead letters, newest first",
+	Run: func(cmd *cobra.Command, args []string) {
+		app := bootstrap.NewApp(pkg.Cfg)
+		defer app.Close()
+
+		deadLetters, err := app.Services.Outbox.GetDeadLetters(context.Background(), deadLetterType, deadLetterLimit)
+		if err != nil {
+			log.Fatalf("Error listing dead letters, %v", err)
+		}
+
+		for _, d := range deadLetters {
+			lastError, _, _ := strings.Cut(d.LastError, "\n")
+			fmt.Printf("%d\t%s\t%s\t%s:%s\t%d attempts\t%s\t%s\n", d.ID, d.FailedAt.Format("2006-01-02 15:04:05"), d.EventType,
+				d.AggregateType, d.AggregateID, d.Attempts, d.EventID, lastError)
+		}
+	},
+}
+
+var outboxReplayCmd = &cobra.Command{
+	Use:   "replay [id...]",
+	Short: "Put dead letters back in the outbox",
+	Long:  `Put dead letters back in the outbox, the relay publishes them again with the same event id. Give the ids of the dead letters, or --all to replay every one of them (of --type when given).`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if replayAll == (len(args) > 0) {
+			log.Fatalf("give the ids of the dead letters or --all")
+		}
+
+		app := bootstrap.NewApp(pkg.Cfg)
+		defer app.Close()
+
+		outbox := app.Services.Outbox
+		ctx := context.Background()
+
+		if replayAll {
+			count, err := outbox.ReplayDeadLetters(ctx, deadLetterType)
+			if err != nil {
+				log.Fatalf("Error replaying dead letters, %v", err)
+			}
+			fmt.Printf("replayed %d dead letters\n", count)
+			return
+		}
+
+		for _, arg := range args {
+			id, err := strconv.ParseUint(arg, 10, 64)
+			if err != nil {
+				log.Fatalf("invalid dead letter id %q", arg)
+			}
+			if err := outbox.ReplayDeadLetter(ctx, id); err != nil {
+				log.Fatalf("Error replaying dead letter %d, %v", id, err)
+			}
+			fmt.Printf("replayed %d\n", id)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(outboxCmd)
+	outboxCmd.AddCommand(outboxDeadLettersCmd, outboxReplayCmd)
+
+	outboxCmd.PersistentFlags().StringVar(&deadLetterType, "type", "", "only the events of this type, e.g. user.registered")
+	outboxDeadLettersCmd.Flags().IntVar(&deadLetterLimit, "limit", 50, "number of dead letters to list, at most 500")
+	outboxReplayCmd.Flags().BoolVar(&replayAll, "all", false, "replay every dead letter")
+}
@@ -4,6 +4,7 @@ import (
 	"os"
 
 	"github.com/DiansSopandi/goride_be/bootstrap"
+	"github.com/DiansSopandi/goride_be/pkg"
 	"github.com/spf13/cobra"
 )
 
@@ -15,10 +16,18 @@ var rootCmd = &cobra.Command{
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
 	Run: func(cmd *cobra.Command, args []string) {
-		bootstrap.ServerInitialize()
+		bootstrap.ServerInitialize(configSource())
+	},
+	// Every command runs on the loaded configuration, the config command loads it itself to report the problems.
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		initConfig()
 	},
 }
 
+var cfgFile string
+var profile string
+var overrides []string
+
 func Execute() {
 	err := rootCmd.Execute()
 	if err != nil {
@@ -32,6 +41,9 @@ func init() {
 	// will be global for your application.
 
 	// rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.ems.yaml)")
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "/path/to/config/env.conf (default "+pkg.DefaultConfigFile+")")
+	rootCmd.PersistentFlags().StringVar(&profile, "profile", "", "profile merged on top of the config file, e.g. dev reads env.dev.conf (default application.env)")
+	rootCmd.PersistentFlags().StringArrayVar(&overrides, "set", nil, "override a setting, e.g. --set application.app_port=8080, wins over the files and "+pkg.EnvPrefix+"_* variables")
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
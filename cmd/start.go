@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"log"
-	"path/filepath"
 	"time"
 
 	"github.com/DiansSopandi/goride_be/bootstrap"
@@ -10,7 +9,6 @@ import (
 	"github.com/spf13/cobra"
 )
 
-var cfgFile string
 var tz string
 var startCmd = &cobra.Command{
 	Use:   "start",
@@ -20,7 +18,7 @@ var startCmd = &cobra.Command{
 		// Do Stuff Here
 		// fmt.Println("🚀 App started from startCmd")
 		// Panggil aplikasi atau server kamu di sini, misalnya:
-		bootstrap.ServerInitialize()
+		bootstrap.ServerInitialize(configSource())
 	},
 }
 
@@ -28,31 +26,31 @@ var startCmd = &cobra.Command{
 // @return void
 func init() {
 	rootCmd.AddCommand(startCmd)
-	cobra.OnInitialize(initConfig)
 
-	startCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "/path/to/config/env.conf")
 	startCmd.PersistentFlags().StringVar(&tz, "timezone", "", "Asia/Jakarta")
 }
 
-func loadConfig() {
-	// Load config file
-	if cfgFile != "" {
-		cfgPath := filepath.Dir(cfgFile)
-		cfgFilename := filepath.Base(cfgFile)
-
-		pkg.LoadConfig(cfgFilename, cfgPath)
-	} else {
-		pkg.LoadConfig("env.conf", "./")
+// configSource collects the --config, --profile, --set and --timezone flags.
+func configSource() pkg.ConfigSource {
+	src := pkg.ConfigSource{File: cfgFile, Profile: profile, Overrides: overrides}
+	if tz != "" {
+		src.Overrides = append(src.Overrides, "application.timezone="+tz)
 	}
+	return src
+}
 
-	// Set timezone
-	if tz != "" {
-		pkg.Cfg.Application.Timezone = tz
+func loadConfig() {
+	cfg, err := pkg.LoadConfig(configSource())
+	if err != nil {
+		log.Fatalf("Error loading config, %v", err)
 	}
+	pkg.Cfg = cfg
+	pkg.SetRuntime(cfg.RuntimeSettings())
+	pkg.InitLogger()
 
 	loc, err := time.LoadLocation(pkg.Cfg.Application.Timezone)
 	if err != nil {
-		log.Fatalf("Error loading timezone, %v", err)
+		pkg.Fatal("error loading timezone", "timezone", pkg.Cfg.Application.Timezone, "error", err)
 	}
 	time.Local = loc
 }
@@ -0,0 +1,46 @@
+package db
+
+import (
+	"context"
+	"sync"
+)
+
+type afterCommitKey struct{}
+
+// AfterCommitHooks are the functions registered with AfterCommit during a transaction.
+type AfterCommitHooks struct {
+	mu    sync.Mutex
+	hooks []func()
+}
+
+// WithAfterCommit returns a context collecting the AfterCommit hooks of a transaction, the owner of the
+// transaction runs them once it is committed and drops them on rollback.
+func WithAfterCommit(ctx context.Context) (context.Context, *AfterCommitHooks) {
+	hooks := &AfterCommitHooks{}
+	return context.WithValue(ctx, afterCommitKey{}, hooks), hooks
+}
+
+// AfterCommit defers fn until the transaction of ctx is committed, it runs fn right away outside of a
+// transaction. Side effects like cache invalidations must not be seen before the data they are about.
+func AfterCommit(ctx context.Context, fn func()) {
+	hooks, ok := ctx.Value(afterCommitKey{}).(*AfterCommitHooks)
+	if !ok {
+		fn()
+		return
+	}
+	hooks.mu.Lock()
+	hooks.hooks = append(hooks.hooks, fn)
+	hooks.mu.Unlock()
+}
+
+// Run calls the hooks in the order they were registered.
+func (h *AfterCommitHooks) Run() {
+	h.mu.Lock()
+	hooks := h.hooks
+	h.hooks = nil
+	h.mu.Unlock()
+
+	for _, fn := range hooks {
+		fn()
+	}
+}
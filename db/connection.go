@@ -1,13 +1,27 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"log"
+	"log/slog"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/DiansSopandi/goride_be/pkg"
+	"github.com/DiansSopandi/goride_be/pkg/metrics"
+	"github.com/XSAM/otelsql"
 	_ "github.com/lib/pq"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+const (
+	defaultConnectRetries = 5
+	defaultConnectBackoff = time.Second
+	maxConnectBackoff     = 30 * time.Second
+	connectPingTimeout    = 5 * time.Second
 )
 
 var (
@@ -21,30 +35,27 @@ func CreateDatabaseIfNotExists() {
 	// user := pkg.GetEnv("DB_USER")
 	// password := pkg.GetEnv("DB_PASSWORD")
 	// dbname := pkg.GetEnv("DB_NAME")
-	host := pkg.Cfg.Database.Host
-	port := pkg.Cfg.Database.Port
-	user := pkg.Cfg.Database.User
-	password := pkg.Cfg.Database.Password
 	dbname := pkg.Cfg.Database.DBName
 
 	// Connect to postgres database (default database)
-	connStr := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=postgres sslmode=disable",
-		host, port, user, password)
-
-	db, err := sql.Open("postgres", connStr)
+	db, err := sql.Open("postgres", dataSourceName("postgres"))
 	if err != nil {
-		log.Fatalf("Error connecting to postgres database: %v", err)
+		pkg.Fatal("error connecting to postgres database", "error", err)
 	}
 
 	defer db.Close()
 
+	if err := pingWithRetry(db, "postgres"); err != nil {
+		pkg.Fatal("error connecting to postgres database", "error", err)
+	}
+
 	// Check if database exists
 	var exists bool
 	query := `SELECT EXISTS(SELECT datname FROM pg_catalog.pg_database WHERE datname = $1)`
 
 	err = db.QueryRow(query, dbname).Scan(&exists)
 	if err != nil {
-		log.Fatalf("Error checking if database exists connection: %v", err)
+		pkg.Fatal("error checking if database exists", "error", err)
 	}
 
 	if !exists {
@@ -52,9 +63,9 @@ func CreateDatabaseIfNotExists() {
 		createQuery := fmt.Sprintf("CREATE DATABASE %s", dbname)
 		_, err = db.Exec(createQuery)
 		if err != nil {
-			log.Fatalf("Error creating database: %v", err)
+			pkg.Fatal("error creating database", "database", dbname, "error", err)
 		}
-		log.Printf("Database '%s' created successfully", dbname)
+		slog.Info("database created", "database", dbname)
 	}
 }
 
@@ -66,29 +77,135 @@ func Connect() *sql.DB {
 	// password := os.Getenv("DB_PASSWORD")
 	// dbname := os.Getenv("DB_NAME")
 	host := pkg.Cfg.Database.Host
-	port := pkg.Cfg.Database.Port
-	user := pkg.Cfg.Database.User
-	password := pkg.Cfg.Database.Password
 	dbname := pkg.Cfg.Database.DBName
 
 	// Buat connection string
-	connStr := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
-		host, port, user, password, dbname)
-
-	db, err := sql.Open("postgres", connStr)
+	db, err := open(dataSourceName(dbname), dbname)
 	if err != nil {
-		log.Fatalf("Error connecting to the database: %v", err)
+		pkg.Fatal("error connecting to the database", "error", err)
 	}
 
-	err = db.Ping()
+	err = pingWithRetry(db, dbname)
 	if err != nil {
-		log.Fatalf("Error pinging the database: %v", err)
+		pkg.Fatal("error pinging the database", "error", err)
 	}
-	fmt.Println("🔌 Connecting to PostgreSQL...")
+	slog.Info("🔌 connected to PostgreSQL", "host", host, "database", dbname, "sslmode", sslMode())
 
 	return db
 }
 
+// ConnectReplica opens the read replica of database.replica_dsn, nil when none is configured.
+// An unreachable replica is logged and skipped, the read-only queries then stay on the primary.
+func ConnectReplica() *sql.DB {
+	dsn := pkg.Cfg.Database.ReplicaDSN
+	if dsn == "" {
+		return nil
+	}
+
+	name := pkg.Cfg.Database.DBName + "_replica"
+	replica, err := open(dsn, pkg.Cfg.Database.DBName)
+	if err == nil {
+		err = pingWithRetry(replica, name)
+	}
+	if err != nil {
+		slog.Error("read replica unreachable, read-only queries stay on the primary", "error", err)
+		if replica != nil {
+			replica.Close()
+		}
+		return nil
+	}
+
+	metrics.RegisterDB(replica, name)
+	slog.Info("🔌 connected to PostgreSQL read replica", "database", name)
+	return replica
+}
+
+// open returns an instrumented pool with the limits of the database config.
+func open(dsn, dbname string) (*sql.DB, error) {
+	db, err := otelsql.Open("postgres", dsn,
+		otelsql.WithAttributes(semconv.DBSystemPostgreSQL, semconv.DBNamespace(dbname)),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{
+			DisableErrSkip:       true,
+			OmitConnResetSession: true,
+			OmitRows:             true,
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := pkg.Cfg.Database
+	if cfg.MaxOpenConn > 0 {
+		db.SetMaxOpenConns(cfg.MaxOpenConn)
+	}
+	if cfg.MaxIdleConn > 0 {
+		db.SetMaxIdleConns(cfg.MaxIdleConn)
+	}
+	db.SetConnMaxLifetime(pkg.ConfigDuration("database.conn_max_lifetime", cfg.ConnMaxLifetime, 0))
+	db.SetConnMaxIdleTime(pkg.ConfigDuration("database.conn_max_idle_time", cfg.ConnMaxIdleTime, 0))
+
+	return db, nil
+}
+
+// pingWithRetry waits for Postgres at startup, so the API may start before the database is up.
+// The wait doubles after each failed attempt, up to maxConnectBackoff.
+func pingWithRetry(db *sql.DB, name string) error {
+	retries := pkg.Cfg.Database.ConnectRetries
+	if retries <= 0 {
+		retries = defaultConnectRetries
+	}
+	backoff := pkg.ConfigDuration("database.connect_retry_backoff", pkg.Cfg.Database.ConnectRetryBackoff, defaultConnectBackoff)
+
+	for attempt := 0; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), connectPingTimeout)
+		err := db.PingContext(ctx)
+		cancel()
+		if err == nil || attempt == retries {
+			return err
+		}
+
+		slog.Warn("postgres unreachable, retrying", "database", name, "retry", attempt+1, "retries", retries, "backoff", backoff, "error", err)
+		time.Sleep(backoff)
+		backoff = min(backoff*2, maxConnectBackoff)
+	}
+}
+
+// dataSourceName builds the key/value DSN of dbname from the database config, sslmode defaults
+// to disable. verify-ca and verify-full check the server against sslrootcert.
+func dataSourceName(dbname string) string {
+	cfg := pkg.Cfg.Database
+	params := []string{
+		"host=" + dsnValue(cfg.Host),
+		"port=" + strconv.Itoa(cfg.Port),
+		"user=" + dsnValue(cfg.User),
+		"password=" + dsnValue(cfg.Password),
+		"dbname=" + dsnValue(dbname),
+		"sslmode=" + dsnValue(sslMode()),
+	}
+	if cfg.SSLRootCert != "" {
+		params = append(params, "sslrootcert="+dsnValue(cfg.SSLRootCert))
+	}
+	if cfg.SSLCert != "" {
+		params = append(params, "sslcert="+dsnValue(cfg.SSLCert), "sslkey="+dsnValue(cfg.SSLKey))
+	}
+	return strings.Join(params, " ")
+}
+
+func sslMode() string {
+	if pkg.Cfg.Database.SSLMode == "" {
+		return "disable"
+	}
+	return pkg.Cfg.Database.SSLMode
+}
+
+// dsnValue quotes value when it is empty or holds a space, quote or backslash.
+func dsnValue(value string) string {
+	if value != "" && !strings.ContainsAny(value, ` '\`) {
+		return value
+	}
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+}
+
 // InitDatabase initializes database (create if not exists and connect)
 func InitDatabase() *sql.DB {
 	once.Do(func() {
@@ -97,6 +214,7 @@ func InitDatabase() *sql.DB {
 
 		// Then connect to the database
 		dbInstance = Connect()
+		metrics.RegisterDB(dbInstance, pkg.Cfg.Database.DBName)
 	})
 	return dbInstance
 }
@@ -105,7 +223,7 @@ func InitTransaction() *sql.Tx {
 	db := InitDatabase() // pastikan dbInstance sudah inisialisasi
 	tx, err := db.Begin()
 	if err != nil {
-		log.Fatalf("Failed to begin transaction: %v", err)
+		pkg.Fatal("failed to begin transaction", "error", err)
 	}
 	return tx
 }
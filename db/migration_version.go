@@ -0,0 +1,60 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//go:embed migrations/*.up.sql
+var migrationFiles embed.FS
+
+var (
+	expectedVersion     uint
+	expectedVersionOnce sync.Once
+)
+
+// ExpectedMigrationVersion is the highest migration in db/migrations, the version this build expects.
+func ExpectedMigrationVersion() uint {
+	expectedVersionOnce.Do(func() {
+		names, _ := fs.Glob(migrationFiles, "migrations/*.up.sql")
+		for _, name := range names {
+			prefix, _, _ := strings.Cut(strings.TrimPrefix(name, "migrations/"), "_")
+			if v, err := strconv.ParseUint(prefix, 10, 64); err == nil && uint(v) > expectedVersion {
+				expectedVersion = uint(v)
+			}
+		}
+	})
+	return expectedVersion
+}
+
+// MigrationVersion returns the version and dirty flag golang-migrate recorded in schema_migrations.
+func MigrationVersion(ctx context.Context, db *sql.DB) (uint, bool, error) {
+	var version uint
+	var dirty bool
+	err := db.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	return version, dirty, err
+}
+
+// CheckMigrationVersion fails when the schema is dirty or not at ExpectedMigrationVersion.
+func CheckMigrationVersion(ctx context.Context, db *sql.DB) error {
+	version, dirty, err := MigrationVersion(ctx, db)
+	if err != nil {
+		return fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	if dirty {
+		return fmt.Errorf("migration %d is dirty", version)
+	}
+	if expected := ExpectedMigrationVersion(); version != expected {
+		return fmt.Errorf("database is at migration %d, expected %d", version, expected)
+	}
+	return nil
+}
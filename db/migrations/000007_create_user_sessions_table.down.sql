@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_user_sessions_active;
+DROP INDEX IF EXISTS idx_user_sessions_user_id;
+DROP TABLE IF EXISTS user_sessions;
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS api_keys (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(16) NOT NULL, -- Bagian publik dari key, dipakai untuk lookup
+    secret_hash VARCHAR(255) NOT NULL, -- Hash dari secret (jangan simpan plain text)
+    owner_user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    rate_limit_per_minute INTEGER, -- NULL = default_max_requests_per_minute
+    expires_at TIMESTAMP NULL,
+    last_used_at TIMESTAMP NULL,
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    revoked_at TIMESTAMP NULL,
+
+    CONSTRAINT uq_api_keys_prefix UNIQUE (prefix)
+);
+
+CREATE INDEX idx_api_keys_owner_user_id ON api_keys(owner_user_id);
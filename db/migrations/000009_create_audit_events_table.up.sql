@@ -0,0 +1,18 @@
+-- Append-only, baris audit tidak pernah di-update atau di-delete oleh aplikasi
+CREATE TABLE IF NOT EXISTS audit_events (
+    id BIGSERIAL PRIMARY KEY,
+    actor_user_id INTEGER NULL, -- NULL untuk aksi sistem, tanpa FK supaya audit tetap ada setelah user dihapus
+    actor_type VARCHAR(20) NOT NULL DEFAULT 'user', -- user, api_key, system
+    action VARCHAR(100) NOT NULL, -- e.g. api_key.create, user.update
+    target_type VARCHAR(50) NOT NULL,
+    target_id VARCHAR(100) NOT NULL,
+    before_data JSONB,
+    after_data JSONB,
+    ip_address VARCHAR(64),
+    request_id VARCHAR(100),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_audit_events_actor ON audit_events(actor_user_id, created_at DESC);
+CREATE INDEX idx_audit_events_target ON audit_events(target_type, target_id, created_at DESC);
+CREATE INDEX idx_audit_events_action ON audit_events(action, created_at DESC);
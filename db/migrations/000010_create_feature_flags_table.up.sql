@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS feature_flags (
+    id SERIAL PRIMARY KEY,
+    key VARCHAR(100) NOT NULL, -- dipakai di kode dan di aplikasi mobile, e.g. ride.scheduled_booking
+    description TEXT,
+    enabled BOOLEAN NOT NULL DEFAULT FALSE, -- saklar utama, FALSE mematikan flag untuk semua user
+    rollout_percentage INTEGER NOT NULL DEFAULT 100, -- persentase user (hash dari user id) yang mendapat flag
+    roles TEXT[] NOT NULL DEFAULT '{}', -- kosong = semua role
+    cities TEXT[] NOT NULL DEFAULT '{}', -- kosong = semua kota
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT uq_feature_flags_key UNIQUE (key),
+    CONSTRAINT chk_feature_flags_rollout CHECK (rollout_percentage BETWEEN 0 AND 100)
+);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_outbox_dead_letters_type;
+DROP TABLE IF EXISTS outbox_dead_letters;
+DROP INDEX IF EXISTS idx_outbox_events_published;
+DROP INDEX IF EXISTS idx_outbox_events_due;
+DROP TABLE IF EXISTS outbox_events;
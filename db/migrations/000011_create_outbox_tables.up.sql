@@ -0,0 +1,39 @@
+-- Event yang ditulis di transaksi yang sama dengan perubahannya, dikirim oleh relay setelah commit
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id BIGSERIAL PRIMARY KEY,
+    event_id UUID NOT NULL, -- id yang dilihat subscriber, dipakai untuk dedup karena pengiriman at-least-once
+    event_type VARCHAR(100) NOT NULL, -- e.g. user.registered
+    aggregate_type VARCHAR(50) NOT NULL, -- e.g. user
+    aggregate_id VARCHAR(100) NOT NULL,
+    payload JSONB NOT NULL,
+    request_id VARCHAR(100),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    delivered_to TEXT[] NOT NULL DEFAULT '{}', -- publisher yang sudah menerima, tidak dikirim ulang saat retry
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, -- juga lease relay yang sedang mengirim
+    last_error TEXT,
+    published_at TIMESTAMP NULL, -- NULL = belum terkirim
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT uq_outbox_events_event_id UNIQUE (event_id)
+);
+
+CREATE INDEX idx_outbox_events_due ON outbox_events(next_attempt_at, id) WHERE published_at IS NULL;
+CREATE INDEX idx_outbox_events_published ON outbox_events(published_at) WHERE published_at IS NOT NULL;
+
+-- Event yang gagal dikirim setelah max_attempts, dikirim ulang dengan `outbox replay`
+CREATE TABLE IF NOT EXISTS outbox_dead_letters (
+    id BIGSERIAL PRIMARY KEY,
+    event_id UUID NOT NULL,
+    event_type VARCHAR(100) NOT NULL,
+    aggregate_type VARCHAR(50) NOT NULL,
+    aggregate_id VARCHAR(100) NOT NULL,
+    payload JSONB NOT NULL,
+    request_id VARCHAR(100),
+    attempts INTEGER NOT NULL,
+    delivered_to TEXT[] NOT NULL DEFAULT '{}',
+    last_error TEXT,
+    created_at TIMESTAMP NOT NULL, -- waktu event dibuat, bukan waktu gagal
+    failed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_outbox_dead_letters_type ON outbox_dead_letters(event_type, failed_at DESC);
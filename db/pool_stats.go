@@ -0,0 +1,49 @@
+package db
+
+import (
+	"database/sql"
+	"log/slog"
+	"time"
+)
+
+// LogPoolStats logs the stats of each pool every interval until the returned func is called.
+// A growing wait_count means requests queue for a connection and max_open_conn is too low.
+func LogPoolStats(interval time.Duration, pools map[string]*sql.DB) (stop func()) {
+	if interval <= 0 {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				for name, pool := range pools {
+					logPoolStats(name, pool.Stats())
+				}
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
+
+func logPoolStats(name string, stats sql.DBStats) {
+	slog.Info("postgres pool stats",
+		"pool", name,
+		"max_open", stats.MaxOpenConnections,
+		"open", stats.OpenConnections,
+		"in_use", stats.InUse,
+		"idle", stats.Idle,
+		"wait_count", stats.WaitCount,
+		"wait_duration", stats.WaitDuration,
+		"max_idle_closed", stats.MaxIdleClosed,
+		"max_idle_time_closed", stats.MaxIdleTimeClosed,
+		"max_lifetime_closed", stats.MaxLifetimeClosed,
+	)
+}
@@ -0,0 +1,21 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/DiansSopandi/goride_be/models"
+)
+
+type ApiKeyCreateRequest struct {
+	Name               string     `json:"name" validate:"required,max=100" example:"Corporate partner X"`
+	OwnerUserID        uint       `json:"owner_user_id,omitempty" example:"12"` // defaults to the admin creating the key
+	Scopes             []string   `json:"scopes" validate:"required,min=1" example:"users:read"`
+	RateLimitPerMinute *int       `json:"rate_limit_per_minute,omitempty" validate:"omitempty,min=1" example:"120"`
+	ExpiresAt          *time.Time `json:"expires_at,omitempty" example:"2026-12-31T23:59:59Z"`
+}
+
+// ApiKeyCreateResponse is the only response that contains the full key, it cannot be retrieved again.
+type ApiKeyCreateResponse struct {
+	models.ApiKey
+	Key string `json:"key" example:"grk_1a2b3c4d5e6f_9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"`
+}
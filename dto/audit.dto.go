@@ -0,0 +1,32 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/DiansSopandi/goride_be/models"
+)
+
+// AuditActor identifies who performs an audited action.
+type AuditActor struct {
+	UserID    uint
+	Type      string // user, api_key or system
+	IPAddress string
+	RequestID string
+}
+
+// AuditEventFilter narrows the admin audit query, zero values are ignored.
+type AuditEventFilter struct {
+	ActorUserID uint
+	Action      string
+	TargetType  string
+	TargetID    string
+	From        *time.Time
+	To          *time.Time
+	BeforeID    uint64
+	Limit       int
+}
+
+type AuditEventPage struct {
+	Events       []models.AuditEvent `json:"events"`
+	NextBeforeID uint64              `json:"next_before_id,omitempty" example:"1042"` // cursor of the next page, empty on the last page
+}
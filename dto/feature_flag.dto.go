@@ -0,0 +1,30 @@
+package dto
+
+type FeatureFlagCreateRequest struct {
+	Key               string   `json:"key" validate:"required,max=100" example:"ride.scheduled_booking"`
+	Description       string   `json:"description,omitempty" validate:"max=1000" example:"Book a ride up to 7 days ahead"`
+	Enabled           bool     `json:"enabled" example:"true"`
+	RolloutPercentage *int     `json:"rollout_percentage,omitempty" validate:"omitempty,min=0,max=100" example:"10"` // defaults to 100
+	Roles             []string `json:"roles,omitempty" example:"driver"`                                             // empty targets every role
+	Cities            []string `json:"cities,omitempty" example:"Jakarta"`                                           // empty targets every city
+}
+
+// FeatureFlagUpdateRequest changes the fields that are set, the key cannot change.
+type FeatureFlagUpdateRequest struct {
+	Description       *string   `json:"description,omitempty" validate:"omitempty,max=1000" example:"Book a ride up to 7 days ahead"`
+	Enabled           *bool     `json:"enabled,omitempty" example:"false"`
+	RolloutPercentage *int      `json:"rollout_percentage,omitempty" validate:"omitempty,min=0,max=100" example:"50"`
+	Roles             *[]string `json:"roles,omitempty" example:"driver"`
+	Cities            *[]string `json:"cities,omitempty" example:"Jakarta"`
+}
+
+// FlagSubject is who the feature flags are evaluated for, UserID is 0 for anonymous requests.
+type FlagSubject struct {
+	UserID uint
+	Roles  []string
+	City   string
+}
+
+type FeatureFlagsResponse struct {
+	Flags map[string]bool `json:"flags"` // every flag by key, true when it is on for the caller
+}
@@ -9,6 +9,6 @@ type RoleResponse struct {
 }
 
 type RoleCreateRequest struct {
-	Name        string  `json:"name" example:"user"`
-	Description *string `json:"description" example:"user"`
+	Name        string  `json:"name" validate:"required,max=50" example:"user"`
+	Description *string `json:"description" validate:"omitempty,max=1000" example:"user"`
 }
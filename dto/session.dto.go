@@ -0,0 +1,27 @@
+package dto
+
+import "time"
+
+// SessionMeta describes the device a login comes from.
+type SessionMeta struct {
+	DeviceName string
+	UserAgent  string
+	IPAddress  string
+}
+
+type SessionTokens struct {
+	SessionID    string
+	AccessToken  string
+	RefreshToken string
+}
+
+type SessionResponse struct {
+	ID         string    `json:"id" example:"0b6f1f9e-3c1d-4a43-9d55-5f7f3bb0d6a1"`
+	DeviceName string    `json:"device_name" example:"Pixel 8"`
+	UserAgent  string    `json:"user_agent" example:"GoRide/1.4.0 (Android 14)"`
+	IPAddress  string    `json:"ip_address" example:"203.0.113.10"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current" example:"true"`
+}
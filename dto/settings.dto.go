@@ -0,0 +1,10 @@
+package dto
+
+import "time"
+
+// SettingsResponse is the configuration in effect, secrets are redacted.
+type SettingsResponse struct {
+	Config          map[string]interface{} `json:"config"`
+	RuntimeSettings []string               `json:"runtime_settings" example:"application.cors_origins"` // keys reloaded without a restart
+	AppliedAt       time.Time              `json:"applied_at"`                                          // when the runtime settings in effect were applied
+}
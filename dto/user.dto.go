@@ -1,10 +1,10 @@
 package dto
 
 type UserCreateRequest struct {
-	Username string   `json:"username" example:"John Doe"`
-	Email    string   `json:"email" example:"Q2Sb9@example.com"`
-	Password string   `json:"password" example:"Cilok99!@"`
-	Roles    []string `json:"roles" example:"admin,driver,user,superadmin"` // multiple roles
+	Username string   `json:"username" validate:"required,min=3,max=50" example:"John Doe"`
+	Email    string   `json:"email" validate:"required,email" example:"Q2Sb9@example.com"`
+	Password string   `json:"password" validate:"required,strong_password" example:"Cilok99!@"`
+	Roles    []string `json:"roles" validate:"omitempty,dive,required" example:"admin,driver,user,superadmin"` // multiple roles
 	// AvatarUrl  string `json:"avatarUrl" db:"avatar_url"`
 	// AvatarName string `json:"avatarName" db:"avatar_name"`
 	// FirstName  string `json:"firstName" db:"first_name"`
@@ -16,9 +16,9 @@ type UserCreateRequest struct {
 type UserRegisterRequest struct {
 	Username        string   `json:"username" validate:"required,min=3,max=50" example:"John Doe"`
 	Email           string   `json:"email" validate:"required,email" example:"Q2Sb9@example.com"`
-	Password        string   `json:"password" validate:"required,min=8" example:"Cilok99!@"`
+	Password        string   `json:"password" validate:"required,strong_password" example:"Cilok99!@"`
 	PasswordConfirm string   `json:"password_confirm" validate:"required,eqfield=Password" example:"Cilok99!@"`
-	Roles           []string `json:"roles" example:"user" validate:"required"`
+	Roles           []string `json:"roles" example:"user" validate:"required,min=1,dive,required"`
 	// AvatarUrl  string `json:"avatarUrl" db:"avatar_url"`
 	// AvatarName string `json:"avatarName" db:"avatar_name"`
 	// FirstName  string `json:"firstName" db:"first_name"`
@@ -35,8 +35,9 @@ type UserUpdateRequest struct {
 }
 
 type UserLoginRequest struct {
-	Email    string `json:"email" validate:"required,email" example:"Q2Sb9@example.com"`
-	Password string `json:"password" validate:"required" example:"Pass123!@"`
+	Email      string `json:"email" validate:"required,email" example:"Q2Sb9@example.com"`
+	Password   string `json:"password" validate:"required" example:"Pass123!@"`
+	DeviceName string `json:"device_name,omitempty" validate:"max=100" example:"Pixel 8"` // shown in the active sessions list
 }
 
 type UserResponse struct {
@@ -46,6 +47,17 @@ type UserResponse struct {
 	Roles    []string `json:"roles"`
 }
 
+// OAuthUserInfo is the provider-agnostic identity returned by any OAuth/OIDC login.
+type OAuthUserInfo struct {
+	Provider      string                 `json:"provider"`
+	Subject       string                 `json:"subject"`
+	Email         string                 `json:"email"`
+	EmailVerified bool                   `json:"email_verified"`
+	Name          string                 `json:"name"`
+	Picture       string                 `json:"picture"`
+	Raw           map[string]interface{} `json:"-"`
+}
+
 type UserLoginResponse struct {
 	User         UserResponse `json:"user"`
 	AccessToken  string       `json:"access_token"`
@@ -5,25 +5,43 @@ import (
 	"net/http"
 
 	"github.com/DiansSopandi/goride_be/pkg"
+	"github.com/DiansSopandi/goride_be/pkg/i18n"
 )
 
+func init() {
+	// the messages of ErrorCodeMap are the English catalog of the errors, the other languages
+	// translate them under the same "error.<code>" keys
+	i18n.Register(i18n.English, "error.", ErrorCodeMap)
+}
+
 type DetailResponse struct {
 	Path       string `json:"path" example:"/api/v1/path"`
 	Param      string `json:"param" example:"?page=1&limit=10"`
 	StatusCode int    `json:"status_code" example:"200"`
 	Method     string `json:"method" example:"GET"`
 	Status     string `json:"status" example:"success_ok"`
+	RequestID  string `json:"request_id,omitempty" example:"0b6f1f9e-3c1d-4a43-9d55-5f7f3bb0d6a1"`
+	TraceID    string `json:"trace_id,omitempty" example:"4bf92f3577b34da6a3ce929d0e0e4736"`
 } // @name	DetailResponse
 
 type AppErrorResponse struct {
 	Details    DetailResponse `json:"details"`
+	Code       string         `json:"code" example:"USER_NOT_FOUND"` // stable key of ErrorCodeMap, for clients to branch on
 	Success    bool           `json:"success" example:"true"`
 	Data       any            `json:"data" swaggertype:"array,object"`
 	Errors     any            `json:"errors" swaggertype:"array,object"`
 	Message    interface{}    `json:"message" example:"API Message"`
-	LogMessage string         `json:"log_message" example:"Log message for internal user"`
+	LogMessage string         `json:"-"` // logged with the request, never sent to the client
+	cause      error
 } // @name ResponseApi
 
+// FieldError describes one invalid field of a request, Field is its json path, e.g. "roles[0]".
+type FieldError struct {
+	Field   string `json:"field" example:"email"`
+	Code    string `json:"code" example:"email"`
+	Message string `json:"message" example:"must be a valid email address"`
+} // @name FieldError
+
 func (e *AppErrorResponse) Error() string {
 	if msg, ok := e.Message.(string); ok {
 		return msg
@@ -32,6 +50,56 @@ func (e *AppErrorResponse) Error() string {
 	// return e.Message.(string)
 }
 
+// WithCause keeps the error that led to e, so errors.Is still sees e.g. context.DeadlineExceeded
+// once it was turned into a log message.
+func (e *AppErrorResponse) WithCause(err error) *AppErrorResponse {
+	e.cause = err
+	return e
+}
+
+func (e *AppErrorResponse) Unwrap() error {
+	return e.cause
+}
+
+// LocalizedMessage returns the message of the code of e in lang. A message set for this error alone,
+// not the one of ErrorCodeMap, is returned as is.
+func (e *AppErrorResponse) LocalizedMessage(lang string) any {
+	if message, ok := e.Message.(string); !ok || message != ErrorCodeMap[e.Code] {
+		return e.Message
+	}
+	return i18n.T(lang, "error."+e.Code, nil)
+}
+
+// CodeForStatus returns the code of the errors not raised as an *AppErrorResponse, e.g. the 404 of fiber.
+func CodeForStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return "INVALID_REQUEST"
+	case http.StatusUnauthorized:
+		return "UNAUTHORIZED"
+	case http.StatusForbidden:
+		return "PERMISSION_DENIED"
+	case http.StatusNotFound:
+		return "RESOURCE_NOT_FOUND"
+	case http.StatusMethodNotAllowed:
+		return "OPERATION_NOT_ALLOWED"
+	case http.StatusConflict:
+		return "RESOURCE_CONFLICT"
+	case http.StatusRequestEntityTooLarge:
+		return "FILE_TOO_LARGE"
+	case http.StatusTooManyRequests:
+		return "TOO_MANY_REQUESTS"
+	case http.StatusServiceUnavailable:
+		return "SERVICE_UNAVAILABLE"
+	case http.StatusGatewayTimeout:
+		return "REQUEST_TIMEOUT"
+	}
+	if status < http.StatusInternalServerError {
+		return "INVALID_REQUEST"
+	}
+	return "INTERNAL_ERROR"
+}
+
 func NewAppErrorResponse(code string, statusCode int, logMessage string, status string) *AppErrorResponse {
 	message, ok := ErrorCodeMap[code]
 
@@ -47,6 +115,7 @@ func NewAppErrorResponse(code string, statusCode int, logMessage string, status
 			Method:     "",
 			Status:     status,
 		},
+		Code:       code,
 		Success:    false,
 		Data:       nil,
 		Errors:     nil,
@@ -154,9 +223,53 @@ func OperationNotAllowed(logMessage string) *AppErrorResponse {
 func ResourceConflict(logMessage string) *AppErrorResponse {
 	return NewAppErrorResponse("RESOURCE_CONFLICT", http.StatusConflict, logMessage, string(pkg.ApiStatusErrorConflict))
 }
+
+func IdempotencyKeyReused(logMessage string) *AppErrorResponse {
+	return NewAppErrorResponse("IDEMPOTENCY_KEY_REUSED", http.StatusConflict, logMessage, string(pkg.ApiStatusErrorConflict))
+}
+
+func IdempotentRequestInProgress(logMessage string) *AppErrorResponse {
+	return NewAppErrorResponse("IDEMPOTENT_REQUEST_IN_PROGRESS", http.StatusConflict, logMessage, string(pkg.ApiStatusErrorConflict))
+}
+
+// ValidationFailed lists the invalid fields of a request in the errors of the response.
+func ValidationFailed(logMessage string, fieldErrors []FieldError) *AppErrorResponse {
+	err := NewAppErrorResponse("VALIDATION_FAILED", http.StatusBadRequest, logMessage, string(pkg.ApiStatusErrorBadRequest))
+	err.Errors = fieldErrors
+	return err
+}
+
 func InvalidRequest(logMessage string) *AppErrorResponse {
 	return NewAppErrorResponse("INVALID_REQUEST", http.StatusBadRequest, logMessage, string(pkg.ApiStatusErrorBadRequest))
 }
+func AccountLinkRequired(logMessage string) *AppErrorResponse {
+	return NewAppErrorResponse("ACCOUNT_LINK_REQUIRED", http.StatusConflict, logMessage, string(pkg.ApiStatusErrorConflict))
+}
+
+func ProviderAlreadyLinked(logMessage string) *AppErrorResponse {
+	return NewAppErrorResponse("PROVIDER_ALREADY_LINKED", http.StatusConflict, logMessage, string(pkg.ApiStatusErrorConflict))
+}
+
+func ProviderNotLinked(logMessage string) *AppErrorResponse {
+	return NewAppErrorResponse("PROVIDER_NOT_LINKED", http.StatusNotFound, logMessage, string(pkg.ApiStatusErrorNotFound))
+}
+
+func LastLoginMethod(logMessage string) *AppErrorResponse {
+	return NewAppErrorResponse("LAST_LOGIN_METHOD", http.StatusConflict, logMessage, string(pkg.ApiStatusErrorConflict))
+}
+
+func ProviderDisabled(logMessage string) *AppErrorResponse {
+	return NewAppErrorResponse("PROVIDER_DISABLED", http.StatusForbidden, logMessage, string(pkg.ApiStatusErrorForbidden))
+}
+
+func RequestTimeout(logMessage string) *AppErrorResponse {
+	return NewAppErrorResponse("REQUEST_TIMEOUT", http.StatusGatewayTimeout, logMessage, string(pkg.ApiStatusErrorGatewayTimeout))
+}
+
+func ServiceUnavailable(logMessage string) *AppErrorResponse {
+	return NewAppErrorResponse("SERVICE_UNAVAILABLE", http.StatusServiceUnavailable, logMessage, string(pkg.ApiStatusErrorServiceUnavailable))
+}
+
 func InvalidRequestWithMessage(message string) *AppErrorResponse {
 	return &AppErrorResponse{
 		Details: DetailResponse{
@@ -166,6 +279,7 @@ func InvalidRequestWithMessage(message string) *AppErrorResponse {
 			Method:     "",
 			Status:     "",
 		},
+		Code:    "INVALID_REQUEST",
 		Success: false,
 		Data:    nil,
 		Errors:  nil,
@@ -182,6 +296,7 @@ func InvalidRequestWithCode(code string) *AppErrorResponse {
 			Method:     "",
 			Status:     "",
 		},
+		Code:    code,
 		Success: false,
 		Data:    nil,
 		Errors:  nil,
@@ -198,6 +313,7 @@ func InvalidRequestWithCodeAndMessage(code, message string) *AppErrorResponse {
 			Method:     "",
 			Status:     "",
 		},
+		Code:    code,
 		Success: false,
 		Data:    nil,
 		Errors:  nil,
@@ -214,6 +330,7 @@ func InvalidRequestWithCodeMessageAndStatus(code, message string, status int) *A
 			Method:     "",
 			Status:     "",
 		},
+		Code:    code,
 		Success: false,
 		Data:    nil,
 		Errors:  nil,
@@ -1,30 +1,40 @@
 package errors
 
 var ErrorCodeMap = map[string]string{
-	"USER_NOT_FOUND":          "User not found",
-	"INVALID_CREDENTIAL":      "Invalid username or password",
-	"DB_ERROR":                "Database error occurred",
-	"INTERNAL_ERROR":          "Internal server error",
-	"ROLE_NOT_FOUND":          "Role not found",
-	"ROLE_VALDATION_FAILED":   "Role validation failed",
-	"PERMISSION_DENIED":       "Permission denied",
-	"INVALID_INPUT":           "Invalid input provided",
-	"EMAIL_ALREADY_EXISTS":    "Email already exists",
-	"USERNAME_ALREADY_EXISTS": "Username already exists",
-	"PHONE_ALREADY_EXISTS":    "Phone number already exists",
-	"UNAUTHORIZED":            "Unauthorized access",
-	"RESOURCE_NOT_FOUND":      "Requested resource not found",
-	"INVALID_TOKEN":           "Invalid or expired token",
-	"PASSWORD_MISMATCH":       "Password does not match",
-	"USER_ALREADY_EXISTS":     "User already exists",
-	"ROLE_ALREADY_EXISTS":     "Role already exists",
-	"EMAIL_NOT_VERIFIED":      "Email not verified",
-	"PHONE_NOT_VERIFIED":      "Phone number not verified",
-	"ACCOUNT_LOCKED":          "Account is locked",
-	"TOO_MANY_REQUESTS":       "Too many requests, please try again later",
-	"INVALID_FILE_TYPE":       "Invalid file type uploaded",
-	"FILE_TOO_LARGE":          "Uploaded file is too large",
-	"OPERATION_NOT_ALLOWED":   "Operation not allowed",
-	"RESOURCE_CONFLICT":       "Resource conflict occurred",
-	"INVALID_REQUEST":         "Invalid request",
+	"USER_NOT_FOUND":                 "User not found",
+	"INVALID_CREDENTIAL":             "Invalid username or password",
+	"DB_ERROR":                       "Database error occurred",
+	"INTERNAL_ERROR":                 "Internal server error",
+	"ROLE_NOT_FOUND":                 "Role not found",
+	"ROLE_VALDATION_FAILED":          "Role validation failed",
+	"PERMISSION_DENIED":              "Permission denied",
+	"INVALID_INPUT":                  "Invalid input provided",
+	"EMAIL_ALREADY_EXISTS":           "Email already exists",
+	"USERNAME_ALREADY_EXISTS":        "Username already exists",
+	"PHONE_ALREADY_EXISTS":           "Phone number already exists",
+	"UNAUTHORIZED":                   "Unauthorized access",
+	"RESOURCE_NOT_FOUND":             "Requested resource not found",
+	"INVALID_TOKEN":                  "Invalid or expired token",
+	"PASSWORD_MISMATCH":              "Password does not match",
+	"USER_ALREADY_EXISTS":            "User already exists",
+	"ROLE_ALREADY_EXISTS":            "Role already exists",
+	"EMAIL_NOT_VERIFIED":             "Email not verified",
+	"PHONE_NOT_VERIFIED":             "Phone number not verified",
+	"ACCOUNT_LOCKED":                 "Account is locked",
+	"TOO_MANY_REQUESTS":              "Too many requests, please try again later",
+	"INVALID_FILE_TYPE":              "Invalid file type uploaded",
+	"FILE_TOO_LARGE":                 "Uploaded file is too large",
+	"OPERATION_NOT_ALLOWED":          "Operation not allowed",
+	"RESOURCE_CONFLICT":              "Resource conflict occurred",
+	"INVALID_REQUEST":                "Invalid request",
+	"VALIDATION_FAILED":              "Some fields are invalid",
+	"ACCOUNT_LINK_REQUIRED":          "An account with this email already exists, sign in and link this provider from your profile",
+	"PROVIDER_ALREADY_LINKED":        "This login provider is already linked to an account",
+	"PROVIDER_NOT_LINKED":            "This login provider is not linked to your account",
+	"LAST_LOGIN_METHOD":              "You cannot remove your last login method",
+	"PROVIDER_DISABLED":              "This login provider has been disabled for your account",
+	"REQUEST_TIMEOUT":                "The request took too long, please try again",
+	"SERVICE_UNAVAILABLE":            "Service temporarily unavailable, please try again later",
+	"IDEMPOTENCY_KEY_REUSED":         "This Idempotency-Key was already used for another request",
+	"IDEMPOTENT_REQUEST_IN_PROGRESS": "A request with this Idempotency-Key is still in progress, please retry shortly",
 }
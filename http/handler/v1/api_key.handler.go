@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/DiansSopandi/goride_be/dto"
+	"github.com/DiansSopandi/goride_be/errors"
+	"github.com/DiansSopandi/goride_be/middlewares"
+	"github.com/DiansSopandi/goride_be/models"
+	"github.com/DiansSopandi/goride_be/pkg"
+	"github.com/DiansSopandi/goride_be/pkg/validation"
+	service "github.com/DiansSopandi/goride_be/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+type ApiKeyHandler struct {
+	baseHandler
+}
+
+func NewApiKeyHandler(services *service.Services, mw *middlewares.Middlewares) *ApiKeyHandler {
+	return &ApiKeyHandler{baseHandler{services: services, mw: mw}}
+}
+
+// ApiKeyRoutes registers the API key management routes under /admin.
+func ApiKeyRoutes(route fiber.Router, handler *ApiKeyHandler) {
+	route.Get("/api-keys", GetApiKeysHandler(handler))
+	route.Post("/api-keys", middlewares.Idempotency(), handler.mw.WithTransaction(CreateApiKeyHandler(handler)))
+	route.Delete("/api-keys/:id", handler.mw.WithTransaction(RevokeApiKeyHandler(handler)))
+}
+
+func GetApiKeysHandler(handler *ApiKeyHandler) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ownerID := c.QueryInt("owner_user_id", 0)
+		if ownerID < 0 {
+			return errors.InvalidInput("owner_user_id must be positive")
+		}
+
+		res, err := handler.GetApiKeys(c, uint(ownerID))
+		if err != nil {
+			return err
+		}
+
+		return pkg.ResponseApiOK(c, "API keys fetch successfully...", res)
+	}
+}
+
+func CreateApiKeyHandler(handler *ApiKeyHandler) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		req, err := validation.BindAndValidate[dto.ApiKeyCreateRequest](c)
+		if err != nil {
+			return err
+		}
+
+		actor := auditActorFromRequest(c, 0)
+
+		res, err := handler.CreateApiKey(c, actor, req)
+		if err != nil {
+			return err
+		}
+
+		return pkg.ResponseApiOK(c, "API key created successfully, store the key now, it will not be shown again", res)
+	}
+}
+
+func RevokeApiKeyHandler(handler *ApiKeyHandler) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+		if err != nil {
+			return errors.ResourceNotFound(fmt.Sprintf("invalid api key id %q", c.Params("id")))
+		}
+
+		actor := auditActorFromRequest(c, 0)
+
+		if err := handler.RevokeApiKey(c, actor, uint(id)); err != nil {
+			return err
+		}
+
+		return pkg.ResponseApiOK(c, "API key revoked successfully", nil)
+	}
+}
+
+// GetApiKeys
+// @Summary List API keys
+// @Description List the API keys of partners and internal services, secrets are never returned
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param owner_user_id query int false "Only keys owned by this user"
+// @Success 200 {array} models.ApiKey
+// @Failure 403 {object} map[string]interface{}
+// @Router /v1/admin/api-keys [get]
+func (h *ApiKeyHandler) GetApiKeys(c *fiber.Ctx, ownerUserID uint) ([]models.ApiKey, error) {
+	return h.services.ApiKeys.GetApiKeys(c.UserContext(), ownerUserID)
+}
+
+// CreateApiKey
+// @Summary Create an API key
+// @Description Create an API key for non-interactive access, the full key is only returned in this response
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body dto.ApiKeyCreateRequest true "API key"
+// @Param Idempotency-Key header string false "Makes retries safe, a retry with the same key and body replays the first response"
+// @Success 200 {object} dto.ApiKeyCreateResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /v1/admin/api-keys [post]
+func (h *ApiKeyHandler) CreateApiKey(c *fiber.Ctx, actor dto.AuditActor, req dto.ApiKeyCreateRequest) (dto.ApiKeyCreateResponse, error) {
+	services, err := h.txServices(c)
+	if err != nil {
+		return dto.ApiKeyCreateResponse{}, err
+	}
+	return services.ApiKeys.CreateApiKey(c.UserContext(), actor, req)
+}
+
+// RevokeApiKey
+// @Summary Revoke an API key
+// @Description Revoke an API key, requests using it are rejected immediately
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "API key id"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /v1/admin/api-keys/{id} [delete]
+func (h *ApiKeyHandler) RevokeApiKey(c *fiber.Ctx, actor dto.AuditActor, id uint) error {
+	services, err := h.txServices(c)
+	if err != nil {
+		return err
+	}
+	return services.ApiKeys.RevokeApiKey(c.UserContext(), actor, id)
+}
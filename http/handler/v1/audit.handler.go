@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/DiansSopandi/goride_be/dto"
+	"github.com/DiansSopandi/goride_be/errors"
+	"github.com/DiansSopandi/goride_be/middlewares"
+	"github.com/DiansSopandi/goride_be/models"
+	"github.com/DiansSopandi/goride_be/pkg"
+	service "github.com/DiansSopandi/goride_be/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+type AuditHandler struct {
+	baseHandler
+}
+
+func NewAuditHandler(services *service.Services, mw *middlewares.Middlewares) *AuditHandler {
+	return &AuditHandler{baseHandler{services: services, mw: mw}}
+}
+
+// AuditRoutes registers the audit log query under /admin.
+func AuditRoutes(route fiber.Router, handler *AuditHandler) {
+	route.Get("/audit-events", GetAuditEventsHandler(handler))
+}
+
+// auditActorFromRequest describes the caller for the audit log, actorID is used when the request
+// is not authenticated yet (login, register).
+func auditActorFromRequest(c *fiber.Ctx, actorID uint) dto.AuditActor {
+	actor := dto.AuditActor{
+		UserID:    actorID,
+		Type:      models.AuditActorUser,
+		IPAddress: pkg.GetClientIP(c),
+		RequestID: pkg.RequestID(c),
+	}
+
+	if userID, err := middlewares.CurrentUserID(c); err == nil {
+		actor.UserID = userID
+	}
+	if middlewares.CurrentApiKey(c) != nil {
+		actor.Type = models.AuditActorApiKey
+	}
+	return actor
+}
+
+// recordAudit writes an audit event in the transaction of the request, see middlewares.WithTransaction.
+func (h baseHandler) recordAudit(c *fiber.Ctx, actorID uint, action, targetType, targetID string, before, after interface{}) error {
+	services, err := h.txServices(c)
+	if err != nil {
+		return errors.InternalError(fmt.Sprintf("audit event %s requires a transaction", action))
+	}
+
+	return services.Audit.Record(c.UserContext(), auditActorFromRequest(c, actorID), action, targetType, targetID, before, after)
+}
+
+func GetAuditEventsHandler(handler *AuditHandler) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		filter := dto.AuditEventFilter{
+			Action:     c.Query("action"),
+			TargetType: c.Query("target_type"),
+			TargetID:   c.Query("target_id"),
+			Limit:      c.QueryInt("limit", 0),
+		}
+
+		if actorID := c.QueryInt("actor_user_id", 0); actorID > 0 {
+			filter.ActorUserID = uint(actorID)
+		}
+		if beforeID := c.QueryInt("before_id", 0); beforeID > 0 {
+			filter.BeforeID = uint64(beforeID)
+		}
+
+		var err error
+		if filter.From, err = parseTimeQuery(c, "from"); err != nil {
+			return err
+		}
+		if filter.To, err = parseTimeQuery(c, "to"); err != nil {
+			return err
+		}
+
+		res, err := handler.GetAuditEvents(c, filter)
+		if err != nil {
+			return err
+		}
+
+		return pkg.ResponseApiOK(c, "Audit events fetch successfully...", res)
+	}
+}
+
+func parseTimeQuery(c *fiber.Ctx, name string) (*time.Time, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, errors.InvalidInput(fmt.Sprintf("%s must be an RFC3339 time: %v", name, err))
+	}
+	return &t, nil
+}
+
+// GetAuditEvents
+// @Summary Query the audit log
+// @Description List security relevant actions, newest first. Use next_before_id as before_id to get the next page
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param actor_user_id query int false "User who performed the action"
+// @Param action query string false "Action" example(user.update)
+// @Param target_type query string false "Target type" example(user)
+// @Param target_id query string false "Target id"
+// @Param from query string false "RFC3339 start time, inclusive"
+// @Param to query string false "RFC3339 end time, exclusive"
+// @Param before_id query int false "Cursor returned as next_before_id"
+// @Param limit query int false "Page size, at most 200" default(50)
+// @Success 200 {object} dto.AuditEventPage
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /v1/admin/audit-events [get]
+func (h *AuditHandler) GetAuditEvents(c *fiber.Ctx, filter dto.AuditEventFilter) (dto.AuditEventPage, error) {
+	return h.services.Audit.GetAuditEvents(c.UserContext(), filter)
+}
@@ -1,13 +1,14 @@
 package handler
 
 import (
-	"context"
+	"crypto/hmac"
 	"crypto/rand"
-	"database/sql"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
-	"log"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
@@ -15,28 +16,23 @@ import (
 	"github.com/DiansSopandi/goride_be/errors"
 	"github.com/DiansSopandi/goride_be/middlewares"
 	"github.com/DiansSopandi/goride_be/pkg"
-	helper "github.com/DiansSopandi/goride_be/pkg/helper"
-	"github.com/DiansSopandi/goride_be/pkg/utils"
-	"github.com/DiansSopandi/goride_be/repository"
+	"github.com/DiansSopandi/goride_be/pkg/events"
+	"github.com/DiansSopandi/goride_be/pkg/i18n"
+	"github.com/DiansSopandi/goride_be/pkg/metrics"
+	"github.com/DiansSopandi/goride_be/pkg/oauth"
+	"github.com/DiansSopandi/goride_be/pkg/validation"
 	service "github.com/DiansSopandi/goride_be/services"
 	"github.com/gofiber/fiber/v2"
 	"golang.org/x/oauth2"
-	"google.golang.org/api/idtoken"
 )
 
 type AuthHandler struct {
-	AuthService *service.UserService
+	baseHandler
+	cfg pkg.ApplicationConfig
 }
 
-var (
-	oauthConfig     *oauth2.Config
-	appJWTSecret    []byte
-	frontendURL     string
-	appJWTExpiresIn time.Duration
-)
-
-func NewAuthHandler() *AuthHandler {
-	return &AuthHandler{}
+func NewAuthHandler(cfg pkg.ApplicationConfig, services *service.Services, mw *middlewares.Middlewares) *AuthHandler {
+	return &AuthHandler{baseHandler: baseHandler{services: services, mw: mw}, cfg: cfg}
 }
 
 func randState() (string, error) {
@@ -48,54 +44,102 @@ func randState() (string, error) {
 	return base64.RawURLEncoding.EncodeToString(b), nil
 }
 
-func OAuthConfig() *oauth2.Config {
-	clientID := pkg.Cfg.Application.GoogleClientID
-	clientSecret := pkg.Cfg.Application.GoogleClientSecret
-	redirectURI := pkg.Cfg.Application.GoogleRedirectURI
-
-	oauthConfig = &oauth2.Config{
-		ClientID:     clientID,
-		ClientSecret: clientSecret,
-		RedirectURL:  redirectURI,
-		Scopes:       []string{"openid", "email", "profile"},
-		Endpoint: oauth2.Endpoint{
-			AuthURL:  "https://accounts.google.com/o/oauth2/auth",
-			TokenURL: "https://oauth2.googleapis.com/token",
-		},
-	}
-	return oauthConfig
+func AuthRoutes(route fiber.Router, handler *AuthHandler) {
+	route.Get("/:provider/login", handler.GetOAuthLogin)
+	route.Get("/:provider/callback", handler.mw.WithTransaction(handler.GetOAuthCallback))
+	route.Post("/register", middlewares.Idempotency(), handler.mw.WithTransaction(RegisterUserHandler(handler)))
+	route.Post("/login", handler.mw.WithTransaction(LoginUserHandler(handler)))
+	route.Post("/logout", handler.mw.WithTransaction(LogoutUserHandler(handler)))
 }
 
-func AuthRoutes(route fiber.Router) {
-	handler := NewAuthHandler()
-	route.Get("/google/login", GetGoogleAuth)
-	route.Get("/google/callback", middlewares.WithTransaction(GetGoogleCallback))
-	route.Post("/register", middlewares.WithTransaction(RegisterUserHandler(handler)))
-	route.Post("/login", middlewares.WithTransaction(LoginUserHandler(handler)))
-	route.Post("/logout", middlewares.WithTransaction(LogoutUserHandler(handler)))
+const (
+	oauthModeLogin = "login"
+	oauthModeLink  = "link"
+)
+
+// oauthState is kept in the oauth_state cookie between the login redirect and the callback.
+type oauthState struct {
+	Mode     string `json:"m"`
+	UserID   uint   `json:"u,omitempty"` // user linking the provider, link mode only
+	Provider string `json:"p"`
+	State    string `json:"s"`
+	Nonce    string `json:"n"`
+	Verifier string `json:"v"`
+	ReturnTo string `json:"r"`
 }
 
-// GetGoogleAuth godoc
-// @Summary Google Auth
-// @Description Initiates Google OAuth2 login
-// @Tags Auth
-// @Produce json
-// @Success 200 {object} map[string]interface{}
-// @Failure 500 {object} map[string]interface{}
-// @Router /v1/auth/google/login [get]
-func GetGoogleAuth(c *fiber.Ctx) error {
-	OAuthConfig()
+// signOAuthState signs the cookie payload with secret, the link mode trusts UserID from it.
+func signOAuthState(secret, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func encodeOAuthState(secret string, st oauthState) (string, error) {
+	b, err := json.Marshal(st)
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(b)
+	return payload + "." + signOAuthState(secret, payload), nil
+}
+
+func decodeOAuthState(secret, value string) (oauthState, error) {
+	var st oauthState
+
+	payload, signature, ok := strings.Cut(value, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(signOAuthState(secret, payload))) {
+		return st, fmt.Errorf("invalid oauth state signature")
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return st, err
+	}
+	err = json.Unmarshal(b, &st)
+	return st, err
+}
+
+// startOAuthFlow stores state, nonce and PKCE verifier in the oauth_state cookie
+// and returns the provider consent url.
+func startOAuthFlow(c *fiber.Ctx, cfg pkg.ApplicationConfig, provider *oauth.Provider, mode string, userID uint) (string, error) {
 	state, err := randState()
 	if err != nil {
-		return fiber.NewError(500, "failed to create state")
+		return "", fiber.NewError(500, "failed to create state")
+	}
+	nonce, err := randState()
+	if err != nil {
+		return "", fiber.NewError(500, "failed to create nonce")
+	}
+	returnTo, err := oauthReturnURL(cfg.FrontendURL, c.Query("returnTo"))
+	if err != nil {
+		return "", err
+	}
+
+	st := oauthState{
+		Mode:     mode,
+		UserID:   userID,
+		Provider: provider.Name,
+		State:    state,
+		Nonce:    nonce,
+		Verifier: oauth2.GenerateVerifier(),
+		ReturnTo: returnTo,
+	}
+
+	authURL, err := provider.AuthCodeURL(st.State, st.Nonce, st.Verifier)
+	if err != nil {
+		return "", errors.InternalError(fmt.Sprintf("failed to build auth url: %v", err))
+	}
+
+	cookieValue, err := encodeOAuthState(cfg.JwtSecretKey, st)
+	if err != nil {
+		return "", fiber.NewError(500, "failed to create state")
 	}
 
-	// Optionally allow returnTo param to persist across redirects
-	returnTo := c.Query("returnTo", "/")
 	// Store state in cookie for CSRF protection
 	c.Cookie(&fiber.Cookie{
 		Name:     "oauth_state",
-		Value:    state + "|" + url.QueryEscape(returnTo),
+		Value:    cookieValue,
 		HTTPOnly: true,
 		Secure:   false, // set true in production with HTTPS
 		SameSite: "Lax",
@@ -103,24 +147,95 @@ func GetGoogleAuth(c *fiber.Ctx) error {
 		Expires:  time.Now().Add(10 * time.Minute),
 	})
 
-	authURL := oauthConfig.AuthCodeURL(state, oauth2.AccessTypeOffline)
+	return authURL, nil
+}
+
+// oauthReturnURL resolves the returnTo of an OAuth flow to the url the callback redirects to. A path is
+// relative to application.frontend_url, a full url must be on the frontend or one of the cors origins,
+// anything else would make the callback an open redirect.
+func oauthReturnURL(frontendURL, returnTo string) (string, error) {
+	if returnTo == "" {
+		return frontendURL, nil
+	}
+	if strings.ContainsAny(returnTo, "\\\r\n\t") {
+		return "", errors.InvalidInput(fmt.Sprintf("invalid returnTo %q", returnTo))
+	}
+
+	if strings.HasPrefix(returnTo, "/") && !strings.HasPrefix(returnTo, "//") {
+		return strings.TrimSuffix(frontendURL, "/") + returnTo, nil
+	}
+
+	u, err := url.Parse(returnTo)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || !allowedFrontendOrigin(frontendURL, u.Scheme+"://"+u.Host) {
+		return "", errors.InvalidInput(fmt.Sprintf("returnTo %q is not on an allowed frontend origin", returnTo))
+	}
+	return returnTo, nil
+}
+
+// allowedFrontendOrigin reports whether origin is the one of frontendURL or one of the cors origins,
+// "https://*.example.com" allows every subdomain.
+func allowedFrontendOrigin(frontendURL, origin string) bool {
+	origins := strings.Split(pkg.Runtime().CorsOrigins, ",")
+	if u, err := url.Parse(frontendURL); err == nil && u.Host != "" {
+		origins = append(origins, u.Scheme+"://"+u.Host)
+	}
+
+	origin = strings.ToLower(origin)
+	for _, allowed := range origins {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		if allowed == origin {
+			return true
+		}
+		if scheme, domain, ok := strings.Cut(allowed, "://*."); ok && strings.HasPrefix(origin, scheme+"://") && strings.HasSuffix(origin, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetOAuthLogin godoc
+// @Summary OAuth Login
+// @Description Initiates the OAuth2 / OIDC login of a configured provider (google, github, facebook, apple, ...)
+// @Tags Auth
+// @Produce json
+// @Param provider path string true "Provider name" example(google)
+// @Param returnTo query string false "Path on the frontend, or url on an allowed frontend origin, to return to after login"
+// @Success 302
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /v1/auth/{provider}/login [get]
+func (h *AuthHandler) GetOAuthLogin(c *fiber.Ctx) error {
+	provider, ok := oauth.GetProvider(c.Params("provider"))
+	if !ok {
+		return errors.ResourceNotFound(fmt.Sprintf("oauth provider %q is not configured", c.Params("provider")))
+	}
+
+	authURL, err := startOAuthFlow(c, h.cfg, provider, oauthModeLogin, 0)
+	if err != nil {
+		return err
+	}
+
 	return c.Redirect(authURL)
 }
 
-// GetGoogleCallback godoc
-// @Summary Google Callback
-// @Description Handles Google OAuth2 callback
+// GetOAuthCallback godoc
+// @Summary OAuth Callback
+// @Description Handles the OAuth2 / OIDC callback of a configured provider
 // @Tags Auth
 // @Produce json
-// @Success 200 {object} map[string]interface{}
+// @Param provider path string true "Provider name" example(google)
+// @Success 302
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
-// @Router /v1/auth/google/callback [get]
-func GetGoogleCallback(c *fiber.Ctx) error {
-	frontendURL := pkg.Cfg.Application.FrontendURL
-	// appJWTSecret := pkg.Cfg.Application.JwtSecretKey
+// @Router /v1/auth/{provider}/callback [get]
+func (h *AuthHandler) GetOAuthCallback(c *fiber.Ctx) error {
+	provider, ok := oauth.GetProvider(c.Params("provider"))
+	if !ok {
+		return errors.ResourceNotFound(fmt.Sprintf("oauth provider %q is not configured", c.Params("provider")))
+	}
 
-	OAuthConfig()
-	ctx := context.Background()
+	ctx := c.UserContext()
 	state := c.Query("state")
 	code := c.Query("code")
 	if state == "" || code == "" {
@@ -128,85 +243,67 @@ func GetGoogleCallback(c *fiber.Ctx) error {
 	}
 
 	// Validate state from cookie
-	st := c.Cookies("oauth_state")
-	parts := strings.SplitN(st, "|", 2)
-	if st == "" || parts[0] != state {
+	st, err := decodeOAuthState(h.cfg.JwtSecretKey, c.Cookies("oauth_state"))
+	if err != nil || st.State != state || st.Provider != provider.Name {
 		return fiber.NewError(400, "invalid state")
 	}
-	returnTo, _ := url.QueryUnescape(parts[1])
+	c.ClearCookie("oauth_state")
+
+	// checked by oauthReturnURL when the flow started, the cookie is signed
+	returnTo := st.ReturnTo
 	if returnTo == "" {
-		returnTo = "/"
+		returnTo = h.cfg.FrontendURL
 	}
 
-	tok, err := oauthConfig.Exchange(ctx, code)
+	tok, err := provider.Exchange(ctx, code, st.Verifier)
 	if err != nil {
-		log.Println("token exchange error:", err)
+		pkg.Logger(c).Warn("oauth token exchange failed", "provider", provider.Name, "error", err)
+		h.recordLoginFailure(c, provider.Name, "", "code_exchange_failed")
 		return fiber.NewError(401, "failed to exchange code")
 	}
 
-	rawIDToken, _ := tok.Extra("id_token").(string)
-	if rawIDToken == "" {
-		return fiber.NewError(401, "id_token not found in token response")
+	// Verify ID Token signature, audience and nonce (OIDC) or fetch userinfo (OAuth2)
+	info, err := provider.UserInfo(ctx, tok, st.Nonce)
+	if err != nil {
+		pkg.Logger(c).Warn("oauth userinfo failed", "provider", provider.Name, "error", err)
+		h.recordLoginFailure(c, provider.Name, "", "userinfo_failed")
+		return errors.InvalidToken(fmt.Sprintf("failed to read %s identity: %v", provider.Name, err))
 	}
 
-	// Verify ID Token signature & audience
-	payload, err := idtoken.Validate(ctx, rawIDToken, oauthConfig.ClientID)
+	services, err := h.txServices(c)
 	if err != nil {
-		log.Println("id token validate error:", err)
-		return fiber.NewError(401, "invalid id_token")
+		return err
 	}
 
-	// Extract user info from claims
-	email, _ := payload.Claims["email"].(string)
-	name, _ := payload.Claims["name"].(string)
-	picture, _ := payload.Claims["picture"].(string)
-	sub, _ := payload.Claims["sub"].(string) // Google user id
-	if email == "" || sub == "" {
-		return fiber.NewError(400, "missing email/sub in id_token")
+	if st.Mode == oauthModeLink {
+		link, err := services.Users.LinkOAuthProvider(c.UserContext(), st.UserID, info)
+		if err != nil {
+			return err
+		}
+		if err := h.recordAudit(c, st.UserID, "user_provider.link", "user", strconv.Itoa(int(st.UserID)), nil, link); err != nil {
+			return err
+		}
+		return c.Redirect(returnTo)
 	}
 
-	// === Upsert user in DB (pseudo) ===
-	// In production, check if user exists by provider=google & provider_id=sub
-	// If not exists, create user record linked to provider.
-
-	tx := c.Locals(middlewares.TxContextKey).(*sql.Tx)
-	userRepo, _ := repository.NewUserRepository(tx)
-	roleRepo, _ := repository.NewRoleRepository(tx)
-	userProviderRepo, _ := repository.NewUserProviderRepository(tx)
-
-	userServiceWithTx := service.NewUserService(userRepo, roleRepo, userProviderRepo)
-
-	fmt.Println("upserting google id:", sub)
-	user, err := userServiceWithTx.UpsertGoogleUser(tx, sub, email, name, picture)
+	user, err := services.Users.UpsertOAuthUser(c.UserContext(), info)
 	if err != nil {
-		return errors.InternalError(fmt.Sprintf("failed to upsert user: %v", err))
-	}
-	// userID := fmt.Sprintf("google:%s", sub)
-
-	// Issue your own app session JWT
-	// appToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-	// 	"sub":     userID,
-	// 	"email":   email,
-	// 	"name":    name,
-	// 	"picture": picture,
-	// 	"iat":     time.Now().Unix(),
-	// 	"exp":     time.Now().Add(appJWTExpiresIn).Unix(),
-	// })
-
-	// appJWT, err := appToken.SignedString(appJWTSecret)
-	// if err != nil {
-	// 	return fiber.NewError(500, "failed to sign app token")
-	// }
-	accessToken, refreshToken, err := utils.GenerateJWT(user.ID, user.Email)
+		h.recordLoginFailure(c, provider.Name, info.Email, loginFailureReason(err))
+		return err
+	}
+
+	tokens, err := services.Sessions.CreateSession(c.UserContext(), user.ID, user.Email, sessionMetaFromRequest(c, ""))
 	if err != nil {
-		return fiber.NewError(500, "failed to generate app token")
+		return err
+	}
+
+	if err := h.recordLoginAudit(c, user.ID, provider.Name, tokens.SessionID); err != nil {
+		return err
 	}
-	// Redirect back to frontend callback with token in query
-	// r := fmt.Sprintf("%s/auth/callback?token=%s&returnTo=%s", strings.TrimRight(frontendURL, "/"), url.QueryEscape(accessToken), url.QueryEscape(returnTo))
 
 	c.Cookie(&fiber.Cookie{
 		Name:     "jwt_at",
-		Value:    accessToken,
+		Value:    tokens.AccessToken,
 		Expires:  time.Now().Add(24 * time.Hour),
 		HTTPOnly: true,
 		Secure:   true,
@@ -215,33 +312,21 @@ func GetGoogleCallback(c *fiber.Ctx) error {
 
 	c.Cookie(&fiber.Cookie{
 		Name:     "jwt_rt",
-		Value:    refreshToken,
+		Value:    tokens.RefreshToken,
 		Expires:  time.Now().Add(7 * 24 * time.Hour),
 		HTTPOnly: true,
 		Secure:   true,
 		SameSite: "Lax",
 	})
 
-	// r := fmt.Sprintf(
-	// 	"%s/auth/callback?accessToken=%s&refreshToken=%s&returnTo=%s",
-	// 	strings.TrimRight(frontendURL, "/"),
-	// 	url.QueryEscape(accessToken),
-	// 	url.QueryEscape(refreshToken),
-	// 	url.QueryEscape(returnTo),
-	// )
-	// return c.Redirect(r)
-	return c.Redirect(frontendURL)
+	return c.Redirect(returnTo)
 }
 
 func RegisterUserHandler(handler *AuthHandler) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		var registerDto dto.UserRegisterRequest
-		if errDto := c.BodyParser(&registerDto); errDto != nil {
-			return pkg.ResponseApiErrorBadRequest(c, fmt.Sprintf("Failed to parse request body: %v", errDto))
-		}
-
-		if err := helper.ValidateRegisterUserRequest(&registerDto); err != nil {
-			return pkg.ResponseApiErrorBadRequest(c, fmt.Sprintf("Validation failed: %v", err))
+		registerDto, err := validation.BindAndValidate[dto.UserRegisterRequest](c)
+		if err != nil {
+			return err
 		}
 
 		res, err := handler.RegisterUser(c, registerDto)
@@ -257,9 +342,9 @@ func RegisterUserHandler(handler *AuthHandler) fiber.Handler {
 
 func LoginUserHandler(handler *AuthHandler) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		var loginDto dto.UserLoginRequest
-		if err := c.BodyParser(&loginDto); err != nil {
-			return pkg.ResponseApiErrorBadRequest(c, fmt.Sprintf("Failed to parse request body: %v", err))
+		loginDto, err := validation.BindAndValidate[dto.UserLoginRequest](c)
+		if err != nil {
+			return err
 		}
 
 		res, err := handler.LoginUser(c, loginDto)
@@ -280,35 +365,98 @@ func LoginUserHandler(handler *AuthHandler) fiber.Handler {
 // @router /v1/auth/logout [post]
 func LogoutUserHandler(handler *AuthHandler) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		// Clear cookies
-		// c.ClearCookie("jwt_at", "/", "")
-		// c.ClearCookie("jwt_rt", "/", "")
-
-		expired := time.Now().Add(-time.Hour)
-
-		c.Cookie(&fiber.Cookie{
-			Name:     "jwt_at",
-			Value:    "",
-			Expires:  expired,
-			Path:     "/",
-			HTTPOnly: true,
-			Secure:   true,
-			SameSite: "Strict",
-		})
-
-		c.Cookie(&fiber.Cookie{
-			Name:     "jwt_rt",
-			Value:    "",
-			Expires:  expired,
-			Path:     "/",
-			HTTPOnly: true,
-			Secure:   true,
-			SameSite: "Strict",
-		})
+		// Revoke the device session so the access token stops working right away
+		if userID, err := middlewares.CurrentUserID(c); err == nil {
+			if sid := middlewares.CurrentSessionID(c); sid != "" {
+				services, err := handler.txServices(c)
+				if err != nil {
+					return err
+				}
+				if err := services.Sessions.RevokeSession(c.UserContext(), userID, sid); err != nil {
+					if appErr, ok := err.(*errors.AppErrorResponse); !ok || appErr.Details.StatusCode != fiber.StatusNotFound {
+						return err
+					}
+				} else if err := handler.recordAudit(c, userID, "auth.logout", "session", sid, nil, nil); err != nil {
+					return err
+				}
+			}
+		}
+
+		clearAuthCookies(c)
 		return pkg.ResponseApiOK(c, "User logged out successfully", nil)
 	}
 }
 
+// recordLoginAudit records a successful login in the request transaction, see recordLoginFailure for the
+// failed attempts.
+func (h *AuthHandler) recordLoginAudit(c *fiber.Ctx, userID int, provider, sessionID string) error {
+	err := h.recordAudit(c, uint(userID), "auth.login", "user", strconv.Itoa(userID), nil, fiber.Map{
+		"provider":   provider,
+		"session_id": sessionID,
+		"user_agent": c.Get(fiber.HeaderUserAgent),
+	})
+	if err == nil {
+		metrics.LoginSucceeded(provider)
+	}
+	return err
+}
+
+// recordLoginFailure records a failed login with the services of the pool, the request transaction is
+// rolled back with the error. The attempt is still counted when the audit event cannot be written.
+func (h *AuthHandler) recordLoginFailure(c *fiber.Ctx, provider, email, reason string) {
+	metrics.LoginFailed(provider)
+
+	after := fiber.Map{
+		"provider":   provider,
+		"reason":     reason,
+		"user_agent": c.Get(fiber.HeaderUserAgent),
+	}
+	if email != "" {
+		after["email"] = email
+	}
+
+	if err := h.services.Audit.Record(c.UserContext(), auditActorFromRequest(c, 0), "auth.login_failed", "provider", provider, nil, after); err != nil {
+		pkg.Logger(c).Warn("failed to record a failed login", "provider", provider, "error", err)
+	}
+}
+
+// loginFailureReason is the error code of a failed login, e.g. INVALID_CREDENTIAL or PROVIDER_DISABLED.
+func loginFailureReason(err error) string {
+	if appErr, ok := err.(*errors.AppErrorResponse); ok {
+		return appErr.Code
+	}
+	return "INTERNAL_ERROR"
+}
+
+// clearAuthCookies expires the jwt_at and jwt_rt cookies.
+func clearAuthCookies(c *fiber.Ctx) {
+	// Clear cookies
+	// c.ClearCookie("jwt_at", "/", "")
+	// c.ClearCookie("jwt_rt", "/", "")
+
+	expired := time.Now().Add(-time.Hour)
+
+	c.Cookie(&fiber.Cookie{
+		Name:     "jwt_at",
+		Value:    "",
+		Expires:  expired,
+		Path:     "/",
+		HTTPOnly: true,
+		Secure:   true,
+		SameSite: "Strict",
+	})
+
+	c.Cookie(&fiber.Cookie{
+		Name:     "jwt_rt",
+		Value:    "",
+		Expires:  expired,
+		Path:     "/",
+		HTTPOnly: true,
+		Secure:   true,
+		SameSite: "Strict",
+	})
+}
+
 // RegisterUser handles user registration, including role assignment.
 // @summary Register a new user with roles
 // @description Register a new user and assign roles if provided.
@@ -316,24 +464,21 @@ func LogoutUserHandler(handler *AuthHandler) fiber.Handler {
 // @accept json
 // @produce json
 // @param registerDto body dto.UserRegisterRequest true "User registration data"
+// @param Idempotency-Key header string false "Makes retries safe, a retry with the same key and body replays the first response"
 // @success 200 {object} models.User "User registration successful"
 // @failure 400 {object} map[string]interface{} "Bad request, validation errors"
+// @failure 409 {object} map[string]interface{}
 // @failure 500 {object} map[string]interface{} "Internal server error, database or service errors"
 // @router /v1/auth/register [post]
 func (h *AuthHandler) RegisterUser(c *fiber.Ctx, regDto dto.UserRegisterRequest) (dto.UserResponse, error) {
-	tx := c.Locals(middlewares.TxContextKey).(*sql.Tx)
-	userRepo, _ := repository.NewUserRepository(tx)
-	roleRepo, _ := repository.NewRoleRepository(tx)
-	userProviderRepo, _ := repository.NewUserProviderRepository(tx)
-
-	userServiceWithTx := service.NewUserService(userRepo, roleRepo, userProviderRepo)
+	services, err := h.txServices(c)
+	if err != nil {
+		return dto.UserResponse{}, err
+	}
 
-	var (
-		roleIDs []int64
-		err     error
-	)
+	var roleIDs []int64
 	if len(regDto.Roles) > 0 {
-		roleIDs, err = userServiceWithTx.ValidateRolesExist(tx, regDto.Roles)
+		roleIDs, err = services.Users.ValidateRolesExist(c.UserContext(), regDto.Roles)
 
 		if err != nil {
 			return dto.UserResponse{}, errors.RoleValidationFailed(fmt.Sprintf("role validation failed: %v", err))
@@ -347,24 +492,40 @@ func (h *AuthHandler) RegisterUser(c *fiber.Ctx, regDto dto.UserRegisterRequest)
 		Roles:    []string{"user"},
 	}
 
-	res, err := userServiceWithTx.CreateUser(tx, &registerDto)
+	res, err := services.Users.CreateUser(c.UserContext(), &registerDto)
 	if err != nil {
 		return dto.UserResponse{}, err
 	}
 
 	if len(roleIDs) > 0 {
-		err = userServiceWithTx.AssignRolesToUserWithTx(tx, uint(res.ID), roleIDs)
+		err = services.Users.AssignRolesToUser(c.UserContext(), uint(res.ID), roleIDs)
 		if err != nil {
 			return dto.UserResponse{}, errors.InternalError(fmt.Sprintf("failed to assign roles to user: %v", err))
 		}
 	}
 
-	return dto.UserResponse{
+	userRes := dto.UserResponse{
 		ID:       uint(res.ID),
 		Username: &registerDto.Username,
 		Email:    res.Email,
 		Roles:    registerDto.Roles,
-	}, nil
+	}
+
+	if err := h.recordAudit(c, uint(res.ID), "user.register", "user", strconv.Itoa(res.ID), nil, userRes); err != nil {
+		return dto.UserResponse{}, err
+	}
+
+	registered := events.UserRegisteredPayload{
+		UserID:   uint(res.ID),
+		Username: registerDto.Username,
+		Email:    res.Email,
+		Language: i18n.Negotiate(c.Get(fiber.HeaderAcceptLanguage)),
+	}
+	if err := h.enqueueEvent(c, events.UserRegistered, "user", strconv.Itoa(res.ID), registered); err != nil {
+		return dto.UserResponse{}, err
+	}
+
+	return userRes, nil
 }
 
 // LoginUser handles user login and returns user details and token.
@@ -379,18 +540,28 @@ func (h *AuthHandler) RegisterUser(c *fiber.Ctx, regDto dto.UserRegisterRequest)
 // @failure 500 {object} map[string]interface{} "Internal server error, database or service errors"
 // @router /v1/auth/login [post]
 func (h *AuthHandler) LoginUser(c *fiber.Ctx, loginDto dto.UserLoginRequest) (dto.UserLoginResponse, error) {
-	tx := c.Locals(middlewares.TxContextKey).(*sql.Tx)
-	userRepo, _ := repository.NewUserRepository(tx)
-	roleRepo, _ := repository.NewRoleRepository(tx)
-	userProviderRepo, _ := repository.NewUserProviderRepository(tx)
+	services, err := h.txServices(c)
+	if err != nil {
+		return dto.UserLoginResponse{}, err
+	}
 
-	userServiceWithTx := service.NewUserService(userRepo, roleRepo, userProviderRepo)
+	res, err := services.Users.LoginUser(c.UserContext(), loginDto)
+	if err != nil {
+		h.recordLoginFailure(c, "local", loginDto.Email, loginFailureReason(err))
+		return dto.UserLoginResponse{}, err
+	}
 
-	res, err := userServiceWithTx.LoginUser(tx, loginDto)
+	tokens, err := services.Sessions.CreateSession(c.UserContext(), int(res.User.ID), res.User.Email, sessionMetaFromRequest(c, loginDto.DeviceName))
 	if err != nil {
 		return dto.UserLoginResponse{}, err
 	}
 
+	if err := h.recordLoginAudit(c, int(res.User.ID), "local", tokens.SessionID); err != nil {
+		return dto.UserLoginResponse{}, err
+	}
+	res.AccessToken = tokens.AccessToken
+	res.RefreshToken = tokens.RefreshToken
+
 	// Set cookie Access Token
 	c.Cookie(&fiber.Cookie{
 		Name:  "jwt_at",
@@ -0,0 +1,171 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	stderrors "errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DiansSopandi/goride_be/middlewares"
+	"github.com/DiansSopandi/goride_be/pkg"
+	"github.com/DiansSopandi/goride_be/pkg/utils"
+	service "github.com/DiansSopandi/goride_be/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestLoginWithWrongPasswordIsAudited(t *testing.T) {
+	hash, err := utils.HashPassword("right-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := &fakeStore{users: map[string]fakeUser{
+		"rider@example.com": {id: 7, username: "rider", passwordHash: hash},
+	}}
+	database := sql.OpenDB(store)
+	defer database.Close()
+
+	services := service.NewServices(database, nil)
+	var cfg pkg.Config
+	mw := middlewares.NewMiddlewares(cfg, database, services)
+	app := fiber.New(fiber.Config{ErrorHandler: mw.ErrorHandler})
+	AuthRoutes(app.Group("/v1/auth"), NewAuthHandler(cfg.Application, services, mw))
+
+	req := httptest.NewRequest("POST", "/v1/auth/login", strings.NewReader(`{"email":"rider@example.com","password":"wrong-password"}`))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	res, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != fiber.StatusUnauthorized {
+		t.Fatalf("got status %d, want %d", res.StatusCode, fiber.StatusUnauthorized)
+	}
+
+	if got := store.committedAudit(); len(got) != 1 || got[0] != "auth.login_failed" {
+		t.Fatalf("committed audit events %v, want [auth.login_failed]", got)
+	}
+}
+
+// fakeStore is a database/sql connector answering the queries of a local login, it keeps the audit
+// events of the committed transactions and of the statements run outside of one.
+type fakeStore struct {
+	mu        sync.Mutex
+	users     map[string]fakeUser
+	committed []string
+}
+
+type fakeUser struct {
+	id           int64
+	username     string
+	passwordHash string
+}
+
+func (s *fakeStore) Connect(context.Context) (driver.Conn, error) {
+	return &fakeConn{store: s}, nil
+}
+
+func (s *fakeStore) Driver() driver.Driver {
+	return fakeDriver{s}
+}
+
+func (s *fakeStore) committedAudit() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.committed...)
+}
+
+type fakeDriver struct {
+	store *fakeStore
+}
+
+func (d fakeDriver) Open(string) (driver.Conn, error) {
+	return &fakeConn{store: d.store}, nil
+}
+
+type fakeConn struct {
+	store   *fakeStore
+	inTx    bool
+	pending []string
+}
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, stderrors.New("fake conn: prepared statements are not supported")
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.inTx = true
+	c.pending = nil
+	return c, nil
+}
+
+func (c *fakeConn) Commit() error {
+	c.store.mu.Lock()
+	c.store.committed = append(c.store.committed, c.pending...)
+	c.store.mu.Unlock()
+	c.inTx, c.pending = false, nil
+	return nil
+}
+
+func (c *fakeConn) Rollback() error {
+	c.inTx, c.pending = false, nil
+	return nil
+}
+
+func (c *fakeConn) ExecContext(context.Context, string, []driver.NamedValue) (driver.Result, error) {
+	return driver.RowsAffected(0), nil
+}
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	switch {
+	case strings.Contains(query, "INSERT INTO audit_events"):
+		action, _ := args[2].Value.(string)
+		if c.inTx {
+			c.pending = append(c.pending, action)
+		} else {
+			c.store.mu.Lock()
+			c.store.committed = append(c.store.committed, action)
+			c.store.mu.Unlock()
+		}
+		return &fakeRows{columns: []string{"id", "created_at"}, values: [][]driver.Value{{int64(1), time.Now()}}}, nil
+
+	case strings.Contains(query, "FROM users WHERE email"):
+		rows := &fakeRows{columns: []string{"id", "username", "email", "password", "created_at", "updated_at", "deleted_at"}}
+		email, _ := args[0].Value.(string)
+		if user, ok := c.store.users[email]; ok {
+			rows.values = append(rows.values, []driver.Value{user.id, user.username, email, user.passwordHash, time.Now(), time.Now(), nil})
+		}
+		return rows, nil
+	}
+	return &fakeRows{}, nil
+}
+
+type fakeRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	return r.columns
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
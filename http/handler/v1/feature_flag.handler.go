@@ -0,0 +1,213 @@
+package handler
+
+import (
+	"log/slog"
+
+	"github.com/DiansSopandi/goride_be/dto"
+	"github.com/DiansSopandi/goride_be/middlewares"
+	"github.com/DiansSopandi/goride_be/models"
+	"github.com/DiansSopandi/goride_be/pkg"
+	"github.com/DiansSopandi/goride_be/pkg/validation"
+	service "github.com/DiansSopandi/goride_be/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+type FeatureFlagHandler struct {
+	baseHandler
+}
+
+func NewFeatureFlagHandler(services *service.Services, mw *middlewares.Middlewares) *FeatureFlagHandler {
+	return &FeatureFlagHandler{baseHandler{services: services, mw: mw}}
+}
+
+// FeatureFlagAdminRoutes registers the flag management routes under /admin.
+func FeatureFlagAdminRoutes(route fiber.Router, handler *FeatureFlagHandler) {
+	route.Get("/feature-flags", GetFeatureFlagsHandler(handler))
+	route.Post("/feature-flags", middlewares.Idempotency(), invalidateFlagsOnSuccess(handler.mw.WithTransaction(CreateFeatureFlagHandler(handler))))
+	route.Patch("/feature-flags/:key", middlewares.Idempotency(), invalidateFlagsOnSuccess(handler.mw.WithTransaction(UpdateFeatureFlagHandler(handler))))
+	route.Delete("/feature-flags/:key", invalidateFlagsOnSuccess(handler.mw.WithTransaction(DeleteFeatureFlagHandler(handler))))
+}
+
+// FeatureFlagRoutes registers the flags of the authenticated user under /me.
+func FeatureFlagRoutes(route fiber.Router, handler *FeatureFlagHandler) {
+	route.Get("/flags", GetMyFlagsHandler(handler))
+}
+
+// invalidateFlagsOnSuccess drops the cached flags once the change of next is committed, dropping them
+// inside the transaction would let a concurrent request cache the flags of before the change.
+func invalidateFlagsOnSuccess(next fiber.Handler) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := next(c); err != nil {
+			return err
+		}
+		if err := pkg.InvalidateFeatureFlags(c.UserContext()); err != nil {
+			slog.Warn("failed to invalidate the feature flag cache, the change shows up once it expires", "error", err)
+		}
+		return nil
+	}
+}
+
+func GetFeatureFlagsHandler(handler *FeatureFlagHandler) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		res, err := handler.GetFeatureFlags(c)
+		if err != nil {
+			return err
+		}
+
+		return pkg.ResponseApiOK(c, "Feature flags fetch successfully...", res)
+	}
+}
+
+func CreateFeatureFlagHandler(handler *FeatureFlagHandler) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		req, err := validation.BindAndValidate[dto.FeatureFlagCreateRequest](c)
+		if err != nil {
+			return err
+		}
+
+		res, err := handler.CreateFeatureFlag(c, auditActorFromRequest(c, 0), req)
+		if err != nil {
+			return err
+		}
+
+		return pkg.ResponseApiOK(c, "Feature flag created successfully", res)
+	}
+}
+
+func UpdateFeatureFlagHandler(handler *FeatureFlagHandler) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		req, err := validation.BindAndValidate[dto.FeatureFlagUpdateRequest](c)
+		if err != nil {
+			return err
+		}
+
+		res, err := handler.UpdateFeatureFlag(c, auditActorFromRequest(c, 0), c.Params("key"), req)
+		if err != nil {
+			return err
+		}
+
+		return pkg.ResponseApiOK(c, "Feature flag updated successfully", res)
+	}
+}
+
+func DeleteFeatureFlagHandler(handler *FeatureFlagHandler) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := handler.DeleteFeatureFlag(c, auditActorFromRequest(c, 0), c.Params("key")); err != nil {
+			return err
+		}
+
+		return pkg.ResponseApiOK(c, "Feature flag deleted successfully", nil)
+	}
+}
+
+func GetMyFlagsHandler(handler *FeatureFlagHandler) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		res, err := handler.GetMyFlags(c)
+		if err != nil {
+			return err
+		}
+
+		return pkg.ResponseApiOK(c, "Feature flags fetch successfully...", res)
+	}
+}
+
+// GetFeatureFlags
+// @Summary List feature flags
+// @Description List every feature flag with its rollout and targeting
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.FeatureFlag
+// @Failure 403 {object} map[string]interface{}
+// @Router /v1/admin/feature-flags [get]
+func (h *FeatureFlagHandler) GetFeatureFlags(c *fiber.Ctx) ([]models.FeatureFlag, error) {
+	return h.services.Flags.GetFeatureFlags(c.UserContext())
+}
+
+// CreateFeatureFlag
+// @Summary Create a feature flag
+// @Description Create a flag. It is on for the users matching roles and cities (empty matches everyone) whose hashed user id falls in rollout_percentage
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body dto.FeatureFlagCreateRequest true "Feature flag"
+// @Param Idempotency-Key header string false "Makes retries safe, a retry with the same key and body replays the first response"
+// @Success 200 {object} models.FeatureFlag
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /v1/admin/feature-flags [post]
+func (h *FeatureFlagHandler) CreateFeatureFlag(c *fiber.Ctx, actor dto.AuditActor, req dto.FeatureFlagCreateRequest) (*models.FeatureFlag, error) {
+	services, err := h.txServices(c)
+	if err != nil {
+		return nil, err
+	}
+	return services.Flags.CreateFeatureFlag(c.UserContext(), actor, req)
+}
+
+// UpdateFeatureFlag
+// @Summary Update a feature flag
+// @Description Change the fields that are set, "enabled": false turns the feature off for everyone
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param key path string true "Feature flag key"
+// @Param request body dto.FeatureFlagUpdateRequest true "Fields to change"
+// @Param Idempotency-Key header string false "Makes retries safe, a retry with the same key and body replays the first response"
+// @Success 200 {object} models.FeatureFlag
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /v1/admin/feature-flags/{key} [patch]
+func (h *FeatureFlagHandler) UpdateFeatureFlag(c *fiber.Ctx, actor dto.AuditActor, key string, req dto.FeatureFlagUpdateRequest) (*models.FeatureFlag, error) {
+	services, err := h.txServices(c)
+	if err != nil {
+		return nil, err
+	}
+	return services.Flags.UpdateFeatureFlag(c.UserContext(), actor, key, req)
+}
+
+// DeleteFeatureFlag
+// @Summary Delete a feature flag
+// @Description Delete a flag, checks of a deleted flag report it off
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param key path string true "Feature flag key"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /v1/admin/feature-flags/{key} [delete]
+func (h *FeatureFlagHandler) DeleteFeatureFlag(c *fiber.Ctx, actor dto.AuditActor, key string) error {
+	services, err := h.txServices(c)
+	if err != nil {
+		return err
+	}
+	return services.Flags.DeleteFeatureFlag(c.UserContext(), actor, key)
+}
+
+// GetMyFlags
+// @Summary Get my feature flags
+// @Description Every feature flag evaluated for the authenticated user, the apps send their city in X-City for city targeted flags
+// @Tags Me
+// @Produce json
+// @Security BearerAuth
+// @Param X-City header string false "City the app operates in" example(Jakarta)
+// @Success 200 {object} dto.FeatureFlagsResponse
+// @Failure 401 {object} map[string]interface{}
+// @Router /v1/me/flags [get]
+func (h *FeatureFlagHandler) GetMyFlags(c *fiber.Ctx) (dto.FeatureFlagsResponse, error) {
+	subject, err := h.mw.FlagSubject(c)
+	if err != nil {
+		return dto.FeatureFlagsResponse{}, err
+	}
+
+	flags, err := h.services.Flags.EvaluateFlags(c.UserContext(), subject)
+	if err != nil {
+		return dto.FeatureFlagsResponse{}, err
+	}
+	return dto.FeatureFlagsResponse{Flags: flags}, nil
+}
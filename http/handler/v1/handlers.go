@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/DiansSopandi/goride_be/errors"
+	"github.com/DiansSopandi/goride_be/middlewares"
+	"github.com/DiansSopandi/goride_be/pkg"
+	service "github.com/DiansSopandi/goride_be/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Handlers holds the API handlers, the bootstrap container builds them once at startup.
+type Handlers struct {
+	Auth         *AuthHandler
+	User         *UserHandler
+	Role         *RoleHandler
+	UserProvider *UserProviderHandler
+	Session      *SessionHandler
+	ApiKey       *ApiKeyHandler
+	Audit        *AuditHandler
+	Settings     *SettingsHandler
+	FeatureFlag  *FeatureFlagHandler
+	Health       *HealthHandler
+}
+
+func NewHandlers(cfg pkg.Config, database, replica *sql.DB, services *service.Services, mw *middlewares.Middlewares) *Handlers {
+	return &Handlers{
+		Auth:         NewAuthHandler(cfg.Application, services, mw),
+		User:         NewUserHandler(services, mw),
+		Role:         NewRoleHandler(services, mw),
+		UserProvider: NewUserProviderHandler(cfg.Application, services, mw),
+		Session:      NewSessionHandler(services, mw),
+		ApiKey:       NewApiKeyHandler(services, mw),
+		Audit:        NewAuditHandler(services, mw),
+		Settings:     NewSettingsHandler(services, mw),
+		FeatureFlag:  NewFeatureFlagHandler(services, mw),
+		Health:       NewHealthHandler(cfg.Application, database, replica),
+	}
+}
+
+// baseHandler gives a handler the services bound to the pool and the middlewares reaching the database.
+type baseHandler struct {
+	services *service.Services
+	mw       *middlewares.Middlewares
+}
+
+// txServices returns the services bound to the request transaction, see middlewares.WithTransaction.
+func (h baseHandler) txServices(c *fiber.Ctx) (*service.Services, error) {
+	tx, ok := c.Locals(middlewares.TxContextKey).(*sql.Tx)
+	if !ok || tx == nil {
+		return nil, errors.InternalError(fmt.Sprintf("%s %s requires a transaction", c.Method(), c.Route().Path))
+	}
+	return h.services.WithTx(tx), nil
+}
+
+// enqueueEvent writes a domain event in the transaction of the request, it is published once committed.
+func (h baseHandler) enqueueEvent(c *fiber.Ctx, eventType, aggregateType, aggregateID string, payload any) error {
+	services, err := h.txServices(c)
+	if err != nil {
+		return err
+	}
+
+	return services.Outbox.Enqueue(c.UserContext(), pkg.RequestID(c), eventType, aggregateType, aggregateID, payload)
+}
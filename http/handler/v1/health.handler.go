@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"github.com/DiansSopandi/goride_be/db"
+	"github.com/DiansSopandi/goride_be/pkg"
+	"github.com/DiansSopandi/goride_be/pkg/health"
+	"github.com/gofiber/fiber/v2"
+)
+
+const defaultHealthCheckTimeout = 2 * time.Second
+
+type HealthHandler struct {
+	db           *sql.DB
+	replica      *sql.DB
+	checkTimeout time.Duration
+}
+
+func NewHealthHandler(cfg pkg.ApplicationConfig, database, replica *sql.DB) *HealthHandler {
+	return &HealthHandler{db: database, replica: replica, checkTimeout: healthCheckTimeout(cfg.HealthCheckTimeout)}
+}
+
+// HealthRoutes registers the probes, /health is kept as an alias of /health/ready.
+func HealthRoutes(route fiber.Router, handler *HealthHandler) {
+	route.Get("/", handler.GetReadiness)
+	route.Get("/live", GetLiveness)
+	route.Get("/ready", handler.GetReadiness)
+}
+
+// GetLiveness godoc
+// @Summary Liveness probe
+// @Description Answers as long as the process serves requests, dependencies are not checked
+// @Tags Health
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /v1/health/live [get]
+func GetLiveness(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"ok": true, "status": "alive"})
+}
+
+// GetReadiness godoc
+// @Summary Readiness probe
+// @Description Checks Postgres, Redis, the migration version and the background workers. Returns 503 when one of them fails or the server is shutting down
+// @Tags Health
+// @Produce json
+// @Success 200 {object} health.Report
+// @Failure 503 {object} health.Report
+// @Router /v1/health/ready [get]
+func (h *HealthHandler) GetReadiness(c *fiber.Ctx) error {
+	report := health.Run(c.UserContext(), h.checkTimeout, h.readinessChecks()...)
+
+	status := fiber.StatusOK
+	if !report.OK {
+		status = fiber.StatusServiceUnavailable
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.Status(status).JSON(report)
+}
+
+func (h *HealthHandler) readinessChecks() []health.Check {
+	checks := []health.Check{
+		{Name: "postgres", Run: h.db.PingContext},
+		{Name: "redis", Run: pkg.PingRedis},
+		{Name: "migrations", Run: func(ctx context.Context) error {
+			return db.CheckMigrationVersion(ctx, h.db)
+		}},
+	}
+	if h.replica != nil {
+		checks = append(checks, health.Check{Name: "postgres_replica", Run: h.replica.PingContext})
+	}
+	return checks
+}
+
+func healthCheckTimeout(value string) time.Duration {
+	if value == "" {
+		return defaultHealthCheckTimeout
+	}
+
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout <= 0 {
+		slog.Warn("invalid application.health_check_timeout, using the default", "value", value, "default", defaultHealthCheckTimeout)
+		return defaultHealthCheckTimeout
+	}
+	return timeout
+}
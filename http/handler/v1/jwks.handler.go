@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"fmt"
+
+	"github.com/DiansSopandi/goride_be/errors"
+	"github.com/DiansSopandi/goride_be/pkg/jwks"
+	"github.com/gofiber/fiber/v2"
+)
+
+// JwksRoutes registers the well-known endpoints at the application root, outside of app_path.
+func JwksRoutes(route fiber.Router) {
+	route.Get("/.well-known/jwks.json", GetJwks)
+}
+
+// GetJwks godoc
+// @Summary JSON Web Key Set
+// @Description Public keys used to verify access tokens, other services select the key by the token kid header
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} jwks.JWKSet
+// @Failure 500 {object} map[string]interface{}
+// @Router /.well-known/jwks.json [get]
+func GetJwks(c *fiber.Ctx) error {
+	set, err := jwks.PublicJWKS()
+	if err != nil {
+		return errors.InternalError(fmt.Sprintf("Failed to load jwks: %v", err))
+	}
+
+	// Standard JWKS format, not wrapped in the api response envelope
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.JSON(set)
+}
@@ -0,0 +1,15 @@
+package handler
+
+import (
+	"github.com/DiansSopandi/goride_be/middlewares"
+	"github.com/DiansSopandi/goride_be/pkg/metrics"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// MetricsRoutes registers the Prometheus scrape endpoint at the application root, outside of app_path.
+func MetricsRoutes(route fiber.Router, mw *middlewares.Middlewares) {
+	handler := adaptor.HTTPHandler(promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{}))
+	route.Get("/metrics", mw.MetricsGuard, handler)
+}
@@ -1,42 +1,41 @@
 package handler
 
 import (
-	"database/sql"
 	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/DiansSopandi/goride_be/dto"
 	"github.com/DiansSopandi/goride_be/errors"
 	"github.com/DiansSopandi/goride_be/middlewares"
 	"github.com/DiansSopandi/goride_be/models"
 	"github.com/DiansSopandi/goride_be/pkg"
-	"github.com/DiansSopandi/goride_be/repository"
+	"github.com/DiansSopandi/goride_be/pkg/cache"
+	"github.com/DiansSopandi/goride_be/pkg/validation"
 	service "github.com/DiansSopandi/goride_be/services"
 	"github.com/gofiber/fiber/v2"
 )
 
 type RoleHandler struct {
-	service *service.RoleService
+	baseHandler
 }
 
-func NewRoleHandler() *RoleHandler {
-	var tx *sql.Tx
-	// roleRepo, _ := repository.NewRoleRepository(false)
-	roleRepo, _ := repository.NewRoleRepository(tx)
-	roleService := service.NewRoleService(roleRepo)
-	return &RoleHandler{
-		service: roleService,
-	}
+func NewRoleHandler(services *service.Services, mw *middlewares.Middlewares) *RoleHandler {
+	return &RoleHandler{baseHandler{services: services, mw: mw}}
 }
 
-func RolesRoutes(route fiber.Router) {
-	handler := NewRoleHandler()
-	route.Get("/roles", GetAllRolesHandler(handler))
-	route.Post("/roles", middlewares.WithTransaction(CreateRoleHandler(handler)))
+func RolesRoutes(route fiber.Router, handler *RoleHandler) {
+	route.Get("/roles", middlewares.RequireScopes("roles:read"), middlewares.ResponseCache(middlewares.CacheConfig{
+		TTL:    5 * time.Minute,
+		Tags:   []string{cache.RolesTag},
+		Shared: true,
+	}), GetAllRolesHandler(handler))
+	route.Post("/roles", middlewares.RequireScopes("roles:write"), middlewares.Idempotency(), handler.mw.WithTransaction(CreateRoleHandler(handler)))
 }
 
 func GetAllRolesHandler(handler *RoleHandler) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		roles, err := handler.GetAllRoles()
+		roles, err := handler.GetAllRoles(c)
 		if err != nil {
 			return errors.InternalError(fmt.Sprintf("Failed to fetch roles: %v", err))
 		}
@@ -53,10 +52,9 @@ func GetAllRolesHandler(handler *RoleHandler) fiber.Handler {
 
 func CreateRoleHandler(handler *RoleHandler) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		var createRoledto dto.RoleCreateRequest
-
-		if err := c.BodyParser(&createRoledto); err != nil {
-			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		createRoledto, err := validation.BindAndValidate[dto.RoleCreateRequest](c)
+		if err != nil {
+			return err
 		}
 
 		res, err := handler.CreateRole(c, &createRoledto)
@@ -78,10 +76,13 @@ func CreateRoleHandler(handler *RoleHandler) fiber.Handler {
 // @Accept json
 // @Produce json
 // @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param If-None-Match header string false "ETag of a previous response, answered with 304 when it still matches"
 // @Success 200 {array} models.Role
+// @Success 304 "Not modified"
 // @Router /v1/roles [get]
-func (h *RoleHandler) GetAllRoles() ([]models.Role, error) {
-	return h.service.GetAllRoles()
+func (h *RoleHandler) GetAllRoles(c *fiber.Ctx) ([]models.Role, error) {
+	return h.services.Roles.GetAllRoles(c.UserContext())
 }
 
 // CreateRole
@@ -92,21 +93,31 @@ func (h *RoleHandler) GetAllRoles() ([]models.Role, error) {
 // @Produce json
 // @Param roleDto body dto.RoleCreateRequest true "Create Role Request"
 // @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param Idempotency-Key header string false "Makes retries safe, a retry with the same key and body replays the first response"
 // @Success 200 {object} models.Role
+// @Failure 409 {object} map[string]interface{}
 // @Router /v1/roles [post]
 func (h *RoleHandler) CreateRole(c *fiber.Ctx, roleDto *dto.RoleCreateRequest) (models.Role, error) {
-	// Ambil TX dari context
-	tx := c.Locals(middlewares.TxContextKey).(*sql.Tx)
-	// isTx := tx != nil
-	// roleRepo, err := repository.NewRoleRepository(isTx)
-	roleRepo, _ := repository.NewRoleRepository(tx)
+	// Ambil services dari transaksi request
+	services, err := h.txServices(c)
+	if err != nil {
+		return models.Role{}, err
+	}
 
 	role := models.Role{
 		Name:        roleDto.Name,
 		Description: roleDto.Description,
 	}
 
-	roleServiceWithTx := service.NewRoleService(roleRepo)
+	res, err := services.Roles.CreateRoles(c.UserContext(), &role)
+	if err != nil {
+		return models.Role{}, err
+	}
+
+	if err := h.recordAudit(c, 0, "role.create", "role", strconv.Itoa(res.ID), nil, res); err != nil {
+		return models.Role{}, err
+	}
 
-	return roleServiceWithTx.CreateRoles(tx, &role)
+	return res, nil
 }
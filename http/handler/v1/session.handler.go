@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"strconv"
+
+	"github.com/DiansSopandi/goride_be/dto"
+	"github.com/DiansSopandi/goride_be/middlewares"
+	"github.com/DiansSopandi/goride_be/pkg"
+	service "github.com/DiansSopandi/goride_be/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+type SessionHandler struct {
+	baseHandler
+}
+
+func NewSessionHandler(services *service.Services, mw *middlewares.Middlewares) *SessionHandler {
+	return &SessionHandler{baseHandler{services: services, mw: mw}}
+}
+
+// SessionRoutes registers the active device routes of the authenticated user under /me.
+func SessionRoutes(route fiber.Router, handler *SessionHandler) {
+	route.Get("/sessions", GetSessionsHandler(handler))
+	route.Delete("/sessions", handler.mw.WithTransaction(RevokeAllSessionsHandler(handler)))
+	route.Delete("/sessions/:id", handler.mw.WithTransaction(RevokeSessionHandler(handler)))
+}
+
+// sessionMetaFromRequest describes the device of the current request, deviceName falls back to X-Device-Name.
+func sessionMetaFromRequest(c *fiber.Ctx, deviceName string) dto.SessionMeta {
+	if deviceName == "" {
+		deviceName = c.Get("X-Device-Name")
+	}
+
+	return dto.SessionMeta{
+		DeviceName: deviceName,
+		UserAgent:  c.Get(fiber.HeaderUserAgent),
+		IPAddress:  pkg.GetClientIP(c),
+	}
+}
+
+func GetSessionsHandler(handler *SessionHandler) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, err := middlewares.CurrentUserID(c)
+		if err != nil {
+			return err
+		}
+
+		res, err := handler.GetSessions(c, userID, middlewares.CurrentSessionID(c))
+		if err != nil {
+			return err
+		}
+
+		return pkg.ResponseApiOK(c, "Sessions fetch successfully...", res)
+	}
+}
+
+func RevokeSessionHandler(handler *SessionHandler) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, err := middlewares.CurrentUserID(c)
+		if err != nil {
+			return err
+		}
+
+		if err := handler.RevokeSession(c, userID, c.Params("id")); err != nil {
+			return err
+		}
+
+		return pkg.ResponseApiOK(c, "Session revoked successfully", nil)
+	}
+}
+
+func RevokeAllSessionsHandler(handler *SessionHandler) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, err := middlewares.CurrentUserID(c)
+		if err != nil {
+			return err
+		}
+
+		count, err := handler.RevokeAllSessions(c, userID)
+		if err != nil {
+			return err
+		}
+
+		clearAuthCookies(c)
+		return pkg.ResponseApiOK(c, "Logged out from every device", fiber.Map{"revoked": count})
+	}
+}
+
+// GetSessions
+// @Summary List active sessions
+// @Description List the devices where the authenticated user is logged in
+// @Tags Me
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} dto.SessionResponse
+// @Router /v1/me/sessions [get]
+func (h *SessionHandler) GetSessions(c *fiber.Ctx, userID uint, currentSessionID string) ([]dto.SessionResponse, error) {
+	return h.services.Sessions.GetSessions(c.UserContext(), userID, currentSessionID)
+}
+
+// RevokeSession
+// @Summary Revoke a session
+// @Description Log one device out, its access tokens are rejected immediately
+// @Tags Me
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Session id"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /v1/me/sessions/{id} [delete]
+func (h *SessionHandler) RevokeSession(c *fiber.Ctx, userID uint, sessionID string) error {
+	services, err := h.txServices(c)
+	if err != nil {
+		return err
+	}
+
+	if err := services.Sessions.RevokeSession(c.UserContext(), userID, sessionID); err != nil {
+		return err
+	}
+
+	return h.recordAudit(c, userID, "session.revoke", "session", sessionID, nil, nil)
+}
+
+// RevokeAllSessions
+// @Summary Log out everywhere
+// @Description Revoke every active session of the authenticated user, including the current one
+// @Tags Me
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Router /v1/me/sessions [delete]
+func (h *SessionHandler) RevokeAllSessions(c *fiber.Ctx, userID uint) (int, error) {
+	services, err := h.txServices(c)
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := services.Sessions.RevokeAllSessions(c.UserContext(), userID)
+	if err != nil {
+		return 0, err
+	}
+
+	err = h.recordAudit(c, userID, "session.revoke_all", "user", strconv.Itoa(int(userID)), nil, fiber.Map{"revoked": count})
+	return count, err
+}
@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"github.com/DiansSopandi/goride_be/dto"
+	"github.com/DiansSopandi/goride_be/middlewares"
+	"github.com/DiansSopandi/goride_be/pkg"
+	service "github.com/DiansSopandi/goride_be/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+type SettingsHandler struct {
+	baseHandler
+}
+
+func NewSettingsHandler(services *service.Services, mw *middlewares.Middlewares) *SettingsHandler {
+	return &SettingsHandler{baseHandler{services: services, mw: mw}}
+}
+
+// SettingsRoutes registers the effective configuration under /admin.
+func SettingsRoutes(route fiber.Router, handler *SettingsHandler) {
+	route.Get("/settings", GetSettingsHandler(handler))
+}
+
+func GetSettingsHandler(handler *SettingsHandler) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return pkg.ResponseApiOK(c, "Settings fetch successfully...", handler.GetSettings())
+	}
+}
+
+// GetSettings
+// @Summary Get the effective configuration
+// @Description Configuration in effect with the secrets redacted. The runtime_settings are reloaded from the configuration files without a restart, applied_at tells when the last reload happened
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} dto.SettingsResponse
+// @Failure 403 {object} map[string]interface{}
+// @Router /v1/admin/settings [get]
+func (h *SettingsHandler) GetSettings() dto.SettingsResponse {
+	return dto.SettingsResponse{
+		Config:          pkg.EffectiveConfig().Redacted(),
+		RuntimeSettings: pkg.RuntimeSettingKeys,
+		AppliedAt:       pkg.RuntimeAppliedAt(),
+	}
+}
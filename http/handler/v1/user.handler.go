@@ -1,8 +1,8 @@
 package handler
 
 import (
-	"database/sql"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/DiansSopandi/goride_be/dto"
@@ -10,51 +10,40 @@ import (
 	"github.com/DiansSopandi/goride_be/middlewares"
 	model "github.com/DiansSopandi/goride_be/models"
 	"github.com/DiansSopandi/goride_be/pkg"
-	helper "github.com/DiansSopandi/goride_be/pkg/helper"
-	"github.com/DiansSopandi/goride_be/repository"
+	"github.com/DiansSopandi/goride_be/pkg/cache"
+	"github.com/DiansSopandi/goride_be/pkg/validation"
 	service "github.com/DiansSopandi/goride_be/services"
 	"github.com/gofiber/fiber/v2"
 )
 
 type UserHandler struct {
-	UserService *service.UserService
+	baseHandler
 }
 
-func NewUserHandler() *UserHandler {
-	var tx *sql.Tx
-	// userRepo, _ := repository.NewUserRepository(tx, false)
-	userRepo, _ := repository.NewUserRepository(tx)
-	roleRepo, _ := repository.NewRoleRepository(tx)
-	userProviderRepo, _ := repository.NewUserProviderRepository(tx)
-	userService := service.NewUserService(userRepo, roleRepo, userProviderRepo)
-
-	return &UserHandler{
-		UserService: userService, // service.NewUserService(),
-	}
+func NewUserHandler(services *service.Services, mw *middlewares.Middlewares) *UserHandler {
+	return &UserHandler{baseHandler{services: services, mw: mw}}
 }
 
-func UserRoutes(route fiber.Router) {
-	handler := NewUserHandler()
+func UserRoutes(route fiber.Router, handler *UserHandler) {
 	limiter := middlewares.NewRateLimiter()
 
-	limit := pkg.Cfg.Application.DefaultMaxRequestPerMinute
+	// nil limit follows default_max_requests_per_minute, also after a reload
 	duration := time.Minute
 	// route.Get("/users", middlewares.RateLimitMiddleware(&limit, &duration), GetUserHandler(handler))
-	route.Get("/users", limiter.RateLimitMiddleware(&limit, &duration), GetUserHandler(handler))
+	route.Get("/users", middlewares.RequireScopes("users:read"), limiter.RateLimitMiddleware(nil, &duration), middlewares.ResponseCache(middlewares.CacheConfig{
+		TTL:    time.Minute,
+		Tags:   []string{cache.UsersTag},
+		Shared: true,
+	}), GetUserHandler(handler))
 	// route.Post("/users", middlewares.RateLimitMiddleware(&limit, &duration), middlewares.WithTransaction(CreateUserHandler(handler)))
-	route.Post("/users", limiter.RateLimitMiddleware(&limit, &duration), middlewares.WithTransaction(CreateUserHandler(handler)))
+	route.Post("/users", middlewares.RequireScopes("users:write"), limiter.RateLimitMiddleware(nil, &duration), middlewares.Idempotency(), handler.mw.WithTransaction(CreateUserHandler(handler)))
 }
 
 func CreateUserHandler(handler *UserHandler) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		var createUserDto dto.UserCreateRequest
-
-		if err := c.BodyParser(&createUserDto); err != nil {
-			return pkg.ResponseApiErrorBadRequest(c, fmt.Sprintf("Failed to parse request body: %v", err))
-		}
-
-		if err := helper.ValidateCreateUserRequest(&createUserDto); err != nil {
-			return pkg.ResponseApiErrorBadRequest(c, fmt.Sprintf("Validation failed: %v", err))
+		createUserDto, err := validation.BindAndValidate[dto.UserCreateRequest](c)
+		if err != nil {
+			return err
 		}
 
 		res, err := handler.CreateUser(c, &createUserDto)
@@ -79,7 +68,7 @@ func GetUserHandler(handler *UserHandler) fiber.Handler {
 		// claims := c.Locals("user").(jwt.MapClaims)
 		// userID := fmt.Sprintf("%v", claims["sub"])
 
-		res, err := handler.GetUser()
+		res, err := handler.GetUser(c)
 
 		if err != nil {
 			return errors.InternalError(fmt.Sprintf("Failed to fetch users: %v", err))
@@ -97,30 +86,25 @@ func GetUserHandler(handler *UserHandler) fiber.Handler {
 // @Produce json
 // @Param createUserDto body dto.UserCreateRequest true "Create User Request"
 // @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param Idempotency-Key header string false "Makes retries safe, a retry with the same key and body replays the first response"
 // @Success 200 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /v1/users [post]
 func (h *UserHandler) CreateUser(c *fiber.Ctx, createUserDto *dto.UserCreateRequest) (model.User, error) {
-	// Ambil TX dari context
-	tx := c.Locals(middlewares.TxContextKey).(*sql.Tx)
-	// isTx := tx != nil
-	// userRepo, err := repository.NewUserRepository(tx, isTx)
-	userRepo, _ := repository.NewUserRepository(tx)
-	roleRepo, _ := repository.NewRoleRepository(tx)
-	userProviderRepo, _ := repository.NewUserProviderRepository(tx)
-
-	userServiceWithTx := service.NewUserService(userRepo, roleRepo, userProviderRepo)
+	// Ambil services dari transaksi request
+	services, err := h.txServices(c)
+	if err != nil {
+		return model.User{}, err
+	}
 
 	// userJson, err := json.MarshalIndent(createUserDto, "", "  ")
 	// fmt.Println(string(userJson))
 
-	var (
-		roleIDs []int64
-		err     error
-	)
+	var roleIDs []int64
 	if len(createUserDto.Roles) > 0 {
-		// roleIDs, err = h.UserService.ValidateRolesExist(tx, createUserDto.Roles)
-		roleIDs, err = userServiceWithTx.ValidateRolesExist(tx, createUserDto.Roles)
+		roleIDs, err = services.Users.ValidateRolesExist(c.UserContext(), createUserDto.Roles)
 		// roleIDs, err = svc.ValidateRolesExist(createUserDto.Roles)
 
 		if err != nil {
@@ -131,22 +115,24 @@ func (h *UserHandler) CreateUser(c *fiber.Ctx, createUserDto *dto.UserCreateRequ
 	// Ambil service dari context (TX sudah aktif) di middleware
 	// svc := c.Locals(middlewares.UserServiceCtxKey).(*service.UserService)
 
-	// res, err := h.UserService.CreateUser(tx, createUserDto)
-	res, err := userServiceWithTx.CreateUser(tx, createUserDto)
+	res, err := services.Users.CreateUser(c.UserContext(), createUserDto)
 	// res, err := svc.CreateUser(createUserDto)
 	if err != nil {
 		return model.User{}, err
 	}
 
 	if len(roleIDs) > 0 {
-		// err = h.UserService.AssignRolesToUserWithTx(tx, uint(res.ID), roleIDs)
-		err = userServiceWithTx.AssignRolesToUserWithTx(tx, uint(res.ID), roleIDs)
-		// err = svc.AssignRolesToUserWithTx(uint(res.ID), roleIDs)
+		err = services.Users.AssignRolesToUser(c.UserContext(), uint(res.ID), roleIDs)
 		if err != nil {
 			return model.User{}, errors.InternalError(fmt.Sprintf("failed to assign roles: %v", err))
 		}
 	}
 
+	after := fiber.Map{"user": res, "roles": createUserDto.Roles}
+	if err := h.recordAudit(c, 0, "user.create", "user", strconv.Itoa(res.ID), nil, after); err != nil {
+		return model.User{}, err
+	}
+
 	return res, nil
 }
 
@@ -156,12 +142,15 @@ func (h *UserHandler) CreateUser(c *fiber.Ctx, createUserDto *dto.UserCreateRequ
 // @Tags User
 // @Produce json
 // @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param If-None-Match header string false "ETag of a previous response, answered with 304 when it still matches"
 // @Success 200 {object} map[string]interface{}
+// @Success 304 "Not modified"
 // @Failure 500 {object} map[string]interface{}
 // @Router /v1/users [get]
-func (h *UserHandler) GetUser() ([]dto.UserResponse, error) {
+func (h *UserHandler) GetUser(c *fiber.Ctx) ([]dto.UserResponse, error) {
 
-	res, err := h.UserService.GetAllUsers()
+	res, err := h.services.Users.GetAllUsers(c.UserContext())
 
 	if err != nil {
 		return []dto.UserResponse{}, errors.InternalError(fmt.Sprintf("Failed to fetch users: %v", err))
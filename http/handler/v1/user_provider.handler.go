@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DiansSopandi/goride_be/errors"
+	"github.com/DiansSopandi/goride_be/middlewares"
+	"github.com/DiansSopandi/goride_be/models"
+	"github.com/DiansSopandi/goride_be/pkg"
+	"github.com/DiansSopandi/goride_be/pkg/oauth"
+	service "github.com/DiansSopandi/goride_be/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+type UserProviderHandler struct {
+	baseHandler
+	cfg pkg.ApplicationConfig
+}
+
+func NewUserProviderHandler(cfg pkg.ApplicationConfig, services *service.Services, mw *middlewares.Middlewares) *UserProviderHandler {
+	return &UserProviderHandler{baseHandler: baseHandler{services: services, mw: mw}, cfg: cfg}
+}
+
+// UserProviderRoutes registers the login provider routes of the authenticated user under /me.
+func UserProviderRoutes(route fiber.Router, handler *UserProviderHandler) {
+	route.Get("/providers", middlewares.ResponseCache(middlewares.CacheConfig{TTL: 5 * time.Minute, UserTag: true}), GetUserProvidersHandler(handler))
+	route.Post("/providers/:provider/link", LinkUserProviderHandler(handler))
+	route.Delete("/providers/:provider", handler.mw.WithTransaction(UnlinkUserProviderHandler(handler)))
+}
+
+func GetUserProvidersHandler(handler *UserProviderHandler) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, err := middlewares.CurrentUserID(c)
+		if err != nil {
+			return err
+		}
+
+		res, err := handler.GetUserProviders(c, userID)
+		if err != nil {
+			return err
+		}
+
+		return pkg.ResponseApiOK(c, "User providers fetch successfully...", res)
+	}
+}
+
+func LinkUserProviderHandler(handler *UserProviderHandler) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, err := middlewares.CurrentUserID(c)
+		if err != nil {
+			return err
+		}
+
+		res, err := handler.LinkUserProvider(c, userID, c.Params("provider"))
+		if err != nil {
+			return err
+		}
+
+		return pkg.ResponseApiOK(c, "Continue to the provider to link your account", res)
+	}
+}
+
+func UnlinkUserProviderHandler(handler *UserProviderHandler) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, err := middlewares.CurrentUserID(c)
+		if err != nil {
+			return err
+		}
+
+		// provider names are stored lowercase, like the names of the oauth registry
+		if err := handler.UnlinkUserProvider(c, userID, strings.ToLower(c.Params("provider"))); err != nil {
+			return err
+		}
+
+		return pkg.ResponseApiOK(c, "Provider unlinked successfully", nil)
+	}
+}
+
+// GetUserProviders
+// @Summary List login providers
+// @Description List the login methods linked to the authenticated user
+// @Tags Me
+// @Produce json
+// @Security BearerAuth
+// @Param If-None-Match header string false "ETag of a previous response, answered with 304 when it still matches"
+// @Success 200 {array} models.UserProvider
+// @Success 304 "Not modified"
+// @Router /v1/me/providers [get]
+func (h *UserProviderHandler) GetUserProviders(c *fiber.Ctx, userID uint) ([]models.UserProvider, error) {
+	return h.services.Users.GetUserProviders(c.UserContext(), userID)
+}
+
+// LinkUserProvider
+// @Summary Link a login provider
+// @Description Starts the OAuth flow that links the provider identity to the authenticated user, the client must follow auth_url
+// @Tags Me
+// @Produce json
+// @Security BearerAuth
+// @Param provider path string true "Provider name" example(github)
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /v1/me/providers/{provider}/link [post]
+func (h *UserProviderHandler) LinkUserProvider(c *fiber.Ctx, userID uint, name string) (fiber.Map, error) {
+	provider, ok := oauth.GetProvider(name)
+	if !ok {
+		return nil, errors.ResourceNotFound(fmt.Sprintf("oauth provider %q is not configured", name))
+	}
+
+	authURL, err := startOAuthFlow(c, h.cfg, provider, oauthModeLink, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return fiber.Map{"auth_url": authURL}, nil
+}
+
+// UnlinkUserProvider
+// @Summary Unlink a login provider
+// @Description Removes a login method from the authenticated user, the last one cannot be removed
+// @Tags Me
+// @Produce json
+// @Security BearerAuth
+// @Param provider path string true "Provider name" example(github)
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /v1/me/providers/{provider} [delete]
+func (h *UserProviderHandler) UnlinkUserProvider(c *fiber.Ctx, userID uint, provider string) error {
+	services, err := h.txServices(c)
+	if err != nil {
+		return err
+	}
+
+	if err := services.Users.UnlinkProvider(c.UserContext(), userID, provider); err != nil {
+		return err
+	}
+
+	return h.recordAudit(c, userID, "user_provider.unlink", "user", strconv.Itoa(int(userID)), fiber.Map{"provider": provider}, nil)
+}
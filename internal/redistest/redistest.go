@@ -0,0 +1,88 @@
+// Package redistest serves a stand-in for Redis to the tests that build the routes, which connect to
+// Redis on startup. It answers PING and rejects every other command, tests using it must not reach Redis.
+package redistest
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/DiansSopandi/goride_be/pkg"
+)
+
+// Start serves the stand-in until the end of the test and points pkg.Cfg.Redis at it.
+func Start(t testing.TB) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("redistest: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serve(conn)
+		}
+	}()
+
+	pkg.Cfg.Redis.Host = "127.0.0.1"
+	pkg.Cfg.Redis.Port = ln.Addr().(*net.TCPAddr).Port
+}
+
+func serve(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		if len(args) > 0 && strings.EqualFold(args[0], "PING") {
+			io.WriteString(conn, "+PONG\r\n")
+		} else {
+			io.WriteString(conn, "-ERR unknown command\r\n")
+		}
+	}
+}
+
+// readCommand reads a RESP array of bulk strings.
+func readCommand(r *bufio.Reader) ([]string, error) {
+	n, err := readLength(r, '*')
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]string, n)
+	for i := range args {
+		size, err := readLength(r, '$')
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+func readLength(r *bufio.Reader, prefix byte) (int, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return 0, err
+	}
+	if len(line) < 3 || line[0] != prefix {
+		return 0, io.ErrUnexpectedEOF
+	}
+	return strconv.Atoi(strings.TrimSpace(line[1:]))
+}
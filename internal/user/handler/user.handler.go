@@ -117,7 +117,7 @@ func userRoutex(route fiber.Router, h *UserHandler) {
 
 func GetAllUserHandler(h *UserHandler) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		users, err := h.UserService.GetAllUsers()
+		users, err := h.UserService.GetAllUsers(c.UserContext())
 		if err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 				"error": "Failed to retrieve users",
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"github.com/DiansSopandi/goride_be/bootstrap"
 	"github.com/DiansSopandi/goride_be/cmd"
 	_ "github.com/DiansSopandi/goride_be/docs"
 )
@@ -12,6 +11,10 @@ import (
 // @in header
 // @name Authorization
 // @description Type "Bearer" followed by a space and JWT token.
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
+// @description API key of a partner or internal service.
 // @BasePath /v1
 func main() {
 	// Load environment variables
@@ -55,6 +58,4 @@ func main() {
 	// if err := app.Listen(":" + port); err != nil {
 	// 	log.Fatalf("Error starting server: %v", err)
 	// }
-
-	bootstrap.ServerInitialize()
 }
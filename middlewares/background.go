@@ -0,0 +1,34 @@
+package middlewares
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+var backgroundTasks sync.WaitGroup
+
+// goBackground runs fn outside of the request, WaitBackgroundTasks lets shutdown wait for it.
+func goBackground(fn func()) {
+	backgroundTasks.Add(1)
+	go func() {
+		defer backgroundTasks.Done()
+		fn()
+	}()
+}
+
+// WaitBackgroundTasks waits for the last_seen / last_used updates still running, or until ctx is done.
+func WaitBackgroundTasks(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		backgroundTasks.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("background tasks still running: %w", ctx.Err())
+	}
+}
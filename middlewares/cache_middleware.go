@@ -0,0 +1,125 @@
+package middlewares
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/DiansSopandi/goride_be/pkg/cache"
+	"github.com/gofiber/fiber/v2"
+)
+
+// CacheConfig is the caching of the responses of a GET route.
+type CacheConfig struct {
+	TTL time.Duration
+	// Tags name the data of the response, a write invalidating one of them drops the response
+	Tags []string
+	// UserTag adds the cache.UserTag of the authenticated user, for the responses about them
+	UserTag bool
+	// Shared caches one response for every caller allowed on the route, otherwise the responses are
+	// cached per api key, per user and for the anonymous callers
+	Shared bool
+}
+
+// cachedResponse is a response stored by ResponseCache.
+type cachedResponse struct {
+	ContentType string `json:"content_type"`
+	ETag        string `json:"etag"`
+	Body        []byte `json:"body"`
+}
+
+// errNotCacheable keeps a response other than 200 out of the cache, it was already sent.
+var errNotCacheable = errors.New("response not cacheable")
+
+// ResponseCache serves the GET requests of a route from the cache when application.enable_cache is set.
+// The responses carry an ETag, a request with a matching If-None-Match gets a 304. Only the 200
+// responses are cached, and one request per key runs the handler while the others wait for its response.
+func ResponseCache(cfg CacheConfig) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !cache.Enabled() || c.Method() != fiber.MethodGet {
+			return c.Next()
+		}
+
+		tags := cfg.Tags
+		if cfg.UserTag {
+			if userID, err := CurrentUserID(c); err == nil {
+				tags = append(slices.Clip(tags), cache.UserTag(userID))
+			}
+		}
+		key := fmt.Sprintf("http:%s:%s", cacheScope(c, cfg.Shared), c.OriginalURL())
+
+		handled, responseETag := false, ""
+		data, err := cache.Remember(c.UserContext(), key, cfg.TTL, tags, func() ([]byte, error) {
+			handled = true
+			if err := c.Next(); err != nil {
+				return nil, err
+			}
+			if c.Response().StatusCode() != fiber.StatusOK {
+				return nil, errNotCacheable
+			}
+			responseETag = etag(c.Response().Body())
+			res := cachedResponse{
+				ContentType: string(c.Response().Header.ContentType()),
+				ETag:        responseETag,
+				Body:        c.Response().Body(),
+			}
+			return json.Marshal(res)
+		})
+		if handled {
+			if errors.Is(err, errNotCacheable) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			c.Set(fiber.HeaderETag, responseETag)
+			if notModified(c, "MISS") {
+				c.Response().ResetBody()
+				return c.SendStatus(fiber.StatusNotModified)
+			}
+			return nil
+		}
+
+		var res cachedResponse
+		if err != nil || json.Unmarshal(data, &res) != nil {
+			return c.Next()
+		}
+		c.Set(fiber.HeaderContentType, res.ContentType)
+		c.Set(fiber.HeaderETag, res.ETag)
+		if notModified(c, "HIT") {
+			return c.SendStatus(fiber.StatusNotModified)
+		}
+		return c.Status(fiber.StatusOK).Send(res.Body)
+	}
+}
+
+// notModified makes the clients revalidate with the ETag, and reports whether theirs still matches.
+func notModified(c *fiber.Ctx, status string) bool {
+	c.Set(fiber.HeaderCacheControl, "private, no-cache")
+	c.Set("X-Cache", status)
+	return c.Fresh()
+}
+
+// cacheScope keeps the responses of a caller from being served to another one.
+func cacheScope(c *fiber.Ctx, shared bool) string {
+	if shared {
+		return "shared"
+	}
+	if apiKey := CurrentApiKey(c); apiKey != nil {
+		return fmt.Sprintf("api_key:%d", apiKey.ID)
+	}
+	if userID, err := CurrentUserID(c); err == nil {
+		return fmt.Sprintf("user:%d", userID)
+	}
+	return "anonymous"
+}
+
+// etag is a strong validator of body.
+func etag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
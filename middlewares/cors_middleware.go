@@ -0,0 +1,42 @@
+package middlewares
+
+import (
+	"sync/atomic"
+
+	"github.com/DiansSopandi/goride_be/pkg"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/cors"
+)
+
+type corsHandler struct {
+	origins string
+	handler fiber.Handler
+}
+
+// CORS allows the application.cors_origins with credentials. The origins are reloadable, the fiber
+// handler is rebuilt by the first request seeing new ones. They are validated before a reload is applied,
+// cors.New panics on an invalid origin.
+func CORS() fiber.Handler {
+	var current atomic.Pointer[corsHandler]
+
+	return func(c *fiber.Ctx) error {
+		origins := pkg.Runtime().CorsOrigins
+		h := current.Load()
+		if h == nil || h.origins != origins {
+			h = &corsHandler{origins: origins, handler: newCORS(origins)}
+			current.Store(h)
+		}
+		return h.handler(c)
+	}
+}
+
+func newCORS(origins string) fiber.Handler {
+	return cors.New(cors.Config{
+		// AllowOrigins: "http://example.com, http://localhost:3000",
+		AllowOrigins:     origins,
+		AllowCredentials: true,
+		AllowHeaders:     "*",
+		AllowMethods:     "GET,POST,PUT,PATCH,DELETE",
+		ExposeHeaders:    pkg.RequestIDHeader,
+	})
+}
@@ -3,6 +3,7 @@ package middlewares
 import (
 	"github.com/DiansSopandi/goride_be/errors"
 	"github.com/DiansSopandi/goride_be/pkg"
+	"github.com/DiansSopandi/goride_be/pkg/i18n"
 	"github.com/gofiber/fiber/v2"
 )
 
@@ -12,10 +13,13 @@ type DetailResponse struct {
 	StatusCode int    `json:"status_code" example:"200"`
 	Method     string `json:"method" example:"GET"`
 	Status     string `json:"status" example:"success_ok"`
+	RequestID  string `json:"request_id,omitempty" example:"0b6f1f9e-3c1d-4a43-9d55-5f7f3bb0d6a1"`
+	TraceID    string `json:"trace_id,omitempty" example:"4bf92f3577b34da6a3ce929d0e0e4736"`
 } // @name	DetailResponse
 
 type ErrorResponse struct {
 	Details    DetailResponse `json:"details"`
+	Code       string         `json:"code" example:"USER_NOT_FOUND"` // stable key of errors.ErrorCodeMap, for clients to branch on
 	Success    bool           `json:"success" example:"true"`
 	Data       any            `json:"data" swaggertype:"array,object"`
 	Errors     any            `json:"errors" swaggertype:"array,object"`
@@ -23,31 +27,43 @@ type ErrorResponse struct {
 	LogMessage string         `json:"-" example:"Log message for internal user"`
 } // @name ResponseApi
 
-func ErrorHandler(c *fiber.Ctx, err error) error {
+// ErrorHandler renders every error of the handlers and middlewares, as ErrorResponse or as
+// application/problem+json (see wantsProblem). The messages of the error codes are in the language
+// negotiated from Accept-Language, the code stays the same in every language.
+func (m *Middlewares) ErrorHandler(c *fiber.Ctx, err error) error {
+	lang := i18n.Negotiate(c.Get(fiber.HeaderAcceptLanguage))
+
 	detail := DetailResponse{
 		StatusCode: int(pkg.HttpStatusInternalServerError),
 		Path:       c.Request().URI().String(),
 		Method:     string(c.Request().Header.Method()),
 		Status:     string(pkg.ApiStatusErrorInternalServerError),
+		RequestID:  pkg.RequestID(c),
+		TraceID:    pkg.TraceID(c),
 	}
 
+	// unexpected errors only reach the log, their text may tell about the internals
 	res := ErrorResponse{
 		Details:    detail,
+		Code:       "INTERNAL_ERROR",
 		Success:    false,
 		Data:       nil,
-		Message:    err.Error(), // errors.ErrorCodeMap["INTERNAL_ERROR"],
+		Message:    i18n.T(lang, "error.INTERNAL_ERROR", nil),
 		LogMessage: err.Error(),
 	}
 
 	if appErr, ok := err.(*errors.AppErrorResponse); ok {
 		res.Details.StatusCode = appErr.Details.StatusCode
 		res.Details.Status = appErr.Details.Status
-		res.Message = appErr.Message
+		res.Code = appErr.Code
+		res.Message = appErr.LocalizedMessage(lang)
+		res.Errors = appErr.Errors
 		res.LogMessage = appErr.LogMessage
 
 		pkg.CreateAccessLog(c, "[ACCESS:API][ERROR]", res.Details.StatusCode, appErr.LogMessage)
 	} else if e, ok := err.(*fiber.Error); ok {
 		res.Details.StatusCode = e.Code
+		res.Code = errors.CodeForStatus(e.Code)
 		res.Message = e.Message
 		res.LogMessage = e.Error()
 	}
@@ -56,5 +72,9 @@ func ErrorHandler(c *fiber.Ctx, err error) error {
 		pkg.CreateAccessLog(c, "[ACCESS:API][ERROR]", res.Details.StatusCode, err.Error())
 	}
 
+	c.Set(fiber.HeaderContentLanguage, lang)
+	if wantsProblem(c, m.cfg.Application.ErrorFormat) {
+		return c.Status(res.Details.StatusCode).JSON(problemDetails(c, res, lang, m.cfg.Application.ProblemTypeBaseURL), ProblemContentType)
+	}
 	return c.Status(res.Details.StatusCode).JSON(res)
 }
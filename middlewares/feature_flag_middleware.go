@@ -0,0 +1,63 @@
+package middlewares
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/DiansSopandi/goride_be/dto"
+	"github.com/DiansSopandi/goride_be/errors"
+	"github.com/gofiber/fiber/v2"
+)
+
+// CityHeader carries the city the mobile apps operate in, used to target feature flags.
+const CityHeader = "X-City"
+
+const flagSubjectContextKey = "flag_subject"
+
+// FlagSubject describes the caller for the feature flags: the user of the JWT or the owner of the API key
+// with their roles, and the city of X-City. Anonymous requests have no user and no roles.
+// It is resolved once per request.
+func (m *Middlewares) FlagSubject(c *fiber.Ctx) (dto.FlagSubject, error) {
+	if subject, ok := c.Locals(flagSubjectContextKey).(dto.FlagSubject); ok {
+		return subject, nil
+	}
+
+	subject := dto.FlagSubject{City: strings.TrimSpace(c.Get(CityHeader))}
+	if userID, err := CurrentUserID(c); err == nil {
+		roles, err := m.services.Roles.Repo.GetRoleByUserID(c.UserContext(), int(userID))
+		if err != nil {
+			return dto.FlagSubject{}, errors.DatabaseError(fmt.Sprintf("failed to get roles of user %d: %v", userID, err)).WithCause(err)
+		}
+
+		subject.UserID = userID
+		for _, role := range roles {
+			subject.Roles = append(subject.Roles, role.Name)
+		}
+	}
+
+	c.Locals(flagSubjectContextKey, subject)
+	return subject, nil
+}
+
+// FeatureEnabled reports whether the flag is on for the caller, for handlers branching on a flag.
+func (m *Middlewares) FeatureEnabled(c *fiber.Ctx, key string) (bool, error) {
+	subject, err := m.FlagSubject(c)
+	if err != nil {
+		return false, err
+	}
+	return m.services.Flags.IsEnabled(c.UserContext(), key, subject)
+}
+
+// RequireFeature hides a route behind a flag, callers without the flag get a 404 as if the route did not exist.
+func (m *Middlewares) RequireFeature(key string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		enabled, err := m.FeatureEnabled(c, key)
+		if err != nil {
+			return err
+		}
+		if !enabled {
+			return errors.ResourceNotFound(fmt.Sprintf("%s %s is behind feature flag %s", c.Method(), c.Path(), key))
+		}
+		return c.Next()
+	}
+}
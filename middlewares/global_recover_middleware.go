@@ -26,11 +26,14 @@ func GlobalRecoveryMiddleware(c *fiber.Ctx) (err error) {
 					Method:     string(c.Request().Header.Method()),
 					StatusCode: fiber.StatusInternalServerError,
 					Status:     string(pkg.ApiStatusErrorInternalServerError),
+					RequestID:  pkg.RequestID(c),
+					TraceID:    pkg.TraceID(c),
 				},
+				Code:       "INTERNAL_ERROR",
 				Success:    false,
 				Data:       nil,
-				Message:    fmt.Sprintf("%v", r), // Message untuk client
-				LogMessage: logMessage,           // Message untuk internal log
+				Message:    errors.ErrorCodeMap["INTERNAL_ERROR"], // Message untuk client, panic hanya di log
+				LogMessage: logMessage,                            // Message untuk internal log
 			}
 		}
 	}()
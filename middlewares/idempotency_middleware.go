@@ -0,0 +1,130 @@
+package middlewares
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/DiansSopandi/goride_be/errors"
+	"github.com/DiansSopandi/goride_be/pkg"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	// IdempotencyKeyHeader carries the key the client generates once per operation (e.g. a UUID)
+	// and sends again on every retry of it.
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader marks a response replayed from the first request of the key.
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+	// idempotencyLockTTL outlives the request timeout, a crashed instance does not hold a key forever.
+	idempotencyLockTTL = 2 * time.Minute
+	// idempotencyResponseTTL is how long a client can retry an operation and get the same response.
+	idempotencyResponseTTL = 24 * time.Hour
+)
+
+// replayedHeaders are the response headers stored with the body, the rest is set again by the middlewares.
+var replayedHeaders = []string{fiber.HeaderContentType, fiber.HeaderLocation}
+
+// Idempotency makes the POST and PATCH requests carrying an Idempotency-Key safe to retry. The first request
+// of a key runs and its response is stored for the caller (user, API key or anonymous ip and user agent) for
+// a day, a retry with the same method, path and body gets the stored response back without running the
+// handler again.
+// Reusing a key for another request or while its first request is still running is a 409.
+// Failed requests (an error or a 5xx) are not stored, so they can be retried with the same key.
+//
+// Put it in front of WithTransaction, the response is only stored once the transaction committed.
+func Idempotency() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		idempotencyKey := strings.TrimSpace(c.Get(IdempotencyKeyHeader))
+		if idempotencyKey == "" || (c.Method() != fiber.MethodPost && c.Method() != fiber.MethodPatch) {
+			return c.Next()
+		}
+		if len(idempotencyKey) > maxIdempotencyKeyLength {
+			return errors.InvalidInput(fmt.Sprintf("%s must be at most %d characters", IdempotencyKeyHeader, maxIdempotencyKeyLength))
+		}
+
+		key := idempotencyScope(c) + ":" + idempotencyKey
+		fingerprint := requestFingerprint(c)
+
+		record, reserved, err := pkg.ReserveIdempotencyKey(c.UserContext(), key, fingerprint, idempotencyLockTTL)
+		if err != nil {
+			return errors.ServiceUnavailable(fmt.Sprintf("Idempotency check error: %v", err)).WithCause(err)
+		}
+		if !reserved {
+			return replayIdempotentResponse(c, idempotencyKey, fingerprint, record)
+		}
+
+		// the stored response must outlive a request that timed out or was cancelled after running
+		ctx := context.WithoutCancel(c.UserContext())
+
+		err = c.Next()
+		if err != nil || c.Response().StatusCode() >= fiber.StatusInternalServerError {
+			if releaseErr := pkg.ReleaseIdempotencyKey(ctx, key); releaseErr != nil {
+				slog.Warn("failed to release idempotency key, retries are rejected until it expires", "error", releaseErr)
+			}
+			return err
+		}
+
+		response := pkg.IdempotentResponse{
+			Fingerprint: fingerprint,
+			Status:      c.Response().StatusCode(),
+			Headers:     map[string]string{},
+			Body:        append([]byte(nil), c.Response().Body()...),
+		}
+		for _, header := range replayedHeaders {
+			if value := c.GetRespHeader(header); value != "" {
+				response.Headers[header] = value
+			}
+		}
+		if err := pkg.StoreIdempotentResponse(ctx, key, response, idempotencyResponseTTL); err != nil {
+			slog.Warn("failed to store idempotent response, retries are rejected until the key expires", "error", err)
+		}
+		return nil
+	}
+}
+
+func replayIdempotentResponse(c *fiber.Ctx, idempotencyKey, fingerprint string, record *pkg.IdempotentResponse) error {
+	switch {
+	case record == nil || record.Status == 0:
+		c.Set(fiber.HeaderRetryAfter, "1")
+		return errors.IdempotentRequestInProgress(fmt.Sprintf("request with %s %s is still in progress", IdempotencyKeyHeader, idempotencyKey))
+	case record.Fingerprint != fingerprint:
+		return errors.IdempotencyKeyReused(fmt.Sprintf("%s %s was used for another request", IdempotencyKeyHeader, idempotencyKey))
+	}
+
+	for header, value := range record.Headers {
+		c.Set(header, value)
+	}
+	c.Set(IdempotentReplayedHeader, "true")
+	return c.Status(record.Status).Send(record.Body)
+}
+
+// idempotencyScope keeps the keys of each caller apart. Anonymous callers are told apart by their ip and
+// user agent, two of them sending the same key must not get the response of each other. The ip is the
+// peer address unless it is a trusted proxy, a client cannot pick the scope of another one through a
+// forwarded header. An anonymous client switching networks between retries runs the request again.
+func idempotencyScope(c *fiber.Ctx) string {
+	if apiKey := CurrentApiKey(c); apiKey != nil {
+		return fmt.Sprintf("api_key:%d", apiKey.ID)
+	}
+	if userID, err := CurrentUserID(c); err == nil {
+		return fmt.Sprintf("user:%d", userID)
+	}
+
+	client := sha256.Sum256([]byte(c.IP() + "\n" + c.Get(fiber.HeaderUserAgent)))
+	return "anonymous:" + hex.EncodeToString(client[:16])
+}
+
+// requestFingerprint hashes the method, path and body, a retry must send the same request.
+func requestFingerprint(c *fiber.Ctx) string {
+	h := sha256.New()
+	h.Write([]byte(c.Method() + " " + c.Path() + "\n"))
+	h.Write(c.Body())
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,185 @@
+package middlewares
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DiansSopandi/goride_be/models"
+	"github.com/DiansSopandi/goride_be/pkg"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestReplayIdempotentResponse(t *testing.T) {
+	const fingerprint = "fingerprint"
+
+	tests := []struct {
+		name        string
+		record      *pkg.IdempotentResponse
+		wantStatus  int
+		wantBody    string
+		wantHeaders map[string]string
+	}{
+		{
+			name:        "expired while reserving",
+			record:      nil,
+			wantStatus:  fiber.StatusConflict,
+			wantBody:    "IDEMPOTENT_REQUEST_IN_PROGRESS",
+			wantHeaders: map[string]string{fiber.HeaderRetryAfter: "1"},
+		},
+		{
+			name:        "first request still running",
+			record:      &pkg.IdempotentResponse{Fingerprint: fingerprint},
+			wantStatus:  fiber.StatusConflict,
+			wantBody:    "IDEMPOTENT_REQUEST_IN_PROGRESS",
+			wantHeaders: map[string]string{fiber.HeaderRetryAfter: "1"},
+		},
+		{
+			name:       "another request",
+			record:     &pkg.IdempotentResponse{Fingerprint: "other", Status: fiber.StatusCreated, Body: []byte(`{"id":1}`)},
+			wantStatus: fiber.StatusConflict,
+			wantBody:   "IDEMPOTENCY_KEY_REUSED",
+		},
+		{
+			name: "same request",
+			record: &pkg.IdempotentResponse{
+				Fingerprint: fingerprint,
+				Status:      fiber.StatusCreated,
+				Headers:     map[string]string{fiber.HeaderContentType: fiber.MIMEApplicationJSON, fiber.HeaderLocation: "/v1/users/1"},
+				Body:        []byte(`{"id":1}`),
+			},
+			wantStatus: fiber.StatusCreated,
+			wantBody:   `{"id":1}`,
+			wantHeaders: map[string]string{
+				IdempotentReplayedHeader: "true",
+				fiber.HeaderContentType:  fiber.MIMEApplicationJSON,
+				fiber.HeaderLocation:     "/v1/users/1",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mw := NewMiddlewares(pkg.Config{}, nil, nil)
+			app := fiber.New(fiber.Config{ErrorHandler: mw.ErrorHandler})
+			app.Post("/", func(c *fiber.Ctx) error {
+				return replayIdempotentResponse(c, "key", fingerprint, tt.record)
+			})
+
+			res, err := app.Test(httptest.NewRequest(fiber.MethodPost, "/", nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(res.Body)
+
+			if res.StatusCode != tt.wantStatus {
+				t.Errorf("got status %d, want %d", res.StatusCode, tt.wantStatus)
+			}
+			if !strings.Contains(string(body), tt.wantBody) {
+				t.Errorf("got body %s, want %s in it", body, tt.wantBody)
+			}
+			for header, want := range tt.wantHeaders {
+				if got := res.Header.Get(header); got != want {
+					t.Errorf("got %s %q, want %q", header, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRequestFingerprint(t *testing.T) {
+	tests := []struct {
+		name         string
+		method, path string
+		body         string
+		wantSame     bool
+	}{
+		{name: "same request", method: fiber.MethodPost, path: "/v1/auth/register", body: `{"email":"a@example.com"}`, wantSame: true},
+		{name: "other body", method: fiber.MethodPost, path: "/v1/auth/register", body: `{"email":"b@example.com"}`},
+		{name: "other path", method: fiber.MethodPost, path: "/v1/users", body: `{"email":"a@example.com"}`},
+		{name: "other method", method: fiber.MethodPatch, path: "/v1/auth/register", body: `{"email":"a@example.com"}`},
+	}
+
+	fingerprints := make(chan string, 1)
+	app := fiber.New()
+	app.All("/*", func(c *fiber.Ctx) error {
+		fingerprints <- requestFingerprint(c)
+		return nil
+	})
+	fingerprint := func(method, path, body string) string {
+		if _, err := app.Test(httptest.NewRequest(method, path, strings.NewReader(body))); err != nil {
+			t.Fatal(err)
+		}
+		return <-fingerprints
+	}
+
+	first := fingerprint(fiber.MethodPost, "/v1/auth/register", `{"email":"a@example.com"}`)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if same := fingerprint(tt.method, tt.path, tt.body) == first; same != tt.wantSame {
+				t.Errorf("same fingerprint %v, want %v", same, tt.wantSame)
+			}
+		})
+	}
+}
+
+func TestIdempotencyScope(t *testing.T) {
+	tests := []struct {
+		name      string
+		locals    map[string]any
+		userAgent string
+		forwarded string
+		want      string // exact scope, or the scope of the anonymous browser when empty
+		wantSame  bool
+	}{
+		{name: "api key", locals: map[string]any{ApiKeyContextKey: &models.ApiKey{ID: 3}}, want: "api_key:3"},
+		{name: "user", locals: map[string]any{"user": jwt.MapClaims{"sub": float64(7)}}, want: "user:7"},
+		{name: "same anonymous client", userAgent: "browser", wantSame: true},
+		{name: "other user agent", userAgent: "curl"},
+		{name: "spoofed forwarded ip", userAgent: "browser", forwarded: "203.0.113.9", wantSame: true},
+	}
+
+	scopes := make(chan string, 1)
+	var locals map[string]any
+	app := fiber.New()
+	app.Post("/", func(c *fiber.Ctx) error {
+		for key, value := range locals {
+			c.Locals(key, value)
+		}
+		scopes <- idempotencyScope(c)
+		return nil
+	})
+	scope := func(userAgent, forwarded string) string {
+		req := httptest.NewRequest(fiber.MethodPost, "/", nil)
+		req.Header.Set(fiber.HeaderUserAgent, userAgent)
+		if forwarded != "" {
+			req.Header.Set(fiber.HeaderXForwardedFor, forwarded)
+		}
+		if _, err := app.Test(req); err != nil {
+			t.Fatal(err)
+		}
+		return <-scopes
+	}
+
+	browser := scope("browser", "")
+	if !strings.HasPrefix(browser, "anonymous:") {
+		t.Fatalf("anonymous scope %q", browser)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			locals = tt.locals
+			got := scope(tt.userAgent, tt.forwarded)
+			if tt.want != "" {
+				if got != tt.want {
+					t.Errorf("got scope %q, want %q", got, tt.want)
+				}
+				return
+			}
+			if same := got == browser; same != tt.wantSame {
+				t.Errorf("scope %q same as the browser %v, want %v", got, same, tt.wantSame)
+			}
+		})
+	}
+}
@@ -1,17 +1,22 @@
 package middlewares
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/DiansSopandi/goride_be/errors"
 	"github.com/DiansSopandi/goride_be/pkg"
+	"github.com/DiansSopandi/goride_be/pkg/utils"
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func JwtAuthGuard(c *fiber.Ctx) error {
+// JwtAuthGuard authenticates every non public route with an API key or an access token.
+func (m *Middlewares) JwtAuthGuard(c *fiber.Ctx) error {
 	var tokenString string
 
 	// publicRoutes := []string{
@@ -33,7 +38,15 @@ func JwtAuthGuard(c *fiber.Ctx) error {
 	// 	}
 	// }
 
-	if isPublicRoute(c.Path()) {
+	if m.isPublicRoute(c.Path()) {
+		return c.Next()
+	}
+
+	// Partner dan service internal memakai API key, bukan JWT
+	if apiKey := c.Get(ApiKeyHeader); apiKey != "" {
+		if err := m.authenticateApiKey(c, apiKey); err != nil {
+			return err
+		}
 		return c.Next()
 	}
 
@@ -65,25 +78,26 @@ func JwtAuthGuard(c *fiber.Ctx) error {
 	// }
 
 	// Parse & validasi JWT
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		// Pastikan algoritma sesuai
-		// SigningMethodHS256
-		// if _, ok := token.Method.(*jwt.SigningMethodHS256); !ok {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.Unauthorized("Unexpected signing method")
-		}
-		return []byte(pkg.Cfg.Application.JwtSecretKey), nil
-	})
-
-	if err != nil || !token.Valid {
+	claims, err := utils.ParseAccessToken(tokenString)
+	if err != nil {
 		return errors.Unauthorized(fmt.Sprintf("Invalid token: %v", err))
 	}
 
-	claims := token.Claims.(jwt.MapClaims)
-	if exp, ok := claims["exp"].(float64); ok {
-		if int64(exp) < time.Now().Unix() {
-			return errors.Unauthorized("token expired")
+	// Tolak token dari session yang sudah di-revoke
+	if sid, ok := claims["sid"].(string); ok && sid != "" {
+		denied, err := pkg.IsSessionDenied(c.UserContext(), sid)
+		if !denied && (err != nil || pkg.SessionDenylistStale()) {
+			// the denylist cannot tell, postgres has every revocation
+			denied, err = m.services.Sessions.Repo.IsSessionRevoked(c.UserContext(), sid)
+			if err != nil {
+				// fail open, an outage must not reject every user
+				slog.Warn("session revocation unknown, token accepted", "session_id", sid, "error", err)
+			}
+		}
+		if denied {
+			return errors.Unauthorized("session has been revoked")
 		}
+		m.touchSession(sid)
 	}
 
 	// Simpan user info ke context (opsional)
@@ -93,11 +107,69 @@ func JwtAuthGuard(c *fiber.Ctx) error {
 	return c.Next()
 }
 
+// CurrentUserID returns the id of the authenticated user stored in the context by JwtAuthGuard.
+func CurrentUserID(c *fiber.Ctx) (uint, error) {
+	claims, ok := c.Locals("user").(jwt.MapClaims)
+	if !ok {
+		return 0, errors.Unauthorized("missing user claims")
+	}
+
+	switch sub := claims["sub"].(type) {
+	case float64:
+		return uint(sub), nil
+	case string:
+		id, err := strconv.ParseUint(sub, 10, 64)
+		if err != nil {
+			return 0, errors.Unauthorized(fmt.Sprintf("invalid sub claim: %v", err))
+		}
+		return uint(id), nil
+	default:
+		return 0, errors.Unauthorized("missing sub claim")
+	}
+}
+
+// CurrentSessionID returns the sid claim of the access token, empty for tokens issued before sessions existed.
+func CurrentSessionID(c *fiber.Ctx) string {
+	claims, ok := c.Locals("user").(jwt.MapClaims)
+	if !ok {
+		return ""
+	}
+	sid, _ := claims["sid"].(string)
+	return sid
+}
+
+// touchSession refreshes last_seen_at in the background, at most once a minute per session.
+func (m *Middlewares) touchSession(sid string) {
+	goBackground(func() {
+		first, err := pkg.MarkSessionSeen(context.Background(), sid, time.Minute)
+		if err != nil || !first {
+			return
+		}
+
+		if err := m.services.Sessions.Repo.TouchLastSeen(context.Background(), sid); err != nil {
+			slog.Warn("failed to update last seen of session", "session_id", sid, "error", err)
+		}
+	})
+}
+
 func GetPublicRoutes() []string {
-	return pkg.Cfg.Application.PublicRoutes
+	return pkg.Runtime().PublicRoutes
 }
 
-func isPublicRoute(path string) bool {
+func (m *Middlewares) isPublicRoute(path string) bool {
+	// JWKS dan discovery document harus selalu bisa diambil tanpa token
+	if strings.HasPrefix(path, "/.well-known/") {
+		return true
+	}
+	// /metrics dilindungi MetricsGuard (allowlist / basic auth), bukan JWT
+	if path == "/metrics" {
+		return true
+	}
+	// probe kubernetes / load balancer tidak membawa token
+	if healthPath := m.cfg.Application.AppPath + "/health"; path == healthPath || strings.HasPrefix(path, healthPath+"/") {
+		return true
+	}
+
 	publicRoutes := GetPublicRoutes()
 	for _, r := range publicRoutes {
 		// kalau pakai wildcard swagger/*
@@ -108,13 +180,39 @@ func isPublicRoute(path string) bool {
 			}
 		}
 
-		if path == r {
+		if path == r || matchRoutePattern(r, path) {
 			return true
 		}
 	}
 	return false
 }
 
+// matchRoutePattern supports fiber style params, e.g. "/v1/auth/:provider/login".
+func matchRoutePattern(pattern, path string) bool {
+	if !strings.Contains(pattern, ":") {
+		return false
+	}
+
+	patternParts := strings.Split(strings.Trim(pattern, "/"), "/")
+	pathParts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(patternParts) != len(pathParts) {
+		return false
+	}
+
+	for i, part := range patternParts {
+		if strings.HasPrefix(part, ":") {
+			if pathParts[i] == "" {
+				return false
+			}
+			continue
+		}
+		if part != pathParts[i] {
+			return false
+		}
+	}
+	return true
+}
+
 func extractToken(c *fiber.Ctx) (string, error) {
 	authHeader := c.Get("Authorization")
 	if authHeader == "" {
@@ -0,0 +1,118 @@
+package middlewares
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/DiansSopandi/goride_be/errors"
+	"github.com/DiansSopandi/goride_be/pkg/metrics"
+	"github.com/gofiber/fiber/v2"
+)
+
+// HTTPMetrics records count and latency of every request by route template, so /v1/users/1 and
+// /v1/users/2 share the /v1/users/:id series.
+func HTTPMetrics(c *fiber.Ctx) error {
+	start := time.Now()
+	err := c.Next()
+
+	route := c.Route().Path
+	if routeNotFound(err) {
+		// only middlewares ran, keep unknown paths out of the route label
+		route = "unmatched"
+	}
+
+	metrics.ObserveHTTPRequest(c.Method(), route, responseStatus(c, err), time.Since(start))
+	return err
+}
+
+// responseStatus is the status the error handler will send for err.
+func responseStatus(c *fiber.Ctx, err error) int {
+	if err == nil {
+		return c.Response().StatusCode()
+	}
+	if appErr, ok := err.(*errors.AppErrorResponse); ok {
+		return appErr.Details.StatusCode
+	}
+	if e, ok := err.(*fiber.Error); ok {
+		return e.Code
+	}
+	return fiber.StatusInternalServerError
+}
+
+// routeNotFound reports whether err is the 404 fiber returns when no route matches the request.
+func routeNotFound(err error) bool {
+	e, ok := err.(*fiber.Error)
+	return ok && e.Code == fiber.StatusNotFound && strings.HasPrefix(e.Message, "Cannot ")
+}
+
+// MetricsGuard lets through allowlisted ips and scrapers with the metrics basic auth credentials.
+func (m *Middlewares) MetricsGuard(c *fiber.Ctx) error {
+	cfg := m.cfg.Metrics
+
+	if cfg.Username != "" && validMetricsBasicAuth(c, cfg.Username, cfg.Password) {
+		return c.Next()
+	}
+
+	allowed := cfg.AllowedIPs
+	if len(allowed) == 0 && cfg.Username == "" {
+		allowed = []string{"127.0.0.1", "::1"}
+	}
+	if ipAllowed(c.IP(), allowed) {
+		return c.Next()
+	}
+
+	if cfg.Username != "" {
+		c.Set(fiber.HeaderWWWAuthenticate, `Basic realm="metrics"`)
+		return errors.Unauthorized("metrics requires basic auth")
+	}
+	return errors.PermissionDenied("ip " + c.IP() + " is not allowed to read metrics")
+}
+
+func validMetricsBasicAuth(c *fiber.Ctx, username, password string) bool {
+	user, pass, ok := parseBasicAuth(c.Get(fiber.HeaderAuthorization))
+	if !ok {
+		return false
+	}
+	userOK := subtle.ConstantTimeCompare([]byte(user), []byte(username)) == 1
+	passOK := subtle.ConstantTimeCompare([]byte(pass), []byte(password)) == 1
+	return userOK && passOK
+}
+
+// ipAllowed matches the connection ip, not X-Forwarded-For, against ips and CIDRs.
+func ipAllowed(ip string, allowed []string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+
+	for _, entry := range allowed {
+		if strings.Contains(entry, "/") {
+			if _, network, err := net.ParseCIDR(entry); err == nil && network.Contains(parsed) {
+				return true
+			}
+			continue
+		}
+		if allowedIP := net.ParseIP(entry); allowedIP != nil && allowedIP.Equal(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseBasicAuth(header string) (string, string, bool) {
+	const prefix = "Basic "
+	if len(header) < len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return "", "", false
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(header[len(prefix):])
+	if err != nil {
+		return "", "", false
+	}
+
+	user, pass, ok := strings.Cut(string(decoded), ":")
+	return user, pass, ok
+}
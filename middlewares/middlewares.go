@@ -0,0 +1,24 @@
+package middlewares
+
+import (
+	"database/sql"
+
+	"github.com/DiansSopandi/goride_be/pkg"
+	service "github.com/DiansSopandi/goride_be/services"
+)
+
+// Middlewares holds the middlewares that reach the database or the configuration. The bootstrap
+// container builds it once with its configuration, the pool and the services bound to it.
+type Middlewares struct {
+	cfg      pkg.Config
+	db       *sql.DB
+	services *service.Services
+}
+
+func NewMiddlewares(cfg pkg.Config, db *sql.DB, services *service.Services) *Middlewares {
+	return &Middlewares{
+		cfg:      cfg,
+		db:       db,
+		services: services,
+	}
+}
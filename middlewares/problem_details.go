@@ -0,0 +1,64 @@
+package middlewares
+
+import (
+	"strings"
+
+	"github.com/DiansSopandi/goride_be/pkg/i18n"
+	"github.com/gofiber/fiber/v2"
+)
+
+// ProblemContentType is the media type of the RFC 7807 error bodies.
+const ProblemContentType = "application/problem+json"
+
+const defaultProblemTypeBaseURL = "urn:goride:error:"
+
+// ProblemDetails is the RFC 7807 rendering of ErrorResponse. Code, the request and trace ids and the
+// field errors are extension members.
+type ProblemDetails struct {
+	Type      string `json:"type" example:"urn:goride:error:user-not-found"`
+	Title     string `json:"title" example:"User not found"`
+	Status    int    `json:"status" example:"404"`
+	Detail    string `json:"detail,omitempty" example:"User not found"`
+	Instance  string `json:"instance,omitempty" example:"/v1/users/42"`
+	Code      string `json:"code" example:"USER_NOT_FOUND"`
+	RequestID string `json:"request_id,omitempty" example:"0b6f1f9e-3c1d-4a43-9d55-5f7f3bb0d6a1"`
+	TraceID   string `json:"trace_id,omitempty" example:"4bf92f3577b34da6a3ce929d0e0e4736"`
+	Errors    any    `json:"errors,omitempty" swaggertype:"array,object"`
+} // @name ProblemDetails
+
+// wantsProblem reports whether the error is rendered as problem+json: always with application.error_format
+// "problem", otherwise when the client prefers it to application/json in Accept.
+func wantsProblem(c *fiber.Ctx, errorFormat string) bool {
+	if strings.EqualFold(errorFormat, "problem") {
+		return true
+	}
+	return c.Accepts(fiber.MIMEApplicationJSON, ProblemContentType) == ProblemContentType
+}
+
+// problemDetails converts the envelope of an error, the title is the message of the code in lang and
+// the detail the message of this occurrence when it says more. The type is under typeBaseURL.
+func problemDetails(c *fiber.Ctx, res ErrorResponse, lang, typeBaseURL string) ProblemDetails {
+	problem := ProblemDetails{
+		Type:      problemType(typeBaseURL, res.Code),
+		Title:     i18n.T(lang, "error."+res.Code, nil),
+		Status:    res.Details.StatusCode,
+		Instance:  c.OriginalURL(),
+		Code:      res.Code,
+		RequestID: res.Details.RequestID,
+		TraceID:   res.Details.TraceID,
+		Errors:    res.Errors,
+	}
+	if detail, ok := res.Message.(string); ok && detail != problem.Title {
+		problem.Detail = detail
+	}
+	return problem
+}
+
+// problemType returns the type uri of an error code under application.problem_type_base_url,
+// USER_NOT_FOUND gives urn:goride:error:user-not-found by default.
+func problemType(base, code string) string {
+	if base == "" {
+		base = defaultProblemTypeBaseURL
+	}
+	return base + strings.ToLower(strings.ReplaceAll(code, "_", "-"))
+}
@@ -0,0 +1,143 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/DiansSopandi/goride_be/errors"
+	"github.com/DiansSopandi/goride_be/pkg"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestErrorHandlerProblemDetails(t *testing.T) {
+	tests := []struct {
+		name            string
+		cfg             pkg.ApplicationConfig
+		accept          string
+		acceptLanguage  string
+		path            string
+		wantContentType string
+		wantProblem     ProblemDetails // compared without the request id, for the problem+json responses
+	}{
+		{
+			name:            "envelope by default",
+			accept:          fiber.MIMEApplicationJSON,
+			path:            "/users/42",
+			wantContentType: fiber.MIMEApplicationJSON,
+		},
+		{
+			name:            "asked for in accept",
+			accept:          ProblemContentType,
+			path:            "/users/42",
+			wantContentType: ProblemContentType,
+			wantProblem:     ProblemDetails{Type: "urn:goride:error:user-not-found", Title: "User not found", Status: 404, Instance: "/users/42", Code: "USER_NOT_FOUND"},
+		},
+		{
+			name:            "json preferred in accept",
+			accept:          "application/json, application/problem+json;q=0.5",
+			path:            "/users/42",
+			wantContentType: fiber.MIMEApplicationJSON,
+		},
+		{
+			name:            "error format problem",
+			cfg:             pkg.ApplicationConfig{ErrorFormat: "Problem"},
+			accept:          fiber.MIMEApplicationJSON,
+			path:            "/users/42",
+			wantContentType: ProblemContentType,
+			wantProblem:     ProblemDetails{Type: "urn:goride:error:user-not-found", Title: "User not found", Status: 404, Instance: "/users/42", Code: "USER_NOT_FOUND"},
+		},
+		{
+			name:            "type base url",
+			cfg:             pkg.ApplicationConfig{ErrorFormat: "problem", ProblemTypeBaseURL: "https://docs.example.com/errors/"},
+			path:            "/users/42",
+			wantContentType: ProblemContentType,
+			wantProblem:     ProblemDetails{Type: "https://docs.example.com/errors/user-not-found", Title: "User not found", Status: 404, Instance: "/users/42", Code: "USER_NOT_FOUND"},
+		},
+		{
+			name:            "localized title",
+			cfg:             pkg.ApplicationConfig{ErrorFormat: "problem"},
+			acceptLanguage:  "id-ID",
+			path:            "/users/42",
+			wantContentType: ProblemContentType,
+			wantProblem:     ProblemDetails{Type: "urn:goride:error:user-not-found", Title: "Pengguna tidak ditemukan", Status: 404, Instance: "/users/42", Code: "USER_NOT_FOUND"},
+		},
+		{
+			name:            "detail of an unknown route",
+			cfg:             pkg.ApplicationConfig{ErrorFormat: "problem"},
+			path:            "/unknown?page=2",
+			wantContentType: ProblemContentType,
+			wantProblem:     ProblemDetails{Type: "urn:goride:error:resource-not-found", Title: "Requested resource not found", Status: 404, Detail: "Cannot GET /unknown", Instance: "/unknown?page=2", Code: "RESOURCE_NOT_FOUND"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mw := NewMiddlewares(pkg.Config{Application: tt.cfg}, nil, nil)
+			app := fiber.New(fiber.Config{ErrorHandler: mw.ErrorHandler})
+			app.Get("/users/:id", func(c *fiber.Ctx) error {
+				return errors.UserNotFound("user " + c.Params("id") + " not found")
+			})
+
+			req := httptest.NewRequest(fiber.MethodGet, tt.path, nil)
+			if tt.accept != "" {
+				req.Header.Set(fiber.HeaderAccept, tt.accept)
+			}
+			if tt.acceptLanguage != "" {
+				req.Header.Set(fiber.HeaderAcceptLanguage, tt.acceptLanguage)
+			}
+			res, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := res.Header.Get(fiber.HeaderContentType); got != tt.wantContentType {
+				t.Fatalf("got %s %q, want %q", fiber.HeaderContentType, got, tt.wantContentType)
+			}
+			if tt.wantContentType != ProblemContentType {
+				var envelope ErrorResponse
+				if err := json.NewDecoder(res.Body).Decode(&envelope); err != nil || envelope.Code == "" {
+					t.Errorf("envelope %+v (%v), want the code of the error", envelope, err)
+				}
+				return
+			}
+
+			var got ProblemDetails
+			if err := json.NewDecoder(res.Body).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			got.RequestID = ""
+			if !reflect.DeepEqual(got, tt.wantProblem) {
+				t.Errorf("got problem %+v, want %+v", got, tt.wantProblem)
+			}
+		})
+	}
+}
+
+func TestProblemDetailsFieldErrors(t *testing.T) {
+	mw := NewMiddlewares(pkg.Config{}, nil, nil)
+	app := fiber.New(fiber.Config{ErrorHandler: mw.ErrorHandler})
+	app.Post("/register", func(c *fiber.Ctx) error {
+		return errors.ValidationFailed("invalid register request", []errors.FieldError{{Field: "email", Code: "email", Message: "must be a valid email address"}})
+	})
+
+	req := httptest.NewRequest(fiber.MethodPost, "/register", nil)
+	req.Header.Set(fiber.HeaderAccept, ProblemContentType)
+	res, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got struct {
+		Status int                 `json:"status"`
+		Code   string              `json:"code"`
+		Errors []errors.FieldError `json:"errors"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != fiber.StatusBadRequest || got.Code != "VALIDATION_FAILED" || len(got.Errors) != 1 || got.Errors[0].Field != "email" {
+		t.Errorf("got problem %+v, want the field errors of VALIDATION_FAILED", got)
+	}
+}
@@ -0,0 +1,67 @@
+package middlewares
+
+import (
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis_rate/v10"
+)
+
+// localLimiter is the in-memory counterpart of redis_rate (same GCRA algorithm and results), used while
+// Redis is unavailable. Its buckets are per instance, behind a load balancer each instance allows the
+// full limit until Redis is back.
+type localLimiter struct {
+	mu sync.Mutex
+	// tats holds the theoretical arrival time of the next request of each bucket
+	tats      map[string]time.Time
+	nextSweep time.Time
+}
+
+// localLimiterSweepInterval bounds how long the buckets of clients that went away are kept.
+const localLimiterSweepInterval = time.Minute
+
+var fallbackLimiter = &localLimiter{tats: map[string]time.Time{}}
+
+func (l *localLimiter) Allow(key string, limit redis_rate.Limit) *redis_rate.Result {
+	now := time.Now()
+	interval := limit.Period / time.Duration(limit.Rate)
+	burstOffset := interval * time.Duration(limit.Burst)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.After(l.nextSweep) {
+		for k, tat := range l.tats {
+			if tat.Before(now) {
+				delete(l.tats, k)
+			}
+		}
+		l.nextSweep = now.Add(localLimiterSweepInterval)
+	}
+
+	tat := l.tats[key]
+	if tat.Before(now) {
+		tat = now
+	}
+	newTat := tat.Add(interval)
+	diff := now.Sub(newTat.Add(-burstOffset))
+
+	if diff < 0 {
+		return &redis_rate.Result{
+			Limit:      limit,
+			Allowed:    0,
+			Remaining:  0,
+			RetryAfter: -diff,
+			ResetAfter: tat.Sub(now),
+		}
+	}
+
+	l.tats[key] = newTat
+	return &redis_rate.Result{
+		Limit:      limit,
+		Allowed:    1,
+		Remaining:  int(diff / interval),
+		RetryAfter: -1,
+		ResetAfter: newTat.Sub(now),
+	}
+}
@@ -0,0 +1,79 @@
+package middlewares
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis_rate/v10"
+)
+
+func TestLocalLimiterAllow(t *testing.T) {
+	tests := []struct {
+		name          string
+		limit         redis_rate.Limit
+		requests      int
+		wantRemaining []int // remaining after each request, -1 for a rejection
+	}{
+		{name: "within the limit", limit: redis_rate.PerMinute(3), requests: 2, wantRemaining: []int{2, 1}},
+		{name: "over the limit", limit: redis_rate.PerMinute(3), requests: 5, wantRemaining: []int{2, 1, 0, -1, -1}},
+		{name: "burst above the rate", limit: redis_rate.Limit{Rate: 1, Period: time.Minute, Burst: 3}, requests: 4, wantRemaining: []int{2, 1, 0, -1}},
+		{name: "burst below the rate", limit: redis_rate.Limit{Rate: 10, Period: time.Minute, Burst: 1}, requests: 2, wantRemaining: []int{0, -1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := &localLimiter{tats: map[string]time.Time{}}
+
+			for i := 0; i < tt.requests; i++ {
+				res := limiter.Allow("client", tt.limit)
+				want := tt.wantRemaining[i]
+				if want < 0 {
+					if res.Allowed != 0 || res.RetryAfter <= 0 {
+						t.Fatalf("request %d: allowed %d retry after %v, want a rejection with a retry after", i+1, res.Allowed, res.RetryAfter)
+					}
+					continue
+				}
+				if res.Allowed != 1 || res.Remaining != want {
+					t.Fatalf("request %d: allowed %d remaining %d, want allowed with %d remaining", i+1, res.Allowed, res.Remaining, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLocalLimiterBucketsPerKey(t *testing.T) {
+	limiter := &localLimiter{tats: map[string]time.Time{}}
+	limit := redis_rate.PerMinute(1)
+
+	if res := limiter.Allow("ip:203.0.113.1", limit); res.Allowed != 1 {
+		t.Fatal("first request of the first client rejected")
+	}
+	if res := limiter.Allow("ip:203.0.113.1", limit); res.Allowed != 0 {
+		t.Error("second request of the first client allowed")
+	}
+	if res := limiter.Allow("ip:203.0.113.2", limit); res.Allowed != 1 {
+		t.Error("first request of another client rejected")
+	}
+}
+
+func TestLocalLimiterRefills(t *testing.T) {
+	limiter := &localLimiter{tats: map[string]time.Time{}}
+	// one request every 20ms
+	limit := redis_rate.Limit{Rate: 5, Period: 100 * time.Millisecond, Burst: 1}
+
+	if res := limiter.Allow("client", limit); res.Allowed != 1 {
+		t.Fatal("first request rejected")
+	}
+	res := limiter.Allow("client", limit)
+	if res.Allowed != 0 {
+		t.Fatal("second request allowed before the interval")
+	}
+	if res.RetryAfter > 20*time.Millisecond {
+		t.Errorf("retry after %v, want at most the 20ms interval", res.RetryAfter)
+	}
+
+	time.Sleep(res.RetryAfter + 5*time.Millisecond)
+	if res := limiter.Allow("client", limit); res.Allowed != 1 {
+		t.Error("request rejected after the retry after")
+	}
+}
@@ -1,26 +1,29 @@
 package middlewares
 
 import (
-	"context"
 	"fmt"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/DiansSopandi/goride_be/errors"
 	"github.com/DiansSopandi/goride_be/pkg"
+	"github.com/DiansSopandi/goride_be/pkg/metrics"
 	"github.com/go-redis/redis_rate/v10"
 	"github.com/gofiber/fiber/v2"
-	"github.com/golang-jwt/jwt/v5"
 )
 
 type RateLimiter struct {
 	limiter *redis_rate.Limiter
 }
 
-var (
-	contex = context.Background()
-	// rdb     *redis.Client
-	// once    sync.Once
-)
+// var (
+// 	rdb     *redis.Client
+// 	once    sync.Once
+// )
 
 func NewRateLimiter() *RateLimiter {
 	return &RateLimiter{
@@ -40,44 +43,214 @@ func NewRateLimiter() *RateLimiter {
 // func RateLimitMiddleware(limiter redis_rate.Limiter) func(c *fiber.Ctx) error {
 func (r *RateLimiter) RateLimitMiddleware(maxRequests *int, window *time.Duration) func(c *fiber.Ctx) error {
 	return func(c *fiber.Ctx) error {
-		// Implement rate limiting logic here
-		// For example, you can use a token bucket or leaky bucket algorithm
-		defaultMaxRequests := pkg.Cfg.Application.DefaultMaxRequestPerMinute
-		defaultWindow := time.Minute
+		// nil falls back to the defaults of each request, default_max_requests_per_minute is reloadable
+		rate := pkg.Runtime().DefaultMaxRequestPerMinute
+		period := time.Minute
 
-		if r.limiter == nil {
-			return errors.InternalError("Rate limiter not initialized")
+		if maxRequests != nil {
+			rate = *maxRequests
 		}
-
-		if maxRequests == nil {
-			maxRequests = &defaultMaxRequests
+		if window != nil {
+			period = *window
 		}
-		if window == nil {
-			window = &defaultWindow
+		if rate <= 0 {
+			return c.Next()
 		}
 
-		claims := c.Locals("user").(jwt.MapClaims)
-		userID := fmt.Sprintf("%v", claims["sub"])
-		key := "rate_limit:" + userID + ":" + c.Path()
+		key := "rate_limit:" + rateLimitIdentity(c) + ":" + c.Path()
 
 		// 50 request per menit per user
 		// res, err := limiter.Allow(contex, key, redis_rate.PerMinute(50))
 
 		limit := redis_rate.Limit{
-			Rate:   *maxRequests,
-			Period: *window,
-			Burst:  *maxRequests,
+			Rate:   rate,
+			Period: period,
+			Burst:  rate,
 		}
 
-		res, err := r.limiter.Allow(contex, key, limit)
-		if err != nil {
-			return errors.InternalError(fmt.Sprintf("Rate limit error: %v", err))
+		res := r.allow(c, key, limit)
+		setRateLimitHeaders(c, res)
+		if res.Allowed == 0 {
+			metrics.RateLimitRejected("route", c.Route().Path)
+			return errors.TooManyRequests("Rate limit exceeded, please try again later")
 		}
 
+		return c.Next()
+	}
+}
+
+// ApiKeyRateLimitMiddleware limits each API key over all routes, using the rate_limit_per_minute of the key
+// or default_max_requests_per_minute. Requests authenticated with a JWT are not affected.
+func (r *RateLimiter) ApiKeyRateLimitMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		apiKey := CurrentApiKey(c)
+		if apiKey == nil {
+			return c.Next()
+		}
+
+		rate := pkg.Runtime().DefaultMaxRequestPerMinute
+		if apiKey.RateLimitPerMinute != nil {
+			rate = *apiKey.RateLimitPerMinute
+		}
+		if rate <= 0 {
+			return c.Next()
+		}
+
+		res := r.allow(c, fmt.Sprintf("rate_limit:api_key:%d", apiKey.ID), redis_rate.PerMinute(rate))
+		setRateLimitHeaders(c, res)
 		if res.Allowed == 0 {
-			return errors.TooManyRequests("Rate limit exceeded, please try again later")
+			// the API key limit spans every route, keep the label bounded
+			metrics.RateLimitRejected("api_key", "*")
+			return errors.TooManyRequests(fmt.Sprintf("Rate limit of API key %s exceeded", apiKey.Prefix))
 		}
 
 		return c.Next()
 	}
 }
+
+// Policies applies the rate_limit.policies in effect, reloaded with the configuration. Every policy matching
+// the request takes a request from the bucket of the client, the first exhausted one rejects it.
+// The RateLimit-* headers describe the policy closest to its limit.
+func (r *RateLimiter) Policies() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var closest *redis_rate.Result
+		for _, policy := range pkg.Runtime().RateLimitPolicies {
+			if !policyMatches(policy, c) {
+				continue
+			}
+			identity, ok := policyIdentity(policy, c)
+			if !ok {
+				continue
+			}
+
+			period, err := time.ParseDuration(policy.Period)
+			if err != nil || period <= 0 {
+				// rejected by the validation of the configuration, never applied
+				continue
+			}
+			limit := redis_rate.Limit{Rate: policy.Requests, Period: period, Burst: policy.Burst}
+			if limit.Burst <= 0 {
+				limit.Burst = limit.Rate
+			}
+
+			res := r.allow(c, "rate_limit:policy:"+policy.Name+":"+identity, limit)
+			if res.Allowed == 0 {
+				setRateLimitHeaders(c, res)
+				// policy names come from the configuration, the label stays bounded
+				metrics.RateLimitRejected(policy.Name, "*")
+				return errors.TooManyRequests(fmt.Sprintf("Rate limit of policy %s exceeded for %s", policy.Name, identity))
+			}
+			if closest == nil || res.Remaining < closest.Remaining {
+				closest = res
+			}
+		}
+
+		if closest != nil {
+			setRateLimitHeaders(c, closest)
+		}
+		return c.Next()
+	}
+}
+
+// redisDegraded is set while Redis errors, so the fallback is logged once instead of on every request.
+var redisDegraded atomic.Bool
+
+// allow takes a request from the bucket of key in Redis, or in the local fallback while Redis is unavailable.
+// Limiting per instance is better than failing every request or not limiting at all.
+func (r *RateLimiter) allow(c *fiber.Ctx, key string, limit redis_rate.Limit) *redis_rate.Result {
+	if r.limiter != nil {
+		res, err := r.limiter.Allow(c.UserContext(), key, limit)
+		if err == nil {
+			if redisDegraded.CompareAndSwap(true, false) {
+				slog.Info("redis available again, rate limits are shared again")
+			}
+			return res
+		}
+		if redisDegraded.CompareAndSwap(false, true) {
+			slog.Warn("redis unavailable, rate limiting per instance until it is back", "error", err)
+		}
+	}
+	return fallbackLimiter.Allow(key, limit)
+}
+
+// setRateLimitHeaders sets the RateLimit-* headers of the IETF draft, and Retry-After when res is a rejection.
+func setRateLimitHeaders(c *fiber.Ctx, res *redis_rate.Result) {
+	c.Set("RateLimit-Limit", strconv.Itoa(res.Limit.Burst))
+	c.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	c.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.ResetAfter)))
+	c.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", res.Limit.Rate, ceilSeconds(res.Limit.Period)))
+	if res.Allowed == 0 {
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(max(ceilSeconds(res.RetryAfter), 1)))
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	return int(math.Ceil(d.Seconds()))
+}
+
+// policyMatches reports whether the request is one of the routes and methods of the policy.
+func policyMatches(policy pkg.RateLimitPolicy, c *fiber.Ctx) bool {
+	if len(policy.Methods) > 0 && !containsFold(policy.Methods, c.Method()) {
+		return false
+	}
+	if len(policy.Routes) == 0 {
+		return true
+	}
+
+	path := c.Path()
+	for _, route := range policy.Routes {
+		if prefix, ok := strings.CutSuffix(route, "/*"); ok {
+			if path == prefix || strings.HasPrefix(path, prefix+"/") {
+				return true
+			}
+			continue
+		}
+		if path == route || matchRoutePattern(route, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// policyIdentity returns the bucket of the client for the key of the policy, false when the policy
+// does not apply to the client (api_key policies on requests without an API key).
+func policyIdentity(policy pkg.RateLimitPolicy, c *fiber.Ctx) (string, bool) {
+	switch policy.Key {
+	case "ip":
+		return "ip:" + c.IP(), true
+	case "user":
+		return rateLimitIdentity(c), true
+	case "api_key":
+		if apiKey := CurrentApiKey(c); apiKey != nil {
+			return fmt.Sprintf("api_key:%d", apiKey.ID), true
+		}
+		return "", false
+	case "global":
+		return "global", true
+	}
+	return "", false
+}
+
+// rateLimitIdentity keeps API keys in their own buckets, separate from the interactive sessions of their owner.
+// Anonymous requests are limited per client ip.
+func rateLimitIdentity(c *fiber.Ctx) string {
+	if apiKey := CurrentApiKey(c); apiKey != nil {
+		return fmt.Sprintf("api_key:%d", apiKey.ID)
+	}
+	if userID, err := CurrentUserID(c); err == nil {
+		return fmt.Sprintf("user:%d", userID)
+	}
+	return "ip:" + c.IP()
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
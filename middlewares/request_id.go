@@ -0,0 +1,36 @@
+package middlewares
+
+import (
+	"github.com/DiansSopandi/goride_be/pkg"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const maxRequestIDLength = 128
+
+// RequestID accepts the X-Request-ID of the caller or generates one, echoes it in the response
+// and stores it for pkg.Logger, the audit log and the error responses.
+func RequestID(c *fiber.Ctx) error {
+	id := c.Get(pkg.RequestIDHeader)
+	if !validRequestID(id) {
+		id = uuid.NewString()
+	}
+
+	c.Locals(pkg.RequestIDKey, id)
+	c.Set(pkg.RequestIDHeader, id)
+	return c.Next()
+}
+
+// validRequestID only keeps ids that are safe to copy into log lines and headers.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		isAlnum := (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
+		if !isAlnum && r != '-' && r != '_' && r != '.' && r != ':' {
+			return false
+		}
+	}
+	return true
+}
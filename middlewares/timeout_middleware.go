@@ -0,0 +1,40 @@
+package middlewares
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"time"
+
+	"github.com/DiansSopandi/goride_be/errors"
+	"github.com/DiansSopandi/goride_be/repository"
+	"github.com/gofiber/fiber/v2"
+)
+
+// RequestTimeout puts a deadline on c.UserContext(), the queries, Redis commands and provider calls
+// of the request give up once it passed. A failure caused by the request deadline becomes a 504,
+// one caused by a shorter deadline below it (e.g. database.query_timeout) a 503.
+func RequestTimeout(timeout time.Duration) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.UserContext()
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+			c.SetUserContext(ctx)
+		}
+
+		err := c.Next()
+		if err == nil {
+			return nil
+		}
+
+		if stderrors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return errors.RequestTimeout(fmt.Sprintf("%s %s exceeded the request timeout of %s: %v", c.Method(), c.Path(), timeout, err)).WithCause(err)
+		}
+		if repository.IsQueryTimeout(err) {
+			return errors.ServiceUnavailable(fmt.Sprintf("%s %s timed out: %v", c.Method(), c.Path(), err)).WithCause(err)
+		}
+		return err
+	}
+}
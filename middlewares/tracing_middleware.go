@@ -0,0 +1,73 @@
+package middlewares
+
+import (
+	"fmt"
+
+	"github.com/DiansSopandi/goride_be/pkg"
+	"github.com/DiansSopandi/goride_be/pkg/tracing"
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracing starts the server span of the request, continuing the trace of the caller when it sends
+// a W3C traceparent header. The span context is stored in c.UserContext() for the layers below.
+func Tracing(c *fiber.Ctx) error {
+	ctx := otel.GetTextMapPropagator().Extract(c.UserContext(), requestHeaderCarrier{c})
+
+	ctx, span := tracing.Tracer().Start(ctx, c.Method(),
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(c.Method()),
+			semconv.URLPath(c.Path()),
+			semconv.URLScheme(c.Protocol()),
+			semconv.ClientAddress(c.IP()),
+			semconv.UserAgentOriginal(c.Get(fiber.HeaderUserAgent)),
+			attribute.String(pkg.RequestIDKey, pkg.RequestID(c)),
+		),
+	)
+	defer span.End()
+
+	c.SetUserContext(ctx)
+
+	err := c.Next()
+
+	status := responseStatus(c, err)
+	span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+	if !routeNotFound(err) {
+		span.SetName(c.Method() + " " + c.Route().Path)
+		span.SetAttributes(semconv.HTTPRoute(c.Route().Path))
+	}
+	if status >= fiber.StatusInternalServerError {
+		if err != nil {
+			span.RecordError(err)
+		}
+		span.SetStatus(codes.Error, fmt.Sprintf("HTTP %d", status))
+	}
+
+	return err
+}
+
+// requestHeaderCarrier reads the propagation headers straight from the fasthttp request.
+type requestHeaderCarrier struct {
+	c *fiber.Ctx
+}
+
+func (h requestHeaderCarrier) Get(key string) string {
+	return h.c.Get(key)
+}
+
+func (h requestHeaderCarrier) Set(key, value string) {
+	h.c.Request().Header.Set(key, value)
+}
+
+func (h requestHeaderCarrier) Keys() []string {
+	keys := make([]string, 0)
+	h.c.Request().Header.VisitAll(func(key, _ []byte) {
+		keys = append(keys, string(key))
+	})
+	return keys
+}
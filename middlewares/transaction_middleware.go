@@ -8,10 +8,12 @@ import (
 const UserServiceCtxKey = "userServiceWithTx"
 const TxContextKey = "tx"
 
-func WithTransaction(handler fiber.Handler) fiber.Handler {
+// WithTransaction runs handler in a transaction stored under TxContextKey, committed when the
+// handler succeeds and rolled back when it returns an error. The db.AfterCommit hooks registered
+// with the user context run once the transaction is committed.
+func (m *Middlewares) WithTransaction(handler fiber.Handler) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		dbConn := db.InitDatabase()
-		tx, err := dbConn.Begin() // start transaction commit rollback
+		tx, err := m.db.BeginTx(c.UserContext(), nil) // start transaction commit rollback
 		if err != nil {
 			return fiber.NewError(fiber.StatusInternalServerError, "Failed to start transaction")
 		}
@@ -27,6 +29,9 @@ func WithTransaction(handler fiber.Handler) fiber.Handler {
 		// Simpan TX ke dalam context
 		c.Locals(TxContextKey, tx)
 
+		ctx, hooks := db.WithAfterCommit(c.UserContext())
+		c.SetUserContext(ctx)
+
 		// Jalankan handler utama
 		err = handler(c)
 
@@ -55,6 +60,7 @@ func WithTransaction(handler fiber.Handler) fiber.Handler {
 				// return pkg.ResponseApiErrorInternalServer(c, fmt.Sprintf("Failed to commit transaction: %v", err))
 			}
 		}
+		hooks.Run()
 
 		return nil
 	}
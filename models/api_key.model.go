@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type ApiKey struct {
+	ID                 uint           `json:"id" db:"id"`
+	Name               string         `json:"name" db:"name"`
+	Prefix             string         `json:"prefix" db:"prefix"` // public part of the key, safe to display
+	SecretHash         string         `json:"-" db:"secret_hash"`
+	OwnerUserID        uint           `json:"owner_user_id" db:"owner_user_id"`
+	Scopes             pq.StringArray `json:"scopes" db:"scopes" swaggertype:"array,string"`
+	RateLimitPerMinute *int           `json:"rate_limit_per_minute,omitempty" db:"rate_limit_per_minute"`
+	ExpiresAt          *time.Time     `json:"expires_at,omitempty" db:"expires_at"`
+	LastUsedAt         *time.Time     `json:"last_used_at,omitempty" db:"last_used_at"`
+	CreatedBy          *uint          `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt          time.Time      `json:"created_at" db:"created_at"`
+	RevokedAt          *time.Time     `json:"revoked_at,omitempty" db:"revoked_at"`
+}
+
+func (k *ApiKey) TableName() string {
+	return "api_keys"
+}
+
+// HasScope reports whether the key was granted scope, "*" grants every scope.
+func (k *ApiKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope || s == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// IsUsable reports whether the key is neither revoked nor expired.
+func (k *ApiKey) IsUsable() bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || k.ExpiresAt.After(time.Now()))
+}
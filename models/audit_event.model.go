@@ -0,0 +1,30 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	AuditActorUser   = "user"
+	AuditActorApiKey = "api_key"
+	AuditActorSystem = "system"
+)
+
+type AuditEvent struct {
+	ID          uint64          `json:"id" db:"id"`
+	ActorUserID *uint           `json:"actor_user_id,omitempty" db:"actor_user_id"`
+	ActorType   string          `json:"actor_type" db:"actor_type"`
+	Action      string          `json:"action" db:"action"`
+	TargetType  string          `json:"target_type" db:"target_type"`
+	TargetID    string          `json:"target_id" db:"target_id"`
+	Before      json.RawMessage `json:"before,omitempty" db:"before_data" swaggertype:"object"`
+	After       json.RawMessage `json:"after,omitempty" db:"after_data" swaggertype:"object"`
+	IPAddress   string          `json:"ip_address" db:"ip_address"`
+	RequestID   string          `json:"request_id,omitempty" db:"request_id"`
+	CreatedAt   time.Time       `json:"created_at" db:"created_at"`
+}
+
+func (e *AuditEvent) TableName() string {
+	return "audit_events"
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type FeatureFlag struct {
+	ID                uint           `json:"id" db:"id"`
+	Key               string         `json:"key" db:"key"`
+	Description       string         `json:"description,omitempty" db:"description"`
+	Enabled           bool           `json:"enabled" db:"enabled"`                          // kill switch, false disables the flag for everyone
+	RolloutPercentage int            `json:"rollout_percentage" db:"rollout_percentage"`    // share of the users, bucketed by hashed user id
+	Roles             pq.StringArray `json:"roles" db:"roles" swaggertype:"array,string"`   // empty targets every role
+	Cities            pq.StringArray `json:"cities" db:"cities" swaggertype:"array,string"` // empty targets every city
+	CreatedBy         *uint          `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt         time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at" db:"updated_at"`
+}
+
+func (f *FeatureFlag) TableName() string {
+	return "feature_flags"
+}
+
+// IsEnabledFor reports whether the flag is on for a user having roles in city. The rollout bucket
+// depends on the flag key and the user id only, a user keeps the flag while the percentage grows and
+// different flags reach different users. Anonymous users (userID 0) only get fully rolled out flags.
+func (f *FeatureFlag) IsEnabledFor(userID uint, roles []string, city string) bool {
+	if !f.Enabled {
+		return false
+	}
+
+	if len(f.Roles) > 0 && !containsAny(f.Roles, roles) {
+		return false
+	}
+	if len(f.Cities) > 0 && !containsAny(f.Cities, []string{city}) {
+		return false
+	}
+
+	if f.RolloutPercentage >= 100 {
+		return true
+	}
+	if userID == 0 || f.RolloutPercentage <= 0 {
+		return false
+	}
+	return rolloutBucket(f.Key, userID) < f.RolloutPercentage
+}
+
+// rolloutBucket maps the user to 0-99 for the flag.
+func rolloutBucket(key string, userID uint) int {
+	h := fnv.New32a()
+	h.Write([]byte(key + ":" + strconv.FormatUint(uint64(userID), 10)))
+	return int(h.Sum32() % 100)
+}
+
+func containsAny(targets []string, values []string) bool {
+	for _, target := range targets {
+		for _, value := range values {
+			if value != "" && strings.EqualFold(target, value) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package models
+
+import "testing"
+
+func TestFeatureFlagIsEnabledFor(t *testing.T) {
+	tests := []struct {
+		name   string
+		flag   FeatureFlag
+		userID uint
+		roles  []string
+		city   string
+		want   bool
+	}{
+		{name: "disabled", flag: FeatureFlag{Key: "f", RolloutPercentage: 100}, userID: 1, want: false},
+		{name: "everyone", flag: FeatureFlag{Key: "f", Enabled: true, RolloutPercentage: 100}, userID: 1, want: true},
+		{name: "anonymous fully rolled out", flag: FeatureFlag{Key: "f", Enabled: true, RolloutPercentage: 100}, want: true},
+		{name: "anonymous partial rollout", flag: FeatureFlag{Key: "f", Enabled: true, RolloutPercentage: 99}, want: false},
+		{name: "zero percent", flag: FeatureFlag{Key: "f", Enabled: true}, userID: 1, want: false},
+		{name: "targeted role", flag: FeatureFlag{Key: "f", Enabled: true, RolloutPercentage: 100, Roles: []string{"driver"}}, userID: 1, roles: []string{"rider", "driver"}, want: true},
+		{name: "other role", flag: FeatureFlag{Key: "f", Enabled: true, RolloutPercentage: 100, Roles: []string{"driver"}}, userID: 1, roles: []string{"rider"}, want: false},
+		{name: "targeted city any case", flag: FeatureFlag{Key: "f", Enabled: true, RolloutPercentage: 100, Cities: []string{"Bandung"}}, userID: 1, city: "bandung", want: true},
+		{name: "unknown city", flag: FeatureFlag{Key: "f", Enabled: true, RolloutPercentage: 100, Cities: []string{"Bandung"}}, userID: 1, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.flag.IsEnabledFor(tt.userID, tt.roles, tt.city); got != tt.want {
+				t.Errorf("IsEnabledFor(%d, %v, %q) = %v, want %v", tt.userID, tt.roles, tt.city, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFeatureFlagPercentageRollout(t *testing.T) {
+	const users = 10000
+
+	tests := []struct {
+		percentage int
+	}{
+		{percentage: 1},
+		{percentage: 10},
+		{percentage: 30},
+		{percentage: 50},
+		{percentage: 90},
+	}
+
+	for _, tt := range tests {
+		flag := FeatureFlag{Key: "new_checkout", Enabled: true, RolloutPercentage: tt.percentage}
+		wider := FeatureFlag{Key: "new_checkout", Enabled: true, RolloutPercentage: tt.percentage + 10}
+
+		enabled := 0
+		for userID := uint(1); userID <= users; userID++ {
+			if !flag.IsEnabledFor(userID, nil, "") {
+				continue
+			}
+			enabled++
+			// growing the rollout never takes the flag away from a user
+			if !wider.IsEnabledFor(userID, nil, "") {
+				t.Fatalf("user %d lost the flag when the rollout grew from %d%%", userID, tt.percentage)
+			}
+		}
+
+		share := enabled * 100 / users
+		if share < tt.percentage-2 || share > tt.percentage+2 {
+			t.Errorf("%d%% rollout enabled the flag for %d%% of the users", tt.percentage, share)
+		}
+	}
+}
+
+func TestRolloutBucketDependsOnTheFlag(t *testing.T) {
+	same := 0
+	for userID := uint(1); userID <= 1000; userID++ {
+		if rolloutBucket("flag_a", userID) == rolloutBucket("flag_b", userID) {
+			same++
+		}
+	}
+	// independent buckets match for about 1% of the users
+	if same > 50 {
+		t.Errorf("%d of 1000 users share the bucket of two flags", same)
+	}
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// OutboxEvent is a domain event written in the transaction of its change, the outbox relay publishes it
+// once committed.
+type OutboxEvent struct {
+	ID            uint64          `json:"id" db:"id"`
+	EventID       string          `json:"event_id" db:"event_id"` // seen by the subscribers, stays the same across retries
+	EventType     string          `json:"event_type" db:"event_type"`
+	AggregateType string          `json:"aggregate_type" db:"aggregate_type"`
+	AggregateID   string          `json:"aggregate_id" db:"aggregate_id"`
+	Payload       json.RawMessage `json:"payload" db:"payload" swaggertype:"object"`
+	RequestID     string          `json:"request_id,omitempty" db:"request_id"`
+	Attempts      int             `json:"attempts" db:"attempts"`
+	DeliveredTo   pq.StringArray  `json:"delivered_to" db:"delivered_to" swaggertype:"array,string"` // publishers that took it, skipped on retry
+	NextAttemptAt time.Time       `json:"next_attempt_at" db:"next_attempt_at"`
+	LastError     string          `json:"last_error,omitempty" db:"last_error"`
+	PublishedAt   *time.Time      `json:"published_at,omitempty" db:"published_at"`
+	CreatedAt     time.Time       `json:"created_at" db:"created_at"`
+}
+
+func (e *OutboxEvent) TableName() string {
+	return "outbox_events"
+}
+
+// OutboxDeadLetter is an event the relay gave up on after outbox.max_attempts, see the `outbox replay` command.
+type OutboxDeadLetter struct {
+	ID            uint64          `json:"id" db:"id"`
+	EventID       string          `json:"event_id" db:"event_id"`
+	EventType     string          `json:"event_type" db:"event_type"`
+	AggregateType string          `json:"aggregate_type" db:"aggregate_type"`
+	AggregateID   string          `json:"aggregate_id" db:"aggregate_id"`
+	Payload       json.RawMessage `json:"payload" db:"payload" swaggertype:"object"`
+	RequestID     string          `json:"request_id,omitempty" db:"request_id"`
+	Attempts      int             `json:"attempts" db:"attempts"`
+	DeliveredTo   pq.StringArray  `json:"delivered_to" db:"delivered_to" swaggertype:"array,string"`
+	LastError     string          `json:"last_error,omitempty" db:"last_error"`
+	CreatedAt     time.Time       `json:"created_at" db:"created_at"`
+	FailedAt      time.Time       `json:"failed_at" db:"failed_at"`
+}
+
+func (d *OutboxDeadLetter) TableName() string {
+	return "outbox_dead_letters"
+}
@@ -1,9 +1,19 @@
 package models
 
+import (
+	"encoding/json"
+	"time"
+)
+
 type UserProvider struct {
-	ID            uint   `json:"id" db:"id"`
-	UserID        uint   `json:"user_id" db:"user_id"`
-	Provider      string `json:"provider" db:"provider"`
-	ProviderID    string `json:"provider_id" db:"provider_id"`
-	ProviderEmail string `json:"provider_email" db:"provider_email"`
+	ID            uint            `json:"id" db:"id"`
+	UserID        uint            `json:"user_id" db:"user_id"`
+	Provider      string          `json:"provider" db:"provider"`
+	ProviderID    string          `json:"provider_id" db:"provider_id"`
+	ProviderEmail string          `json:"provider_email" db:"provider_email"`
+	ProviderData  json.RawMessage `json:"provider_data,omitempty" db:"provider_data" swaggertype:"object"` // raw claims returned by the provider
+	IsActive      bool            `json:"is_active" db:"is_active"`
+	LastLoginAt   *time.Time      `json:"last_login_at,omitempty" db:"last_login_at"`
+	CreatedAt     time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at" db:"updated_at"`
 }
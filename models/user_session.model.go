@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+type UserSession struct {
+	ID               string     `json:"id" db:"id"` // also the sid claim of the tokens
+	UserID           uint       `json:"user_id" db:"user_id"`
+	RefreshTokenHash string     `json:"-" db:"refresh_token_hash"`
+	DeviceName       string     `json:"device_name" db:"device_name"`
+	UserAgent        string     `json:"user_agent" db:"user_agent"`
+	IPAddress        string     `json:"ip_address" db:"ip_address"`
+	CreatedAt        time.Time  `json:"created_at" db:"created_at"`
+	LastSeenAt       time.Time  `json:"last_seen_at" db:"last_seen_at"`
+	ExpiresAt        time.Time  `json:"expires_at" db:"expires_at"`
+	RevokedAt        *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+}
+
+func (s *UserSession) TableName() string {
+	return "user_sessions"
+}
@@ -0,0 +1,15 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const usedApiKeyPrefix = "api_key:used:"
+
+// MarkApiKeyUsed returns true at most once per interval for a key,
+// so last_used_at is not written to postgres on every request.
+func MarkApiKeyUsed(ctx context.Context, apiKeyID uint, interval time.Duration) (bool, error) {
+	return GetRedisClient().SetNX(ctx, fmt.Sprintf("%s%d", usedApiKeyPrefix, apiKeyID), 1, interval).Result()
+}
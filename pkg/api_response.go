@@ -17,6 +17,7 @@ const (
 	HttpStatusTooManyRequests     HttpStatusCode = 429
 	HttpStatusGone                HttpStatusCode = 410
 	HttpStatusServiceUnavailable  HttpStatusCode = 503
+	HttpStatusGatewayTimeout      HttpStatusCode = 504
 )
 
 type ApiStatusOK string
@@ -40,6 +41,7 @@ const (
 	ApiStatusErrorTooManyRequests     ApiStatusError = "error_too_many_requests"
 	ApiStatusErrorGone                ApiStatusError = "error_gone"
 	ApiStatusErrorServiceUnavailable  ApiStatusError = "error_service_unavailable"
+	ApiStatusErrorGatewayTimeout      ApiStatusError = "error_gateway_timeout"
 	ApiErrorUnprocessAble             ApiStatusError = "error_unprocessable"
 	ApiErrorLimitReached              ApiStatusError = "error_limit_reached"
 	ApiStatusErrorConflict            ApiStatusError = "error_conflict"
@@ -52,6 +54,8 @@ type DetailResponse struct {
 	StatusCode int    `json:"status_code" example:"200"`
 	Method     string `json:"method" example:"GET"`
 	Status     string `json:"status" example:"success_ok"`
+	RequestID  string `json:"request_id,omitempty" example:"0b6f1f9e-3c1d-4a43-9d55-5f7f3bb0d6a1"`
+	TraceID    string `json:"trace_id,omitempty" example:"4bf92f3577b34da6a3ce929d0e0e4736"`
 } // @name	DetailResponse
 
 type ResponseApi struct {
@@ -75,6 +79,8 @@ func ResponseApiWrapper(ctx *fiber.Ctx, msg string, status string, statusCode in
 		Path:       ctx.Request().URI().String(),
 		Method:     string(ctx.Request().Header.Method()),
 		Status:     status,
+		RequestID:  RequestID(ctx),
+		TraceID:    TraceID(ctx),
 	}
 
 	if statusCode >= 400 {
@@ -122,6 +128,8 @@ func ResponseApiErrorWrapper(ctx *fiber.Ctx, status ApiStatusError, statusCode H
 		Path:       ctx.Request().URI().String(),
 		Method:     string(ctx.Request().Header.Method()),
 		Status:     string(status),
+		RequestID:  RequestID(ctx),
+		TraceID:    TraceID(ctx),
 	}
 
 	CreateAccessLog(ctx, "[ACCESS:API][ERROR]", int(statusCode), message)
@@ -248,6 +256,8 @@ func ResponseApiErrorWithStatusAndData(ctx *fiber.Ctx, status ApiStatusError, da
 		Path:       ctx.Request().URI().String(),
 		Method:     string(ctx.Request().Header.Method()),
 		Status:     string(status),
+		RequestID:  RequestID(ctx),
+		TraceID:    TraceID(ctx),
 	}
 
 	return ctx.Status(int(HttpStatusInternalServerError)).JSON(ResponseApi{
@@ -0,0 +1,183 @@
+// Package cache keeps read results in redis under tags, a write invalidates every entry of its tags by
+// bumping their versions. It is disabled unless application.enable_cache is set, and a redis failure
+// falls back to loading the value.
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/DiansSopandi/goride_be/db"
+	"github.com/DiansSopandi/goride_be/pkg"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	keyPrefix = "cache:"
+	tagPrefix = "cache:tag:"
+
+	// lockTTL bounds how long the other callers wait for the one loading a missing value
+	lockTTL      = 5 * time.Second
+	lockPollWait = 50 * time.Millisecond
+)
+
+// Tags of the cached data, a write invalidates the tags of the data it changes.
+const (
+	RolesTag = "roles"
+	UsersTag = "users"
+)
+
+// Enabled reports whether application.enable_cache is set.
+func Enabled() bool {
+	return pkg.Cfg.Application.EnableCache
+}
+
+// UserTag is the tag of the cached values about a user.
+func UserTag(userID uint) string {
+	return fmt.Sprintf("user:%d", userID)
+}
+
+// Remember returns the value cached under key, on a miss it caches what load returns for ttl under
+// tags. Only one caller loads a missing value, the others wait for it up to lockTTL and load it
+// themselves when it does not show up. Errors of load are returned and not cached.
+func Remember(ctx context.Context, key string, ttl time.Duration, tags []string, load func() ([]byte, error)) ([]byte, error) {
+	if !Enabled() {
+		return load()
+	}
+
+	rdb := pkg.GetRedisClient()
+	entryKey, err := versionedKey(ctx, rdb, key, tags)
+	if err != nil {
+		slog.Warn("cache unavailable, loading the value", "key", key, "error", err)
+		return load()
+	}
+
+	data, err := rdb.Get(ctx, entryKey).Bytes()
+	if err == nil {
+		return data, nil
+	}
+	if !errors.Is(err, redis.Nil) {
+		slog.Warn("cache unavailable, loading the value", "key", key, "error", err)
+		return load()
+	}
+
+	lockKey := entryKey + ":lock"
+	locked, err := rdb.SetNX(ctx, lockKey, "1", lockTTL).Result()
+	if err != nil {
+		slog.Warn("cache unavailable, loading the value", "key", key, "error", err)
+		return load()
+	}
+	if !locked {
+		if data, ok := waitFor(ctx, rdb, entryKey); ok {
+			return data, nil
+		}
+		return load()
+	}
+	defer rdb.Del(context.WithoutCancel(ctx), lockKey)
+
+	data, err = load()
+	if err != nil {
+		return nil, err
+	}
+	if err := rdb.Set(ctx, entryKey, data, ttl).Err(); err != nil {
+		slog.Warn("failed to cache the value", "key", key, "error", err)
+	}
+	return data, nil
+}
+
+// RememberJSON is Remember for a value encoded in json.
+func RememberJSON[T any](ctx context.Context, key string, ttl time.Duration, tags []string, load func() (T, error)) (T, error) {
+	var value T
+	loaded := false
+	data, err := Remember(ctx, key, ttl, tags, func() ([]byte, error) {
+		v, err := load()
+		if err != nil {
+			return nil, err
+		}
+		value, loaded = v, true
+		return json.Marshal(v)
+	})
+	if err != nil || loaded {
+		return value, err
+	}
+	if err := json.Unmarshal(data, &value); err != nil {
+		// written by another version of T, load it again
+		return load()
+	}
+	return value, nil
+}
+
+// InvalidateTags drops the cached values of tags, call it once the write they are about is committed.
+func InvalidateTags(ctx context.Context, tags ...string) error {
+	if !Enabled() || len(tags) == 0 {
+		return nil
+	}
+	_, err := pkg.GetRedisClient().Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, tag := range tags {
+			pipe.Incr(ctx, tagPrefix+tag)
+		}
+		return nil
+	})
+	return err
+}
+
+// InvalidateAfterCommit invalidates tags once the transaction of ctx is committed, see db.AfterCommit.
+// A failure is logged, the change shows up once the entries expire.
+func InvalidateAfterCommit(ctx context.Context, tags ...string) {
+	db.AfterCommit(ctx, func() {
+		if err := InvalidateTags(context.WithoutCancel(ctx), tags...); err != nil {
+			slog.Warn("failed to invalidate the cache, the change shows up once it expires", "tags", tags, "error", err)
+		}
+	})
+}
+
+// versionedKey appends the versions of tags to key, the entries cached before a tag was invalidated are
+// no longer read and expire with their ttl.
+func versionedKey(ctx context.Context, rdb *redis.Client, key string, tags []string) (string, error) {
+	if len(tags) == 0 {
+		return keyPrefix + key, nil
+	}
+
+	tagKeys := make([]string, len(tags))
+	for i, tag := range tags {
+		tagKeys[i] = tagPrefix + tag
+	}
+	versions, err := rdb.MGet(ctx, tagKeys...).Result()
+	if err != nil {
+		return "", err
+	}
+
+	parts := make([]string, len(versions))
+	for i, version := range versions {
+		if version == nil {
+			version = "0"
+		}
+		parts[i] = fmt.Sprintf("%s=%v", tags[i], version)
+	}
+	return keyPrefix + key + "@" + strings.Join(parts, ","), nil
+}
+
+// waitFor polls entryKey while another caller loads it.
+func waitFor(ctx context.Context, rdb *redis.Client, entryKey string) ([]byte, bool) {
+	deadline := time.Now().Add(lockTTL)
+	for time.Now().Before(deadline) {
+		select {
+		case <-ctx.Done():
+			return nil, false
+		case <-time.After(lockPollWait):
+		}
+		data, err := rdb.Get(ctx, entryKey).Bytes()
+		if err == nil {
+			return data, true
+		}
+		if !errors.Is(err, redis.Nil) {
+			return nil, false
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,139 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// tagStore answers the MGET and INCR of the tag versions in memory, the client never dials.
+type tagStore struct {
+	versions map[string]int64
+	err      error
+}
+
+func (s *tagStore) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (s *tagStore) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if s.err != nil {
+			cmd.SetErr(s.err)
+			return s.err
+		}
+		args := cmd.Args()
+		switch cmd := cmd.(type) {
+		case *redis.SliceCmd:
+			values := make([]interface{}, len(args)-1)
+			for i, key := range args[1:] {
+				if version, ok := s.versions[key.(string)]; ok {
+					values[i] = strconv.FormatInt(version, 10)
+				}
+			}
+			cmd.SetVal(values)
+		case *redis.IntCmd:
+			s.versions[args[1].(string)]++
+			cmd.SetVal(s.versions[args[1].(string)])
+		}
+		return nil
+	}
+}
+
+func (s *tagStore) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func newTagStore(t *testing.T) (*redis.Client, *tagStore) {
+	store := &tagStore{versions: map[string]int64{}}
+	rdb := redis.NewClient(&redis.Options{Addr: "127.0.0.1:0"})
+	rdb.AddHook(store)
+	t.Cleanup(func() { rdb.Close() })
+	return rdb, store
+}
+
+func TestVersionedKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		key      string
+		tags     []string
+		versions map[string]int64
+		want     string
+	}{
+		{
+			name: "no tags",
+			key:  "roles:all",
+			want: "cache:roles:all",
+		},
+		{
+			name: "tags never invalidated",
+			key:  "users:7",
+			tags: []string{UsersTag, UserTag(7)},
+			want: "cache:users:7@users=0,user:7=0",
+		},
+		{
+			name:     "invalidated tags",
+			key:      "users:7",
+			tags:     []string{UsersTag, UserTag(7)},
+			versions: map[string]int64{tagPrefix + UsersTag: 3, tagPrefix + UserTag(8): 5},
+			want:     "cache:users:7@users=3,user:7=0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rdb, store := newTagStore(t)
+			for tag, version := range tt.versions {
+				store.versions[tag] = version
+			}
+
+			got, err := versionedKey(context.Background(), rdb, tt.key, tt.tags)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got key %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInvalidatingATagChangesOnlyItsKeys(t *testing.T) {
+	ctx := context.Background()
+	rdb, _ := newTagStore(t)
+
+	keys := func() (string, string) {
+		user, err := versionedKey(ctx, rdb, "users:7", []string{UserTag(7)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		roles, err := versionedKey(ctx, rdb, "roles:all", []string{RolesTag})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return user, roles
+	}
+
+	user, roles := keys()
+	if err := rdb.Incr(ctx, tagPrefix+UserTag(7)).Err(); err != nil {
+		t.Fatal(err)
+	}
+	userAfter, rolesAfter := keys()
+
+	if userAfter == user {
+		t.Errorf("key %q of the invalidated tag did not change", user)
+	}
+	if rolesAfter != roles {
+		t.Errorf("key of another tag changed from %q to %q", roles, rolesAfter)
+	}
+}
+
+func TestVersionedKeyRedisError(t *testing.T) {
+	rdb, store := newTagStore(t)
+	store.err = errors.New("connection refused")
+
+	if key, err := versionedKey(context.Background(), rdb, "users:7", []string{UsersTag}); err == nil {
+		t.Errorf("got key %q, want the redis error so the value is loaded", key)
+	}
+}
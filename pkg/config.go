@@ -1,77 +1,209 @@
 package pkg
 
 import (
-	"log"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
 	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/spf13/viper"
 )
 
 type DatabaseConfig struct {
-	Host        string `mapstructure:"db_host"`
-	Port        int    `mapstructure:"db_port"`
-	User        string `mapstructure:"db_user"`
-	Password    string `mapstructure:"db_password"`
-	DBName      string `mapstructure:"db_name"`
-	SSLMode     string `mapstructure:"sslmode"`
-	Timezone    string `mapstructure:"timezone"`
-	MaxIdleConn int    `mapstructure:"max_idle_conn"`
-	MaxOpenConn int    `mapstructure:"max_open_conn"`
+	Host                string `mapstructure:"db_host" validate:"required"`
+	Port                int    `mapstructure:"db_port" validate:"required,min=1,max=65535"`
+	User                string `mapstructure:"db_user" validate:"required"`
+	Password            string `mapstructure:"db_password" secret:"true"`
+	DBName              string `mapstructure:"db_name" validate:"required"`
+	SSLMode             string `mapstructure:"sslmode" validate:"omitempty,oneofci=disable allow prefer require verify-ca verify-full"`
+	Timezone            string `mapstructure:"timezone"`
+	MaxIdleConn         int    `mapstructure:"max_idle_conn" validate:"min=0"`
+	MaxOpenConn         int    `mapstructure:"max_open_conn" validate:"min=0"`
+	QueryTimeout        string `mapstructure:"query_timeout" validate:"omitempty,duration"`         // deadline of one repository call, e.g. "5s"
+	ConnMaxLifetime     string `mapstructure:"conn_max_lifetime" validate:"omitempty,duration"`     // close connections older than this, e.g. "30m"
+	ConnMaxIdleTime     string `mapstructure:"conn_max_idle_time" validate:"omitempty,duration"`    // close connections idle for this long, e.g. "5m"
+	SSLRootCert         string `mapstructure:"sslrootcert"`                                         // CA of the server certificate, for sslmode verify-ca and verify-full
+	SSLCert             string `mapstructure:"sslcert"`                                             // client certificate, when the server asks for one
+	SSLKey              string `mapstructure:"sslkey"`                                              // key of the client certificate
+	ConnectRetries      int    `mapstructure:"connect_retries" validate:"min=0"`                    // retries while Postgres is unreachable at startup
+	ConnectRetryBackoff string `mapstructure:"connect_retry_backoff" validate:"omitempty,duration"` // wait before the first retry, doubled on each one, e.g. "1s"
+	ReplicaDSN          string `mapstructure:"replica_dsn" secret:"true"`                           // optional read replica for read-only listings, key/value DSN or postgres:// url
+	PoolStatsInterval   string `mapstructure:"pool_stats_interval" validate:"omitempty,duration"`   // log the pool stats this often, e.g. "1m", empty disables
 }
 
 type RedisConfig struct {
-	Host     string `mapstructure:"redis_host"`
-	Port     int    `mapstructure:"redis_port"`
-	Password string `mapstructure:"redis_password"`
-	DB       int    `mapstructure:"redis_db"`
+	Host     string `mapstructure:"redis_host" validate:"required"`
+	Port     int    `mapstructure:"redis_port" validate:"required,min=1,max=65535"`
+	Password string `mapstructure:"redis_password" secret:"true"`
+	DB       int    `mapstructure:"redis_db" validate:"min=0"`
 }
 
 type ApplicationConfig struct {
-	Name                       string `mapstructure:"name"`
-	Version                    string `mapstructure:"version"`
-	Env                        string `mapstructure:"env"`
-	Host                       string `mapstructure:"host"`
-	AppPort                    int    `mapstructure:"app_port"`
-	AppUrl                     string `mapstructure:"app_url"`
-	AppPath                    string `mapstructure:"app_path"`
-	RedirectPath               string `mapstructure:"redirect_path"`
-	WsUrl                      string `mapstructure:"ws_url"`
-	Timezone                   string `mapstructure:"timezone"`
-	EnableLog                  bool   `mapstructure:"enable_log"`
-	EnableLogToFile            bool   `mapstructure:"enable_log_to_file"`
-	LogPath                    string `mapstructure:"log_path"`
-	Prefork                    bool   `mapstructure:"prefork"`
-	AllowOrigins               string `mapstructure:"allow_origins"`
-	AllowHeaders               string `mapstructure:"allow_headers"`
-	AllowMethods               string `mapstructure:"allow_methods"`
-	EnableTrustedProxyCheck    bool   `mapstructure:"enable_trusted_proxy_check"`
-	EnableCache                bool   `mapstructure:"enable_cache"`
-	AppKey                     string `mapstructure:"app_key"`
-	JwtSecretKey               string `mapstructure:"jwt_secret_key"`
-	SsoJwtSecret               string `mapstructure:"sso_jwt_secret"`
-	FilePath                   string `mapstructure:"file_path"`
-	DefaultMaxRequestPerMinute int    `mapstructure:"default_max_requests_per_minute"`
+	Name                       string   `mapstructure:"name"`
+	Version                    string   `mapstructure:"version"`
+	Env                        string   `mapstructure:"env"`
+	Host                       string   `mapstructure:"host"`
+	AppPort                    int      `mapstructure:"app_port" validate:"required,min=1,max=65535"`
+	AppUrl                     string   `mapstructure:"app_url"`
+	AppPath                    string   `mapstructure:"app_path"`
+	RedirectPath               string   `mapstructure:"redirect_path"`
+	WsUrl                      string   `mapstructure:"ws_url"`
+	Timezone                   string   `mapstructure:"timezone" validate:"omitempty,timezone"`
+	EnableLog                  bool     `mapstructure:"enable_log"`
+	EnableLogToFile            bool     `mapstructure:"enable_log_to_file"`
+	LogPath                    string   `mapstructure:"log_path"`
+	LogLevel                   string   `mapstructure:"log_level" validate:"omitempty,oneofci=debug info warn error"` // debug, info, warn or error
+	LogFormat                  string   `mapstructure:"log_format" validate:"omitempty,oneofci=json text"`            // json or text, defaults to json in production
+	LogSampleRate              float64  `mapstructure:"log_sample_rate" validate:"min=0,max=1"`                       // fraction (0-1) of successful access logs kept, errors are always logged
+	LogMaxSizeMB               int      `mapstructure:"log_max_size_mb" validate:"min=0"`                             // rotate log_path when it grows past this size
+	LogRotateInterval          string   `mapstructure:"log_rotate_interval" validate:"omitempty,duration"`            // also rotate on a fixed interval, e.g. "24h"
+	LogMaxBackups              int      `mapstructure:"log_max_backups" validate:"min=0"`                             // rotated files kept
+	LogMaxAgeDays              int      `mapstructure:"log_max_age_days" validate:"min=0"`                            // rotated files older than this are deleted
+	LogCompress                bool     `mapstructure:"log_compress"`                                                 // gzip rotated files
+	LogBufferLines             int      `mapstructure:"log_buffer_lines" validate:"min=0"`                            // queued lines before new ones are dropped
+	Prefork                    bool     `mapstructure:"prefork"`
+	AllowOrigins               string   `mapstructure:"allow_origins"`
+	AllowHeaders               string   `mapstructure:"allow_headers"`
+	AllowMethods               string   `mapstructure:"allow_methods"`
+	EnableTrustedProxyCheck    bool     `mapstructure:"enable_trusted_proxy_check"`              // client ip from proxy_header only on requests of the trusted_proxies
+	TrustedProxies             []string `mapstructure:"trusted_proxies" validate:"dive,ip|cidr"` // ips or cidr ranges of the reverse proxies, e.g. ["10.0.0.0/8"]
+	ProxyHeader                string   `mapstructure:"proxy_header"`                            // header the trusted proxies set to the client ip, default X-Forwarded-For
+	EnableCache                bool     `mapstructure:"enable_cache"`
+	AppKey                     string   `mapstructure:"app_key" secret:"true"`
+	JwtSecretKey               string   `mapstructure:"jwt_secret_key" validate:"required" secret:"true"`
+	SsoJwtSecret               string   `mapstructure:"sso_jwt_secret" secret:"true"`
+	FilePath                   string   `mapstructure:"file_path"`
+	DefaultMaxRequestPerMinute int      `mapstructure:"default_max_requests_per_minute" validate:"min=0"`
+	HealthCheckTimeout         string   `mapstructure:"health_check_timeout" validate:"omitempty,duration"`         // per dependency timeout of /health/ready, e.g. "2s"
+	ShutdownDelay              string   `mapstructure:"shutdown_delay" validate:"omitempty,duration"`               // keep serving while readiness reports not ready, e.g. "5s"
+	ShutdownTimeout            string   `mapstructure:"shutdown_timeout" validate:"omitempty,duration"`             // drain in-flight requests, then each shutdown step, e.g. "30s"
+	RequestTimeout             string   `mapstructure:"request_timeout" validate:"omitempty,duration"`              // deadline of the queries and outgoing calls of a request, e.g. "30s"
+	ErrorFormat                string   `mapstructure:"error_format" validate:"omitempty,oneofci=envelope problem"` // "envelope" (default) or "problem" for application/problem+json, clients can also ask for it in Accept
+	ProblemTypeBaseURL         string   `mapstructure:"problem_type_base_url"`                                      // prefix of the problem type uris, e.g. "https://docs.example.com/errors/", default "urn:goride:error:"
 	// DefaultRequestDuration     time.Duration `mapstructure:"default_request_duration"`
 	// ✅ GOOGLE OAUTH - Pastikan mapping ke quoted string
 	GoogleClientID     string `mapstructure:"google_client_id"`
-	GoogleClientSecret string `mapstructure:"google_client_secret"`
+	GoogleClientSecret string `mapstructure:"google_client_secret" secret:"true"`
 	GoogleRedirectURI  string `mapstructure:"google_redirect_uri"`
 
 	// ✅ FRONTEND & CORS
 	FrontendURL  string   `mapstructure:"frontend_url"`
-	CorsOrigins  string   `mapstructure:"cors_origins"`
+	CorsOrigins  string   `mapstructure:"cors_origins" validate:"required,origins"` // comma separated, e.g. "https://app.example.com, https://*.example.com"
 	PublicRoutes []string `mapstructure:"public_routes"`
 
 	// ✅ JWT EXPIRATION (integers in seconds)
-	AppJWTAccessExpiresIn  int `mapstructure:"app_jwt_access_expires_in"`  // in seconds
-	AppJWTRefreshExpiresIn int `mapstructure:"app_jwt_refresh_expires_in"` // in seconds
+	AppJWTAccessExpiresIn  int `mapstructure:"app_jwt_access_expires_in" validate:"min=0"`  // in seconds
+	AppJWTRefreshExpiresIn int `mapstructure:"app_jwt_refresh_expires_in" validate:"min=0"` // in seconds
+}
+
+// OAuthClaimsMapping tells the claims mapper which claim (dotted path for nested
+// values, e.g. "picture.data.url") holds each user attribute.
+type OAuthClaimsMapping struct {
+	Subject       string `mapstructure:"subject"`
+	Email         string `mapstructure:"email"`
+	EmailVerified string `mapstructure:"email_verified"`
+	Name          string `mapstructure:"name"`
+	Picture       string `mapstructure:"picture"`
+}
+
+// OAuthProviderConfig configures one login provider under [oauth_providers.<name>].
+// Well-known providers (google, github, facebook, apple) only need client credentials,
+// any empty field falls back to the built-in defaults of that provider.
+type OAuthProviderConfig struct {
+	Type           string             `mapstructure:"type" validate:"omitempty,oneofci=oidc oauth2"` // "oidc" or "oauth2"
+	ClientID       string             `mapstructure:"client_id" validate:"required"`
+	ClientSecret   string             `mapstructure:"client_secret" secret:"true"`
+	RedirectURI    string             `mapstructure:"redirect_uri"`
+	Scopes         []string           `mapstructure:"scopes"`
+	Issuer         string             `mapstructure:"issuer"` // OIDC discovery base url
+	AuthURL        string             `mapstructure:"auth_url"`
+	TokenURL       string             `mapstructure:"token_url"`
+	UserInfoURL    string             `mapstructure:"userinfo_url"`
+	EmailsURL      string             `mapstructure:"emails_url"`      // oauth2, lists the addresses when userinfo has no verified email (github)
+	VerifiedEmails bool               `mapstructure:"verified_emails"` // the provider only returns confirmed addresses and sends no email_verified claim (facebook)
+	DisablePKCE    bool               `mapstructure:"disable_pkce"`
+	Claims         OAuthClaimsMapping `mapstructure:"claims"`
+}
+
+// JwtConfig selects how access tokens are signed. HS256 keeps using application.jwt_secret_key,
+// RS256 and EdDSA sign with the active private key in KeysDir (see the `jwt-keys` command).
+type JwtConfig struct {
+	Algorithm string   `mapstructure:"algorithm" validate:"omitempty,oneof=HS256 RS256 EdDSA"`
+	KeysDir   string   `mapstructure:"keys_dir"`
+	Issuer    string   `mapstructure:"issuer"`
+	Audience  []string `mapstructure:"audience"`
+}
+
+// MetricsConfig protects /metrics. A scraper is let through when its ip is in AllowedIPs
+// (ips or CIDRs) or when it sends the basic auth credentials, with neither set only localhost can scrape.
+type MetricsConfig struct {
+	AllowedIPs []string `mapstructure:"allowed_ips"`
+	Username   string   `mapstructure:"username"`
+	Password   string   `mapstructure:"password" secret:"true"`
+}
+
+// TracingConfig configures OpenTelemetry tracing. Exporter "otlp" sends spans to Endpoint over
+// Protocol ("http" or "grpc"), the default "none" keeps trace ids in logs and errors without exporting.
+type TracingConfig struct {
+	Exporter    string            `mapstructure:"exporter" validate:"omitempty,oneofci=none otlp"`
+	Protocol    string            `mapstructure:"protocol" validate:"omitempty,oneofci=http grpc"`
+	Endpoint    string            `mapstructure:"endpoint"`
+	Insecure    bool              `mapstructure:"insecure"`
+	Headers     map[string]string `mapstructure:"headers" secret:"true"`
+	ServiceName string            `mapstructure:"service_name"`
+	SampleRatio float64           `mapstructure:"sample_ratio" validate:"min=0,max=1"`
+}
+
+// RateLimitPolicy allows Requests per Period to each client identified by Key, on the requests matching
+// Routes and Methods (empty matches every route or method). Key is "ip", "user" (the user of the JWT,
+// the ip of anonymous requests), "api_key" (only requests authenticated with an API key) or "global"
+// (one bucket shared by every client).
+type RateLimitPolicy struct {
+	Name     string   `mapstructure:"name" validate:"required"`
+	Key      string   `mapstructure:"key" validate:"required,oneof=ip user api_key global"`
+	Requests int      `mapstructure:"requests" validate:"min=1"`
+	Period   string   `mapstructure:"period" validate:"required,duration"` // e.g. "1m"
+	Burst    int      `mapstructure:"burst" validate:"min=0"`              // requests allowed at once, defaults to requests
+	Routes   []string `mapstructure:"routes"`                              // e.g. "/v1/auth/login", "/v1/admin/*", "/v1/rides/:id"
+	Methods  []string `mapstructure:"methods"`                             // e.g. "POST"
+}
+
+// RateLimitConfig declares the rate limit policies under [[rate_limit.policies]], a request must pass every
+// policy matching it. Without the section DefaultRateLimitPolicies apply, an empty list disables them.
+type RateLimitConfig struct {
+	Policies []RateLimitPolicy `mapstructure:"policies" validate:"dive"`
+}
+
+// OutboxConfig tunes the relay publishing the outbox events to the in-process subscribers and to the
+// redis stream Stream. An event failing MaxAttempts times goes to the dead letters, see the `outbox` command.
+type OutboxConfig struct {
+	Disabled        bool   `mapstructure:"disabled"`                                        // leave the events to the relay of another instance
+	PollInterval    string `mapstructure:"poll_interval" validate:"omitempty,duration"`     // look for due events this often, e.g. "1s"
+	BatchSize       int    `mapstructure:"batch_size" validate:"min=0"`                     // events claimed at once
+	MaxAttempts     int    `mapstructure:"max_attempts" validate:"min=0"`                   // deliveries before an event goes to the dead letters
+	RetryBackoff    string `mapstructure:"retry_backoff" validate:"omitempty,duration"`     // wait before the first retry, doubled on each one, e.g. "5s"
+	MaxRetryBackoff string `mapstructure:"max_retry_backoff" validate:"omitempty,duration"` // e.g. "10m"
+	Stream          string `mapstructure:"stream"`                                          // redis stream of the events, defaults to "goride:events"
+	StreamMaxLen    int    `mapstructure:"stream_max_len" validate:"min=0"`                 // trim the stream to about this many entries, 0 keeps every entry
+	Retention       string `mapstructure:"retention" validate:"omitempty,duration"`         // delete the published events after this, e.g. "168h", "0" keeps them
 }
 
 type Config struct {
-	Database    DatabaseConfig    `mapstructure:"database"`
-	Redis       RedisConfig       `mapstructure:"redis"`
-	Application ApplicationConfig `mapstructure:"application"`
+	Database       DatabaseConfig                 `mapstructure:"database"`
+	Redis          RedisConfig                    `mapstructure:"redis"`
+	Application    ApplicationConfig              `mapstructure:"application"`
+	Jwt            JwtConfig                      `mapstructure:"jwt"`
+	Metrics        MetricsConfig                  `mapstructure:"metrics"`
+	Tracing        TracingConfig                  `mapstructure:"tracing"`
+	RateLimit      RateLimitConfig                `mapstructure:"rate_limit"`
+	Outbox         OutboxConfig                   `mapstructure:"outbox"`
+	OAuthProviders map[string]OAuthProviderConfig `mapstructure:"oauth_providers" validate:"dive"`
 	// SsoClientCredentials SsoClientCredentials  `mapstructure:"sso_client_credentials"`
 	// Email                EmailConfig           `mapstructure:"email"`
 	// Instrumentation      InstrumentationConfig `mapstructure:"instrumentation"`
@@ -104,43 +236,142 @@ type Config struct {
 
 var Cfg Config
 
-// LoadConfig loads configuration from file.
-func LoadConfig(configFileName string, path string) {
-	viper.SetConfigName(configFileName)
-	viper.SetConfigType("toml")
-	viper.AddConfigPath(path)
+const (
+	// DefaultConfigFile is read when no --config is given, it may be missing when the environment provides everything.
+	DefaultConfigFile = "env.conf"
+	// EnvPrefix prefixes the environment overrides, database.db_password is read from GORIDE_DATABASE_DB_PASSWORD.
+	EnvPrefix = "GORIDE"
+)
+
+// ConfigSource tells LoadConfig where the configuration comes from. The layers are applied in order,
+// each one overriding the previous: the file, the profile file next to it (env.<profile>.conf),
+// the GORIDE_* environment variables (a .env file of the working directory included) and the overrides.
+type ConfigSource struct {
+	File      string   // default DefaultConfigFile
+	Profile   string   // default application.env, e.g. dev merges env.dev.conf
+	Overrides []string // key=value pairs of the command line, e.g. application.app_port=8080
+}
+
+// LoadConfig reads and validates the configuration. A *ConfigError lists every invalid setting,
+// the decoded configuration is returned with it so it can still be printed.
+func LoadConfig(src ConfigSource) (Config, error) {
+	var cfg Config
+
+	// The .env file is optional and never overrides variables that are already set.
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return cfg, fmt.Errorf("reading .env: %w", err)
+	}
+
+	v := viper.New()
+	v.SetConfigType("toml")
+	v.SetEnvPrefix(EnvPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+	bindEnv(v, reflect.TypeOf(cfg), "")
+
+	file := src.File
+	if file == "" {
+		file = DefaultConfigFile
+	}
+	v.SetConfigFile(file)
+	if err := v.ReadInConfig(); err != nil && (src.File != "" || !errors.Is(err, fs.ErrNotExist)) {
+		return cfg, fmt.Errorf("reading %s: %w", file, err)
+	}
+
+	profile := src.Profile
+	if profile == "" {
+		profile = v.GetString("application.env")
+	}
+	if profile != "" {
+		path := ProfileFile(file, profile)
+		f, err := os.Open(path)
+		switch {
+		case err == nil:
+			err = v.MergeConfig(f)
+			f.Close()
+			if err != nil {
+				return cfg, fmt.Errorf("reading %s: %w", path, err)
+			}
+		case src.Profile != "" || !errors.Is(err, fs.ErrNotExist):
+			// A profile asked for on the command line must exist, one only named by application.env may not.
+			return cfg, fmt.Errorf("reading profile %s: %w", profile, err)
+		}
+		v.Set("application.env", profile)
+	}
 
-	if err := viper.ReadInConfig(); err != nil {
-		log.Fatalf("Error reading config file, %s", err)
+	for _, override := range src.Overrides {
+		key, value, ok := strings.Cut(override, "=")
+		if !ok || !strings.Contains(key, ".") {
+			return cfg, fmt.Errorf("invalid override %q, use section.key=value", override)
+		}
+		v.Set(strings.ToLower(strings.TrimSpace(key)), value)
 	}
 
-	if err := viper.Unmarshal(&Cfg); err != nil {
-		log.Fatalf("Unable to decode into struct, %v", err)
+	if err := v.Unmarshal(&cfg); err != nil {
+		return cfg, fmt.Errorf("decoding the configuration: %w", err)
+	}
+	if !v.IsSet("rate_limit.policies") {
+		cfg.RateLimit.Policies = DefaultRateLimitPolicies(cfg.Application.AppPath)
 	}
+	return cfg, ValidateConfig(cfg)
 }
 
-func LoadEnv() {
-	if err := godotenv.Load(); err != nil {
-		log.Fatalf("Error loading .env file")
+// DefaultRateLimitPolicies protect the anonymous login and registration routes against brute force,
+// per client ip.
+func DefaultRateLimitPolicies(appPath string) []RateLimitPolicy {
+	return []RateLimitPolicy{
+		{
+			Name:     "auth",
+			Key:      "ip",
+			Requests: 10,
+			Period:   "1m",
+			Routes:   []string{appPath + "/auth/login", appPath + "/auth/register"},
+			Methods:  []string{"POST"},
+		},
 	}
+}
 
+// ProfileFile returns the file of a profile next to the base file, env.conf and dev give env.dev.conf.
+func ProfileFile(file, profile string) string {
+	ext := filepath.Ext(file)
+	return strings.TrimSuffix(file, ext) + "." + profile + ext
 }
 
-// GetEnv gets environment variable value by key
-func GetEnv(key string) string {
-	value := os.Getenv(key)
-	if value == "" {
-		log.Printf("Warning: Environment variable %s is not set", key)
+// bindEnv binds every setting of the struct to its GORIDE_* variable. AutomaticEnv alone only overrides
+// keys present in a file, binding lets the environment provide settings the files leave out.
+// Map sections (oauth_providers, tracing.headers) have no fixed keys, only their keys present in a file are overridable,
+// lists of sections (rate_limit.policies) are only read from the files.
+func bindEnv(v *viper.Viper, t reflect.Type, prefix string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := prefix + field.Tag.Get("mapstructure")
+		if field.Type.Kind() == reflect.Struct {
+			bindEnv(v, field.Type, key+".")
+			continue
+		}
+		if field.Type.Kind() == reflect.Slice && field.Type.Elem().Kind() == reflect.Struct {
+			continue
+		}
+		v.BindEnv(key)
 	}
-	return value
 }
 
-// GetEnvOrDefault gets environment variable value by key or returns default value if not set
-func GetEnvOrDefault(key, defaultValue string) string {
-	value := os.Getenv(key)
+// EnvName returns the environment variable overriding a setting, e.g. GORIDE_DATABASE_DB_HOST.
+func EnvName(key string) string {
+	return EnvPrefix + "_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// ConfigDuration parses a duration setting such as "30s", an empty value gives fallback and an
+// invalid or negative one is logged under key and gives fallback as well.
+func ConfigDuration(key, value string, fallback time.Duration) time.Duration {
 	if value == "" {
-		log.Printf("Environment variable %s is not set, using default value: %s", key, defaultValue)
-		return defaultValue
+		return fallback
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		slog.Warn("invalid "+key+", using the default", "value", value, "default", fallback)
+		return fallback
 	}
-	return value
+	return d
 }
@@ -0,0 +1,83 @@
+package pkg
+
+import (
+	"reflect"
+	"sort"
+)
+
+const redacted = "******"
+
+// Redacted returns the settings keyed like the configuration file with the values of the fields
+// tagged secret:"true" masked, so the effective configuration can be printed or served safely.
+func (c Config) Redacted() map[string]any {
+	return structSettings(reflect.ValueOf(c), true)
+}
+
+// ChangedSettings returns the sorted keys, e.g. application.cors_origins, whose value differs in other.
+func (c Config) ChangedSettings(other Config) []string {
+	before := flattenSettings(structSettings(reflect.ValueOf(c), false), "", map[string]any{})
+	after := flattenSettings(structSettings(reflect.ValueOf(other), false), "", map[string]any{})
+
+	var changed []string
+	for key, value := range after {
+		if !reflect.DeepEqual(before[key], value) {
+			changed = append(changed, key)
+		}
+	}
+	for key := range before {
+		if _, ok := after[key]; !ok {
+			changed = append(changed, key)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+func structSettings(v reflect.Value, redact bool) map[string]any {
+	settings := make(map[string]any, v.NumField())
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		settings[field.Tag.Get("mapstructure")] = settingValue(v.Field(i), redact && field.Tag.Get("secret") == "true", redact)
+	}
+	return settings
+}
+
+func settingValue(v reflect.Value, secret, redact bool) any {
+	switch v.Kind() {
+	case reflect.Struct:
+		return structSettings(v, redact)
+	case reflect.Map:
+		values := make(map[string]any, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			values[iter.Key().String()] = settingValue(iter.Value(), secret, redact)
+		}
+		return values
+	case reflect.Slice:
+		// lists of sections such as rate_limit.policies, compared and printed as a whole
+		if v.Type().Elem().Kind() != reflect.Struct {
+			break
+		}
+		values := make([]any, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			values[i] = structSettings(v.Index(i), redact)
+		}
+		return values
+	case reflect.String:
+		if secret && v.String() != "" {
+			return redacted
+		}
+	}
+	return v.Interface()
+}
+
+func flattenSettings(settings map[string]any, prefix string, flat map[string]any) map[string]any {
+	for key, value := range settings {
+		if nested, ok := value.(map[string]any); ok {
+			flattenSettings(nested, prefix+key+".", flat)
+			continue
+		}
+		flat[prefix+key] = value
+	}
+	return flat
+}
@@ -0,0 +1,143 @@
+package pkg
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testConfigFile = `
+[database]
+db_host = "localhost"
+db_port = 5432
+db_user = "goride"
+db_name = "goride"
+
+[redis]
+redis_host = "localhost"
+redis_port = 6379
+
+[application]
+app_port = 8000
+app_path = "/v1"
+jwt_secret_key = "secret"
+cors_origins = "https://app.example.com"
+`
+
+func TestLoadConfigLayers(t *testing.T) {
+	tests := []struct {
+		name      string
+		profile   string // content of env.dev.conf, written when not empty
+		env       string // GORIDE_APPLICATION_APP_PORT, set when not empty
+		overrides []string
+		wantPort  int
+	}{
+		{name: "file", wantPort: 8000},
+		{name: "profile over file", profile: "[application]\napp_port = 8001\n", wantPort: 8001},
+		{name: "environment over profile", profile: "[application]\napp_port = 8001\n", env: "8002", wantPort: 8002},
+		{name: "override over environment", env: "8002", overrides: []string{"application.app_port=8003"}, wantPort: 8003},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "env.conf")
+			writeTestFile(t, file, testConfigFile)
+			src := ConfigSource{File: file, Overrides: tt.overrides}
+			if tt.profile != "" {
+				writeTestFile(t, ProfileFile(file, "dev"), tt.profile)
+				src.Profile = "dev"
+			}
+			if tt.env != "" {
+				t.Setenv(EnvName("application.app_port"), tt.env)
+			}
+
+			cfg, err := LoadConfig(src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Application.AppPort != tt.wantPort {
+				t.Errorf("app_port %d, want %d", cfg.Application.AppPort, tt.wantPort)
+			}
+			if len(cfg.RateLimit.Policies) != 1 || cfg.RateLimit.Policies[0].Routes[0] != "/v1/auth/login" {
+				t.Errorf("rate_limit.policies %+v, want the default policies of /v1", cfg.RateLimit.Policies)
+			}
+		})
+	}
+}
+
+func TestLoadConfigMissingProfile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "env.conf")
+	writeTestFile(t, file, testConfigFile)
+
+	if _, err := LoadConfig(ConfigSource{File: file, Profile: "staging"}); err == nil {
+		t.Error("a missing profile asked for on the command line was accepted")
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(cfg *Config)
+		want   string // a problem of the error, empty when the configuration is valid
+	}{
+		{name: "valid", modify: func(*Config) {}},
+		{name: "missing secret", modify: func(cfg *Config) { cfg.Application.JwtSecretKey = "" }, want: "application.jwt_secret_key is required (GORIDE_APPLICATION_JWT_SECRET_KEY)"},
+		{name: "port out of range", modify: func(cfg *Config) { cfg.Database.Port = 70000 }, want: "database.db_port must be at most 65535"},
+		{name: "invalid origin", modify: func(cfg *Config) { cfg.Application.CorsOrigins = "app.example.com" }, want: "application.cors_origins must be comma separated origins"},
+		{name: "wildcard origin", modify: func(cfg *Config) { cfg.Application.CorsOrigins = "https://*.example.com, http://localhost:3000" }},
+		{name: "invalid duration", modify: func(cfg *Config) { cfg.Application.RequestTimeout = "30" }, want: "application.request_timeout must be a duration"},
+		{name: "log level case insensitive", modify: func(cfg *Config) { cfg.Application.LogLevel = "DEBUG" }},
+		{name: "invalid trusted proxy", modify: func(cfg *Config) { cfg.Application.TrustedProxies = []string{"10.0.0.0/8", "proxy"} }, want: "must be an ip or a cidr range"},
+		{name: "asymmetric jwt without keys", modify: func(cfg *Config) { cfg.Jwt.Algorithm = "RS256" }, want: "jwt.keys_dir is required when jwt.algorithm is RS256"},
+		{
+			name: "duplicate policy names",
+			modify: func(cfg *Config) {
+				policy := RateLimitPolicy{Name: "auth", Key: "ip", Requests: 10, Period: "1m"}
+				cfg.RateLimit.Policies = []RateLimitPolicy{policy, policy}
+			},
+			want: `rate_limit.policies[1].name "auth" is used by another policy`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validTestConfig()
+			tt.modify(&cfg)
+
+			err := ValidateConfig(cfg)
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("ValidateConfig() = %v, want nil", err)
+				}
+				return
+			}
+
+			var configErr *ConfigError
+			if !errors.As(err, &configErr) {
+				t.Fatalf("ValidateConfig() = %v, want a *ConfigError", err)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ValidateConfig() = %v, want a problem with %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func validTestConfig() Config {
+	var cfg Config
+	cfg.Database = DatabaseConfig{Host: "localhost", Port: 5432, User: "goride", DBName: "goride"}
+	cfg.Redis = RedisConfig{Host: "localhost", Port: 6379}
+	cfg.Application.AppPort = 8000
+	cfg.Application.JwtSecretKey = "secret"
+	cfg.Application.CorsOrigins = "https://app.example.com"
+	return cfg
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,120 @@
+package pkg
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// ConfigError lists every invalid setting, so one run reports all of them instead of the first.
+type ConfigError struct {
+	Problems []string
+}
+
+func (e *ConfigError) Error() string {
+	return "invalid configuration:\n  " + strings.Join(e.Problems, "\n  ")
+}
+
+// ValidateConfig checks the validate tags of the configuration and the rules spanning several settings.
+func ValidateConfig(cfg Config) error {
+	validate := validator.New()
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		return field.Tag.Get("mapstructure")
+	})
+	validate.RegisterValidation("duration", func(fl validator.FieldLevel) bool {
+		d, err := time.ParseDuration(fl.Field().String())
+		return err == nil && d >= 0
+	})
+	validate.RegisterValidation("oneofci", func(fl validator.FieldLevel) bool {
+		value := fl.Field().String()
+		for _, allowed := range strings.Fields(fl.Param()) {
+			if strings.EqualFold(value, allowed) {
+				return true
+			}
+		}
+		return false
+	})
+
+	validate.RegisterValidation("origins", func(fl validator.FieldLevel) bool {
+		for _, origin := range strings.Split(fl.Field().String(), ",") {
+			if !validOrigin(strings.Replace(strings.TrimSpace(origin), "://*.", "://", 1)) {
+				return false
+			}
+		}
+		return true
+	})
+
+	var problems []string
+	if err := validate.Struct(cfg); err != nil {
+		validationErrors, ok := err.(validator.ValidationErrors)
+		if !ok {
+			return err
+		}
+		for _, e := range validationErrors {
+			problems = append(problems, configProblem(strings.TrimPrefix(e.Namespace(), "Config."), e))
+		}
+	}
+
+	if alg := cfg.Jwt.Algorithm; alg != "" && alg != "HS256" && cfg.Jwt.KeysDir == "" {
+		problems = append(problems, fmt.Sprintf("jwt.keys_dir is required when jwt.algorithm is %s (%s)", alg, EnvName("jwt.keys_dir")))
+	}
+
+	names := map[string]bool{}
+	for i, policy := range cfg.RateLimit.Policies {
+		if names[policy.Name] {
+			problems = append(problems, fmt.Sprintf("rate_limit.policies[%d].name %q is used by another policy", i, policy.Name))
+		}
+		names[policy.Name] = true
+		if d, err := time.ParseDuration(policy.Period); err == nil && d == 0 {
+			problems = append(problems, fmt.Sprintf("rate_limit.policies[%d].period must be longer than 0", i))
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ConfigError{Problems: problems}
+	}
+	return nil
+}
+
+func configProblem(key string, e validator.FieldError) string {
+	var msg string
+	switch e.Tag() {
+	case "required":
+		msg = "is required"
+	case "min":
+		msg = "must be at least " + e.Param()
+	case "max":
+		msg = "must be at most " + e.Param()
+	case "oneof", "oneofci":
+		msg = fmt.Sprintf("must be one of %s, got %q", strings.ReplaceAll(e.Param(), " ", ", "), e.Value())
+	case "duration":
+		msg = fmt.Sprintf("must be a duration such as 30s or 5m, got %q", e.Value())
+	case "origins":
+		msg = fmt.Sprintf("must be comma separated origins such as https://app.example.com, got %q", e.Value())
+	case "ip|cidr":
+		msg = fmt.Sprintf("must be an ip or a cidr range such as 10.0.0.0/8, got %q", e.Value())
+	case "timezone":
+		msg = fmt.Sprintf("must be a time zone such as Asia/Jakarta, got %q", e.Value())
+	default:
+		msg = fmt.Sprintf("is invalid (%s)", e.Tag())
+	}
+
+	// Settings of map sections such as oauth_providers[google] have no fixed variable.
+	if strings.Contains(key, "[") {
+		return key + " " + msg
+	}
+	return fmt.Sprintf("%s %s (%s)", key, msg, EnvName(key))
+}
+
+// validOrigin applies the rules of the fiber cors middleware, which panics on an invalid origin.
+func validOrigin(origin string) bool {
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" || strings.Contains(u.Host, "*") {
+		return false
+	}
+	return (u.Path == "" || u.Path == "/") && u.RawQuery == "" && u.Fragment == ""
+}
@@ -0,0 +1,142 @@
+package pkg
+
+import (
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// editors save with several events (truncate, write, rename), reload once they settled
+const configReloadDebounce = 500 * time.Millisecond
+
+type configWatcher struct {
+	src      ConfigSource
+	onChange func(before, after RuntimeSettings)
+
+	mu      sync.Mutex
+	current Config
+	timer   *time.Timer
+}
+
+// WatchConfig reloads the configuration when the config file or the profile file of current changes.
+// A reload is loaded and validated like at startup, then only its runtime settings are swapped in and
+// onChange gets them before and after. Changes of other settings are logged as needing a restart,
+// an invalid configuration is logged and the settings in effect are kept.
+func WatchConfig(src ConfigSource, current Config, onChange func(before, after RuntimeSettings)) (stop func(), err error) {
+	file := src.File
+	if file == "" {
+		file = DefaultConfigFile
+	}
+	files := []string{file}
+	if current.Application.Env != "" {
+		files = append(files, ProfileFile(file, current.Application.Env))
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	// watch the directories, editors replace the file instead of writing it
+	watched := make(map[string]bool, len(files))
+	for _, f := range files {
+		path, err := filepath.Abs(f)
+		if err != nil {
+			watcher.Close()
+			return nil, err
+		}
+		if err := watcher.Add(filepath.Dir(path)); err != nil {
+			watcher.Close()
+			return nil, err
+		}
+		watched[path] = true
+	}
+
+	w := &configWatcher{src: src, onChange: onChange, current: current}
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if watched[filepath.Clean(event.Name)] && !event.Has(fsnotify.Chmod) {
+					w.schedule()
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				slog.Warn("config watcher error", "error", err)
+			}
+		}
+	}()
+
+	slog.Info("watching the configuration for runtime settings", "files", files)
+	return func() {
+		close(done)
+		watcher.Close()
+		w.mu.Lock()
+		if w.timer != nil {
+			w.timer.Stop()
+		}
+		w.mu.Unlock()
+	}, nil
+}
+
+func (w *configWatcher) schedule() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	w.timer = time.AfterFunc(configReloadDebounce, w.reload)
+}
+
+func (w *configWatcher) reload() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	cfg, err := LoadConfig(w.src)
+	if err != nil {
+		slog.Error("configuration reload rejected, keeping the settings in effect", "error", err)
+		return
+	}
+
+	changed := w.current.ChangedSettings(cfg)
+	if len(changed) == 0 {
+		return
+	}
+	w.current = cfg
+
+	var applied, restart []string
+	for _, key := range changed {
+		if slices.Contains(RuntimeSettingKeys, key) {
+			applied = append(applied, key)
+		} else {
+			restart = append(restart, key)
+		}
+	}
+	if len(restart) > 0 {
+		slog.Warn("configuration changes need a restart to take effect", "settings", restart)
+	}
+	if len(applied) == 0 {
+		return
+	}
+
+	before := *Runtime()
+	after := cfg.RuntimeSettings()
+	SetRuntime(after)
+	slog.Info("runtime settings reloaded", "settings", applied)
+
+	if w.onChange != nil {
+		w.onChange(before, after)
+	}
+}
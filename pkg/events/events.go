@@ -0,0 +1,111 @@
+// Package events defines the domain events and delivers them to the in-process subscribers and to a redis
+// stream. The events are written to the outbox in the transaction of their change and published by the
+// outbox relay once committed, at least once: a subscriber may see an event again and must skip the ids
+// it already handled.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Types of the domain events, named <aggregate>.<past tense verb>.
+const (
+	UserRegistered  = "user.registered"
+	RideCompleted   = "ride.completed"
+	PaymentCaptured = "payment.captured"
+)
+
+// AllEvents subscribes to every event type.
+const AllEvents = "*"
+
+// Event is a domain event as delivered to the subscribers.
+type Event struct {
+	ID            string          `json:"id"` // stays the same when the event is delivered again
+	Type          string          `json:"type"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   string          `json:"aggregate_id"`
+	Payload       json.RawMessage `json:"payload"`
+	RequestID     string          `json:"request_id,omitempty"`
+	OccurredAt    time.Time       `json:"occurred_at"`
+	Attempt       int             `json:"attempt"` // 1 on the first delivery
+}
+
+// Decode unmarshals the payload into v.
+func (e Event) Decode(v any) error {
+	if err := json.Unmarshal(e.Payload, v); err != nil {
+		return fmt.Errorf("decoding the payload of %s %s: %w", e.Type, e.ID, err)
+	}
+	return nil
+}
+
+// UserRegisteredPayload is the payload of UserRegistered.
+type UserRegisteredPayload struct {
+	UserID   uint   `json:"user_id"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Language string `json:"language"` // negotiated from the registration request, for the notifications
+}
+
+// Publisher delivers an event, an error makes the relay deliver it again later.
+type Publisher interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+// Handler handles an event delivered by the Bus.
+type Handler func(ctx context.Context, event Event) error
+
+type subscriber struct {
+	name    string
+	handler Handler
+}
+
+// Bus delivers the events to the handlers subscribed in process.
+type Bus struct {
+	mu          sync.RWMutex
+	subscribers map[string][]subscriber
+}
+
+func NewBus() *Bus {
+	return &Bus{subscribers: map[string][]subscriber{}}
+}
+
+// Subscribe calls handler for the events of eventType, AllEvents for every type. name identifies the
+// subscriber in the logs and the delivery errors.
+func (b *Bus) Subscribe(eventType, name string, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers[eventType] = append(b.subscribers[eventType], subscriber{name: name, handler: handler})
+}
+
+// Publish calls every subscriber of the event, also when one of them fails, and returns their errors.
+// The event is delivered again to all of them when one failed.
+func (b *Bus) Publish(ctx context.Context, event Event) error {
+	b.mu.RLock()
+	subscribers := append(append([]subscriber{}, b.subscribers[event.Type]...), b.subscribers[AllEvents]...)
+	b.mu.RUnlock()
+
+	var errs []error
+	for _, s := range subscribers {
+		if err := s.call(ctx, event); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.name, err))
+		}
+	}
+	return stderrors.Join(errs...)
+}
+
+// call turns a panic of the handler into an error, the relay keeps running and retries the event.
+func (s subscriber) call(ctx context.Context, event Event) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Error("event subscriber panicked", "subscriber", s.name, "event_type", event.Type, "event_id", event.ID, "panic", r)
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return s.handler(ctx, event)
+}
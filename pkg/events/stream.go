@@ -0,0 +1,39 @@
+package events
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Stream publishes the events to a redis stream for the consumers outside of this process, e.g. with
+// XREADGROUP. Every entry has the fields of Event, the payload as json.
+type Stream struct {
+	rdb    *redis.Client
+	name   string
+	maxLen int64
+}
+
+// NewStream publishes to the stream name, trimmed to about maxLen entries, 0 keeps every entry.
+func NewStream(rdb *redis.Client, name string, maxLen int64) *Stream {
+	return &Stream{rdb: rdb, name: name, maxLen: maxLen}
+}
+
+func (s *Stream) Publish(ctx context.Context, event Event) error {
+	return s.rdb.XAdd(ctx, &redis.XAddArgs{
+		Stream: s.name,
+		MaxLen: s.maxLen,
+		Approx: s.maxLen > 0,
+		Values: map[string]any{
+			"id":             event.ID,
+			"type":           event.Type,
+			"aggregate_type": event.AggregateType,
+			"aggregate_id":   event.AggregateID,
+			"payload":        string(event.Payload),
+			"request_id":     event.RequestID,
+			"occurred_at":    event.OccurredAt.UTC().Format(time.RFC3339Nano),
+			"attempt":        event.Attempt,
+		},
+	}).Err()
+}
@@ -0,0 +1,30 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const featureFlagsKey = "feature_flags:all"
+
+// CachedFeatureFlags returns the flag list stored by CacheFeatureFlags, nil when it is not cached.
+func CachedFeatureFlags(ctx context.Context) ([]byte, error) {
+	data, err := GetRedisClient().Get(ctx, featureFlagsKey).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	return data, err
+}
+
+// CacheFeatureFlags stores the encoded flag list, the ttl bounds how long a missed invalidation lasts.
+func CacheFeatureFlags(ctx context.Context, data []byte, ttl time.Duration) error {
+	return GetRedisClient().Set(ctx, featureFlagsKey, data, ttl).Err()
+}
+
+// InvalidateFeatureFlags drops the cached list, call it once a flag change is committed.
+func InvalidateFeatureFlags(ctx context.Context) error {
+	return GetRedisClient().Del(ctx, featureFlagsKey).Err()
+}
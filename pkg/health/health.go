@@ -0,0 +1,106 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StatusReady        = "ready"
+	StatusNotReady     = "not_ready"
+	StatusShuttingDown = "shutting_down"
+)
+
+// Check probes one dependency, it must return once ctx is done.
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// CheckResult is the outcome of one check in a Report.
+type CheckResult struct {
+	OK        bool    `json:"ok"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Report is the body of the readiness endpoint.
+type Report struct {
+	OK         bool                   `json:"ok"`
+	Status     string                 `json:"status" example:"ready"`
+	Checks     map[string]CheckResult `json:"checks"`
+	DurationMs float64                `json:"duration_ms"`
+}
+
+var shuttingDown atomic.Bool
+
+// SetShuttingDown flips readiness to "not ready" for good, so load balancers stop sending
+// new requests while the in-flight ones drain.
+func SetShuttingDown() {
+	shuttingDown.Store(true)
+}
+
+// ShuttingDown reports whether SetShuttingDown was called.
+func ShuttingDown() bool {
+	return shuttingDown.Load()
+}
+
+// Run executes the checks concurrently, each bounded by timeout, plus one check per registered worker.
+func Run(ctx context.Context, timeout time.Duration, checks ...Check) Report {
+	start := time.Now()
+	checks = append(checks, workerChecks()...)
+
+	results := make(map[string]CheckResult, len(checks))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range checks {
+		wg.Add(1)
+		go func(check Check) {
+			defer wg.Done()
+			result := runCheck(ctx, timeout, check)
+
+			mu.Lock()
+			results[check.Name] = result
+			mu.Unlock()
+		}(check)
+	}
+	wg.Wait()
+
+	report := Report{OK: true, Status: StatusReady, Checks: results}
+	for _, result := range results {
+		if !result.OK {
+			report.OK = false
+			report.Status = StatusNotReady
+		}
+	}
+	if ShuttingDown() {
+		report.OK = false
+		report.Status = StatusShuttingDown
+	}
+
+	report.DurationMs = milliseconds(time.Since(start))
+	return report
+}
+
+func runCheck(ctx context.Context, timeout time.Duration, check Check) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check.Run(ctx)
+	if err == nil && ctx.Err() != nil {
+		err = ctx.Err()
+	}
+
+	result := CheckResult{OK: err == nil, LatencyMs: milliseconds(time.Since(start))}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,80 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type worker struct {
+	staleAfter time.Duration
+	lastBeat   atomic.Int64
+}
+
+var (
+	workersMu sync.RWMutex
+	workers   = map[string]*worker{}
+)
+
+// RegisterWorker adds a background worker to readiness, it turns unhealthy when Beat(name)
+// was not called for staleAfter.
+func RegisterWorker(name string, staleAfter time.Duration) {
+	w := &worker{staleAfter: staleAfter}
+	w.lastBeat.Store(time.Now().UnixNano())
+
+	workersMu.Lock()
+	workers[name] = w
+	workersMu.Unlock()
+}
+
+// UnregisterWorker removes a worker that stopped on purpose.
+func UnregisterWorker(name string) {
+	workersMu.Lock()
+	delete(workers, name)
+	workersMu.Unlock()
+}
+
+// Beat records that the worker is still making progress.
+func Beat(name string) {
+	workersMu.RLock()
+	w := workers[name]
+	workersMu.RUnlock()
+
+	if w != nil {
+		w.lastBeat.Store(time.Now().UnixNano())
+	}
+}
+
+func workerChecks() []Check {
+	workersMu.RLock()
+	defer workersMu.RUnlock()
+
+	checks := make([]Check, 0, len(workers))
+	for _, name := range sortedNames(workers) {
+		w := workers[name]
+		checks = append(checks, Check{
+			Name: "worker." + name,
+			Run: func(context.Context) error {
+				since := time.Since(time.Unix(0, w.lastBeat.Load()))
+				if since > w.staleAfter {
+					return fmt.Errorf("no heartbeat for %s", since.Round(time.Millisecond))
+				}
+				return nil
+			},
+		})
+	}
+	return checks
+}
+
+// sortedNames is used to keep the worker checks in a stable order.
+func sortedNames(m map[string]*worker) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
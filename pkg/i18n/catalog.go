@@ -0,0 +1,87 @@
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+// The catalogs are the locales/<language>.json files, flat objects of message templates keyed like
+// "validation.required" or "notification.user_registered.subject". A language is supported by adding its file,
+// the messages it lacks fall back to DefaultLanguage.
+//
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+var catalogs = loadCatalogs()
+
+func loadCatalogs() map[string]map[string]string {
+	files, err := localeFiles.ReadDir("locales")
+	if err != nil {
+		panic(fmt.Sprintf("reading the message catalogs: %v", err))
+	}
+
+	loaded := map[string]map[string]string{}
+	for _, file := range files {
+		data, err := localeFiles.ReadFile("locales/" + file.Name())
+		if err != nil {
+			panic(fmt.Sprintf("reading the message catalog %s: %v", file.Name(), err))
+		}
+
+		var messages map[string]string
+		if err := json.Unmarshal(data, &messages); err != nil {
+			panic(fmt.Sprintf("decoding the message catalog %s: %v", file.Name(), err))
+		}
+		lang := strings.TrimSuffix(file.Name(), path.Ext(file.Name()))
+		loaded[lang] = messages
+		if !slices.Contains(Supported, lang) {
+			Supported = append(Supported, lang)
+		}
+	}
+	return loaded
+}
+
+// Register adds the messages of lang under prefix, e.g. the English error messages under "error.".
+// Call it from an init function, the catalogs are not guarded for concurrent writes.
+func Register(lang, prefix string, messages map[string]string) {
+	catalog, ok := catalogs[lang]
+	if !ok {
+		catalog = map[string]string{}
+		catalogs[lang] = catalog
+		Supported = append(Supported, lang)
+	}
+	for key, message := range messages {
+		catalog[prefix+key] = message
+	}
+}
+
+// Lookup returns the template of key in lang, or in DefaultLanguage when lang lacks it.
+func Lookup(lang, key string) (string, bool) {
+	if message, ok := catalogs[lang][key]; ok {
+		return message, true
+	}
+	message, ok := catalogs[DefaultLanguage][key]
+	return message, ok
+}
+
+// T returns the message of key in lang with the {name} placeholders replaced by params,
+// the key itself when no catalog has it.
+func T(lang, key string, params map[string]string) string {
+	message, ok := Lookup(lang, key)
+	if !ok {
+		return key
+	}
+	for name, value := range params {
+		message = strings.ReplaceAll(message, "{"+name+"}", value)
+	}
+	return message
+}
+
+// Notification returns the subject and body of the "notification.<name>" templates in lang,
+// e.g. Notification("id", "user_registered", map[string]string{"name": "Budi"}).
+func Notification(lang, name string, params map[string]string) (subject, body string) {
+	return T(lang, "notification."+name+".subject", params), T(lang, "notification."+name+".body", params)
+}
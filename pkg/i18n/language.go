@@ -0,0 +1,43 @@
+package i18n
+
+import (
+	"strconv"
+	"strings"
+)
+
+const (
+	English    = "en"
+	Indonesian = "id"
+)
+
+// DefaultLanguage is used when the client accepts none of the Supported languages.
+const DefaultLanguage = English
+
+// Supported are the languages the messages are translated to, languages of the other catalog files
+// are appended.
+var Supported = []string{English, Indonesian}
+
+// Negotiate picks the supported language the client prefers from an Accept-Language header,
+// e.g. "id-ID,id;q=0.9,en;q=0.8" gives "id". On a tie the language listed first in the header wins.
+func Negotiate(acceptLanguage string) string {
+	best, bestQ := DefaultLanguage, 0.0
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+
+		base, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		for _, lang := range Supported {
+			if base == lang && q > bestQ {
+				best, bestQ = lang, q
+			}
+		}
+	}
+	return best
+}
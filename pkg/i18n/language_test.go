@@ -0,0 +1,80 @@
+package i18n
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		want           string
+	}{
+		{name: "empty", acceptLanguage: "", want: English},
+		{name: "wildcard", acceptLanguage: "*", want: English},
+		{name: "unsupported", acceptLanguage: "fr-FR,fr;q=0.9", want: English},
+		{name: "region", acceptLanguage: "id-ID", want: Indonesian},
+		{name: "case insensitive", acceptLanguage: "ID", want: Indonesian},
+		{name: "preferred by quality", acceptLanguage: "en;q=0.8, id;q=0.9", want: Indonesian},
+		{name: "browser header", acceptLanguage: "id-ID,id;q=0.9,en-US;q=0.8,en;q=0.7", want: Indonesian},
+		{name: "unsupported first", acceptLanguage: "fr, id;q=0.5", want: Indonesian},
+		{name: "tie keeps the order of the header", acceptLanguage: "id;q=0.5, en;q=0.5", want: Indonesian},
+		{name: "not acceptable", acceptLanguage: "id;q=0", want: English},
+		{name: "invalid quality skipped", acceptLanguage: "id;q=high, en;q=0.1", want: English},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Negotiate(tt.acceptLanguage); got != tt.want {
+				t.Errorf("Negotiate(%q) = %q, want %q", tt.acceptLanguage, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestT(t *testing.T) {
+	Register(English, "test.", map[string]string{"only_english": "only in {lang}"})
+
+	tests := []struct {
+		name   string
+		lang   string
+		key    string
+		params map[string]string
+		want   string
+	}{
+		{name: "english", lang: English, key: "validation.required", want: "is required"},
+		{name: "translated", lang: Indonesian, key: "validation.required", want: "wajib diisi"},
+		{name: "params", lang: Indonesian, key: "validation.min", params: map[string]string{"param": "8"}, want: "minimal 8"},
+		{name: "falls back to english", lang: Indonesian, key: "test.only_english", params: map[string]string{"lang": "English"}, want: "only in English"},
+		{name: "unsupported language", lang: "fr", key: "validation.required", want: "is required"},
+		{name: "unknown key", lang: Indonesian, key: "validation.unknown", want: "validation.unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := T(tt.lang, tt.key, tt.params); got != tt.want {
+				t.Errorf("T(%q, %q) = %q, want %q", tt.lang, tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCatalogsTranslateEveryEnglishMessage(t *testing.T) {
+	data, err := localeFiles.ReadFile("locales/" + English + ".json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var english map[string]string
+	if err := json.Unmarshal(data, &english); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, lang := range Supported {
+		for key := range english {
+			if _, ok := catalogs[lang][key]; !ok {
+				t.Errorf("%s catalog lacks %s", lang, key)
+			}
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const idempotencyPrefix = "idempotency:"
+
+// IdempotentResponse is the record of an Idempotency-Key. Status stays 0 while the first request
+// carrying the key is in flight, then holds the response to replay.
+type IdempotentResponse struct {
+	Fingerprint string            `json:"fingerprint"`
+	Status      int               `json:"status"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Body        []byte            `json:"body,omitempty"`
+}
+
+// ReserveIdempotencyKey claims key for the request with fingerprint until lockTTL elapses. It returns true
+// when the key was free, otherwise the record already stored, nil when it expired in the meantime.
+func ReserveIdempotencyKey(ctx context.Context, key, fingerprint string, lockTTL time.Duration) (*IdempotentResponse, bool, error) {
+	data, err := json.Marshal(IdempotentResponse{Fingerprint: fingerprint})
+	if err != nil {
+		return nil, false, err
+	}
+
+	reserved, err := GetRedisClient().SetNX(ctx, idempotencyPrefix+key, data, lockTTL).Result()
+	if err != nil || reserved {
+		return nil, reserved, err
+	}
+
+	data, err = GetRedisClient().Get(ctx, idempotencyPrefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	var record IdempotentResponse
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, false, err
+	}
+	return &record, false, nil
+}
+
+// StoreIdempotentResponse replaces the reservation of key with the response, replayed for ttl.
+func StoreIdempotentResponse(ctx context.Context, key string, response IdempotentResponse, ttl time.Duration) error {
+	data, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+	return GetRedisClient().Set(ctx, idempotencyPrefix+key, data, ttl).Err()
+}
+
+// ReleaseIdempotencyKey drops the reservation of key, so the client can retry a request that failed.
+func ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	return GetRedisClient().Del(ctx, idempotencyPrefix+key).Err()
+}
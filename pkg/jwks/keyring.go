@@ -0,0 +1,170 @@
+package jwks
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/DiansSopandi/goride_be/pkg"
+)
+
+const (
+	// reloadInterval bounds how long a rotation done with the `jwt-keys rotate` command takes to be picked up.
+	reloadInterval = time.Minute
+	// minReloadInterval stops tokens with random kids from hitting the disk on every request.
+	minReloadInterval = 5 * time.Second
+)
+
+type keyring struct {
+	mu       sync.RWMutex
+	keys     map[string]*Key
+	active   string
+	loadedAt time.Time
+}
+
+var ring = &keyring{}
+
+// JWK is the public part of a key as published on /.well-known/jwks.json.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// Algorithm returns the configured signing algorithm, HS256 when none is set.
+func Algorithm() string {
+	if pkg.Cfg.Jwt.Algorithm == "" {
+		return AlgHS256
+	}
+	return pkg.Cfg.Jwt.Algorithm
+}
+
+// UsesHMAC reports whether tokens are still signed with the shared application.jwt_secret_key.
+func UsesHMAC() bool {
+	return Algorithm() == AlgHS256
+}
+
+func (r *keyring) load(force bool) error {
+	r.mu.RLock()
+	age := time.Since(r.loadedAt)
+	fresh := r.keys != nil && (age < minReloadInterval || (!force && age < reloadInterval))
+	r.mu.RUnlock()
+	if fresh {
+		return nil
+	}
+
+	dir := pkg.Cfg.Jwt.KeysDir
+	if dir == "" {
+		return fmt.Errorf("jwt.keys_dir is required for %s", Algorithm())
+	}
+
+	keys, active, err := ListKeys(dir)
+	if err != nil {
+		return fmt.Errorf("failed to load jwt keys: %w", err)
+	}
+
+	byID := make(map[string]*Key, len(keys))
+	for _, key := range keys {
+		byID[key.ID] = key
+	}
+
+	r.mu.Lock()
+	r.keys, r.active, r.loadedAt = byID, active, time.Now()
+	r.mu.Unlock()
+	return nil
+}
+
+// SigningKey returns the active private key.
+func SigningKey() (*Key, error) {
+	if err := ring.load(false); err != nil {
+		return nil, err
+	}
+
+	ring.mu.RLock()
+	defer ring.mu.RUnlock()
+
+	key, ok := ring.keys[ring.active]
+	if !ok {
+		return nil, fmt.Errorf("no active jwt key in %s, run the `jwt-keys rotate` command", pkg.Cfg.Jwt.KeysDir)
+	}
+	if key.Algorithm != Algorithm() {
+		return nil, fmt.Errorf("active jwt key %s is %s but jwt.algorithm is %s", key.ID, key.Algorithm, Algorithm())
+	}
+	return key, nil
+}
+
+// VerificationKey returns the key matching the kid header, every key still on disk is accepted
+// so tokens signed before a rotation stay valid until they expire.
+func VerificationKey(kid string) (*Key, error) {
+	if err := ring.load(false); err != nil {
+		return nil, err
+	}
+
+	ring.mu.RLock()
+	key, ok := ring.keys[kid]
+	ring.mu.RUnlock()
+	if ok {
+		return key, nil
+	}
+
+	// the key may have been rotated in by another instance
+	if err := ring.load(true); err != nil {
+		return nil, err
+	}
+
+	ring.mu.RLock()
+	defer ring.mu.RUnlock()
+	if key, ok = ring.keys[kid]; !ok {
+		return nil, fmt.Errorf("unknown kid %q", kid)
+	}
+	return key, nil
+}
+
+// PublicJWKS returns every verification key, HS256 secrets are never published.
+func PublicJWKS() (JWKSet, error) {
+	set := JWKSet{Keys: []JWK{}}
+	if UsesHMAC() {
+		return set, nil
+	}
+
+	if err := ring.load(false); err != nil {
+		return set, err
+	}
+
+	ring.mu.RLock()
+	defer ring.mu.RUnlock()
+
+	for _, key := range ring.keys {
+		jwk := JWK{Kid: key.ID, Use: "sig", Alg: key.Algorithm}
+
+		switch pub := key.Public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		default:
+			continue
+		}
+
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	return set, nil
+}
@@ -0,0 +1,190 @@
+package jwks
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+
+	activeFile = "active"
+	keyExt     = ".pem"
+	rsaBits    = 2048
+)
+
+// Key is one signing key of the key ring, the file name (without .pem) is the kid.
+type Key struct {
+	ID        string
+	Algorithm string
+	Private   crypto.Signer
+	Public    crypto.PublicKey
+	CreatedAt time.Time
+}
+
+// GenerateKey creates a new private key in dir. It does not activate it, see SetActive.
+func GenerateKey(dir, alg string) (*Key, error) {
+	var (
+		signer crypto.Signer
+		err    error
+	)
+
+	switch alg {
+	case AlgRS256:
+		signer, err = rsa.GenerateKey(rand.Reader, rsaBits)
+	case AlgEdDSA:
+		_, signer, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("unsupported key algorithm %q, use %s or %s", alg, AlgRS256, AlgEdDSA)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(signer)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	kid := now.Format("20060102T150405") + "-" + hex.EncodeToString(suffix)
+	block := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(filepath.Join(dir, kid+keyExt), block, 0o600); err != nil {
+		return nil, err
+	}
+
+	return &Key{ID: kid, Algorithm: alg, Private: signer, Public: signer.Public(), CreatedAt: now}, nil
+}
+
+// SetActive marks kid as the key used to sign new tokens.
+func SetActive(dir, kid string) error {
+	if _, err := os.Stat(filepath.Join(dir, kid+keyExt)); err != nil {
+		return fmt.Errorf("key %s not found: %w", kid, err)
+	}
+	return os.WriteFile(filepath.Join(dir, activeFile), []byte(kid+"\n"), 0o600)
+}
+
+// ListKeys reads every key in dir, newest first, together with the active kid. Files that are not a
+// valid key are logged and skipped.
+func ListKeys(dir string) ([]*Key, string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var keys []*Key
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), keyExt) {
+			continue
+		}
+
+		// one unreadable file must not take down the other keys, tokens signed with them stay valid
+		key, err := readKey(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			slog.Error("skipping unreadable jwt key", "file", entry.Name(), "error", err)
+			continue
+		}
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.After(keys[j].CreatedAt)
+	})
+
+	active := ""
+	if b, err := os.ReadFile(filepath.Join(dir, activeFile)); err == nil {
+		active = strings.TrimSpace(string(b))
+	} else if len(keys) > 0 {
+		active = keys[0].ID
+	}
+
+	return keys, active, nil
+}
+
+// PruneKeys deletes the oldest inactive keys, keeping `keep` keys in total (the active one always stays).
+// Only prune once every token signed by the removed keys has expired.
+func PruneKeys(dir string, keep int) ([]string, error) {
+	keys, active, err := ListKeys(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []string
+	kept := 0
+	for _, key := range keys {
+		if key.ID == active || kept < keep-1 {
+			if key.ID != active {
+				kept++
+			}
+			continue
+		}
+
+		if err := os.Remove(filepath.Join(dir, key.ID+keyExt)); err != nil {
+			return removed, err
+		}
+		removed = append(removed, key.ID)
+	}
+
+	return removed, nil
+}
+
+func readKey(path string) (*Key, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data", path)
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	kid := strings.TrimSuffix(filepath.Base(path), keyExt)
+	key := &Key{ID: kid}
+
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.Algorithm, key.Private = AlgRS256, k
+	case ed25519.PrivateKey:
+		key.Algorithm, key.Private = AlgEdDSA, k
+	default:
+		return nil, fmt.Errorf("%s: unsupported key type %T", path, parsed)
+	}
+	key.Public = key.Private.Public()
+
+	if created, err := time.Parse("20060102T150405", strings.SplitN(kid, "-", 2)[0]); err == nil {
+		key.CreatedAt = created
+	} else if info, err := os.Stat(path); err == nil {
+		key.CreatedAt = info.ModTime()
+	}
+
+	return key, nil
+}
@@ -3,13 +3,8 @@ package pkg
 import (
 	"encoding/json"
 	"fmt"
-	"log"
-	"os"
-	"strconv"
-	"strings"
-	"time"
+	"log/slog"
 
-	"github.com/fatih/color"
 	"github.com/gofiber/fiber/v2"
 )
 
@@ -45,6 +40,14 @@ func sanitizeSensitiveData(bytes []byte) string {
 		dataParse["token"] = "*****"
 	}
 
+	if dataParse["access_token"] != nil {
+		dataParse["access_token"] = "*****"
+	}
+
+	if dataParse["refresh_token"] != nil {
+		dataParse["refresh_token"] = "*****"
+	}
+
 	if dataParse["key"] != nil {
 		dataParse["key"] = "*****"
 	}
@@ -57,17 +60,10 @@ func sanitizeSensitiveData(bytes []byte) string {
 	return string(res)
 }
 
-func getIp(c *fiber.Ctx) string {
-	ip := c.Get("X-Forwarded-For")
-
-	if ip != "" {
-		ips := strings.Split(ip, ",")
-		ip = strings.TrimSpace(ips[0])
-	} else {
-		ip = c.IP()
-	}
-
-	return ip
+// GetClientIP returns the caller ip. The proxy header is only read on requests of the trusted proxies,
+// see application.enable_trusted_proxy_check, so a client cannot choose its own ip.
+func GetClientIP(c *fiber.Ctx) string {
+	return c.IP()
 }
 
 // func CreateAccessLog(ctx *fiber.Ctx, ptr string, statusCode int, resp any) {
@@ -77,7 +73,7 @@ func getIp(c *fiber.Ctx) string {
 // 			" " +
 // 			time.Now().Format("2006/01/02 15:04:05") +
 // 			" " +
-// 			getIp(ctx) +
+// 			GetClientIP(ctx) +
 // 			" " +
 // 			ctx.Method() +
 // 			" " +
@@ -144,91 +140,65 @@ func getIp(c *fiber.Ctx) string {
 //			color.Magenta(logFormat)
 //		}
 //	}
-func buildLogFormat(ctx *fiber.Ctx, ptr string, statusCode int) string {
-	logFormat := ptr +
-		" " +
-		time.Now().Format("2006/01/02 15:04:05") +
-		" " +
-		getIp(ctx) +
-		" " +
-		ctx.Method() +
-		" " +
-		strconv.Itoa(statusCode) +
-		" " +
-		"CONTENT_TYPE=" + string(ctx.Request().Header.ContentType()) +
-		" " +
-		"ROUTE=" + ctx.Route().Path
-
-	if ctx.Request().URI().QueryString() != nil {
-		logFormat = logFormat + " QUERY_URL=" + string(ctx.Request().URI().QueryString())
-	}
-	return logFormat
-}
 
-func appendPayload(ctx *fiber.Ctx, logFormat string) string {
-	if ctx.Body() != nil {
-		body := string(ctx.Request().Body())
+// accessLogAttrs describes the request, the payload is sanitized before it is logged.
+func accessLogAttrs(ctx *fiber.Ctx, statusCode int) []any {
+	attrs := []any{
+		slog.String("ip", GetClientIP(ctx)),
+		slog.String("method", ctx.Method()),
+		slog.Int("status", statusCode),
+		slog.String("content_type", string(ctx.Request().Header.ContentType())),
+		slog.String("route", ctx.Route().Path),
+		slog.String("path", ctx.Path()),
+	}
+
+	if query := ctx.Request().URI().QueryString(); len(query) > 0 {
+		attrs = append(attrs, slog.String("query", string(query)))
+	}
+
+	if body := ctx.Request().Body(); len(body) > 0 {
 		helper := make(map[string]interface{})
-		err := json.Unmarshal([]byte(body), &helper)
-		if err == nil {
-			bytes, err := json.Marshal(helper)
-			if err == nil {
-				var dataSanitize = sanitizeSensitiveData(bytes)
-				logFormat = logFormat + " PAYLOAD=" + dataSanitize
+		if err := json.Unmarshal(body, &helper); err == nil {
+			if bytes, err := json.Marshal(helper); err == nil {
+				attrs = append(attrs, slog.Any("payload", json.RawMessage(sanitizeSensitiveData(bytes))))
 			}
 		}
 	}
-	return logFormat
-}
 
-func appendResponse(resp any, logFormat string) string {
-	bytes, err := json.Marshal(resp)
-	if err == nil {
-		var dataSanitize = sanitizeSensitiveData(bytes)
-		if dataSanitize != "null" {
-			logFormat = logFormat + " RESPONSE=" + dataSanitize
-		} else {
-			logFormat = logFormat + " MESSAGE={ " + fmt.Sprintf("%v", resp) + " }"
-		}
+	if executor := ctx.Cookies("cms_email", ""); executor != "" {
+		attrs = append(attrs, slog.String("executor", executor))
 	}
-	return logFormat
-}
 
-func appendExecutor(ctx *fiber.Ctx, logFormat string) string {
-	executor := ctx.Cookies("cms_email", "")
-	if executor != "" {
-		logFormat = logFormat + " EXECUTOR=" + executor
-	}
-	return logFormat
+	return attrs
 }
 
-func writeLogToFile(logFormat string) {
-	go func() {
-		f, err := os.OpenFile(Cfg.Application.LogPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-		if err != nil {
-			log.Println(err)
-			return
-		}
-		defer f.Close()
-		if _, err := f.WriteString(logFormat + "\n"); err != nil {
-			log.Println(err)
+func responseLogAttr(resp any) slog.Attr {
+	bytes, err := json.Marshal(resp)
+	if err == nil {
+		if dataSanitize := sanitizeSensitiveData(bytes); dataSanitize != "null" {
+			return slog.Any("response", json.RawMessage(dataSanitize))
 		}
-	}()
+	}
+	return slog.String("message", fmt.Sprintf("%v", resp))
 }
 
+// CreateAccessLog logs one api call, ptr tags the kind of line (e.g. "[ACCESS:API][ERROR]").
+// 5xx are logged as errors and 4xx as warnings, successful calls are sampled by application.log_sample_rate.
 func CreateAccessLog(ctx *fiber.Ctx, ptr string, statusCode int, resp any) {
 	if !Cfg.Application.EnableLog {
 		return
 	}
 
-	logFormat := buildLogFormat(ctx, ptr, statusCode)
-	logFormat = appendPayload(ctx, logFormat)
-	logFormat = appendResponse(resp, logFormat)
-	logFormat = appendExecutor(ctx, logFormat)
-
-	if Cfg.Application.EnableLogToFile {
-		writeLogToFile(logFormat)
+	level := slog.LevelInfo
+	switch {
+	case statusCode >= 500:
+		level = slog.LevelError
+	case statusCode >= 400:
+		level = slog.LevelWarn
+	case !sampled():
+		return
 	}
 
-	color.Magenta(logFormat)
+	attrs := append(accessLogAttrs(ctx, statusCode), responseLogAttr(resp))
+	Logger(ctx).Log(ctx.Context(), level, ptr, attrs...)
 }
@@ -0,0 +1,277 @@
+package pkg
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/DiansSopandi/goride_be/pkg/health"
+)
+
+const (
+	defaultLogBufferLines = 10000
+	logFlushInterval      = time.Second
+	logBackupTimeFormat   = "20060102T150405.000000"
+	logFileWorker         = "log_file"
+)
+
+// LogFileConfig controls the rotation of application.log_path, zero values disable that limit.
+type LogFileConfig struct {
+	Path        string
+	MaxSizeMB   int
+	MaxAge      time.Duration // delete backups older than this
+	MaxBackups  int           // keep at most this many backups
+	RotateEvery time.Duration // rotate on a fixed interval, e.g. 24h
+	Compress    bool          // gzip rotated files
+	BufferLines int           // lines queued before new ones are dropped
+}
+
+// rotatingFileWriter owns the log file from a single goroutine, Write only queues the line
+// so request goroutines never block on disk and lines of concurrent requests never interleave.
+type rotatingFileWriter struct {
+	cfg      LogFileConfig
+	lines    chan []byte
+	done     chan struct{}
+	dropped  atomic.Uint64
+	closing  sync.Once
+	file     *os.File
+	buf      *bufio.Writer
+	size     int64
+	openedAt time.Time
+	compress sync.WaitGroup
+	// archiving serializes compression and retention of the backups
+	archiving sync.Mutex
+}
+
+func newRotatingFileWriter(cfg LogFileConfig) (*rotatingFileWriter, error) {
+	if cfg.BufferLines <= 0 {
+		cfg.BufferLines = defaultLogBufferLines
+	}
+
+	w := &rotatingFileWriter{
+		cfg:   cfg,
+		lines: make(chan []byte, cfg.BufferLines),
+		done:  make(chan struct{}),
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+
+	health.RegisterWorker(logFileWorker, 10*logFlushInterval)
+	go w.run()
+	return w, nil
+}
+
+// Write queues a copy of p, the line is counted as dropped when the queue is full.
+func (w *rotatingFileWriter) Write(p []byte) (int, error) {
+	line := make([]byte, len(p))
+	copy(line, p)
+
+	select {
+	case w.lines <- line:
+	default:
+		w.dropped.Add(1)
+	}
+	return len(p), nil
+}
+
+// Dropped returns how many lines were lost because the queue was full.
+func (w *rotatingFileWriter) Dropped() uint64 {
+	return w.dropped.Load()
+}
+
+// Close writes the queued lines, flushes and closes the file. Lines written after Close are dropped.
+func (w *rotatingFileWriter) Close() error {
+	w.closing.Do(func() { close(w.lines) })
+	<-w.done
+	health.UnregisterWorker(logFileWorker)
+	w.compress.Wait()
+	return nil
+}
+
+func (w *rotatingFileWriter) run() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(logFlushInterval)
+	defer ticker.Stop()
+
+	var reported uint64
+	for {
+		select {
+		case line, ok := <-w.lines:
+			if !ok {
+				w.reportDropped(&reported)
+				w.flush()
+				if w.file != os.Stderr {
+					w.file.Close()
+				}
+				return
+			}
+			w.write(line)
+		case <-ticker.C:
+			health.Beat(logFileWorker)
+			w.reportDropped(&reported)
+			w.flush()
+			if w.cfg.RotateEvery > 0 && time.Since(w.openedAt) >= w.cfg.RotateEvery {
+				w.rotate()
+			}
+		}
+	}
+}
+
+func (w *rotatingFileWriter) write(line []byte) {
+	if w.cfg.MaxSizeMB > 0 && w.size+int64(len(line)) > int64(w.cfg.MaxSizeMB)<<20 && w.size > 0 {
+		w.rotate()
+	}
+
+	n, err := w.buf.Write(line)
+	w.size += int64(n)
+	if err != nil {
+		// stderr, not slog: a failing log file must not log back into itself
+		fmt.Fprintln(os.Stderr, "log file error:", err)
+	}
+}
+
+// reportDropped writes the number of lines dropped since the last report into the file itself.
+func (w *rotatingFileWriter) reportDropped(reported *uint64) {
+	dropped := w.dropped.Load()
+	if dropped == *reported {
+		return
+	}
+
+	line := fmt.Sprintf(`{"time":%q,"level":"WARN","msg":"log lines dropped, buffer full","dropped":%d,"dropped_total":%d}`+"\n",
+		time.Now().Format(time.RFC3339Nano), dropped-*reported, dropped)
+	w.write([]byte(line))
+	*reported = dropped
+}
+
+func (w *rotatingFileWriter) flush() {
+	if err := w.buf.Flush(); err != nil {
+		fmt.Fprintln(os.Stderr, "log file error:", err)
+	}
+}
+
+func (w *rotatingFileWriter) open() error {
+	if err := os.MkdirAll(filepath.Dir(w.cfg.Path), 0o755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(w.cfg.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	w.file = f
+	w.buf = bufio.NewWriterSize(f, 64<<10)
+	w.size = info.Size()
+	w.openedAt = time.Now()
+	return nil
+}
+
+// rotate renames the current file to <name>-<timestamp><ext>, reopens the path and applies retention.
+func (w *rotatingFileWriter) rotate() {
+	w.flush()
+	if w.file != os.Stderr {
+		w.file.Close()
+	}
+
+	ext := filepath.Ext(w.cfg.Path)
+	backup := fmt.Sprintf("%s-%s%s", strings.TrimSuffix(w.cfg.Path, ext), time.Now().Format(logBackupTimeFormat), ext)
+	if err := os.Rename(w.cfg.Path, backup); err != nil {
+		fmt.Fprintln(os.Stderr, "log rotation error:", err)
+		backup = ""
+	}
+
+	if err := w.open(); err != nil {
+		// keep logging somewhere rather than losing every line
+		fmt.Fprintln(os.Stderr, "log rotation error:", err)
+		w.file = os.Stderr
+		w.buf = bufio.NewWriter(os.Stderr)
+		w.size = 0
+		w.openedAt = time.Now()
+	}
+
+	w.compress.Add(1)
+	go func() {
+		defer w.compress.Done()
+		w.archiving.Lock()
+		defer w.archiving.Unlock()
+
+		if backup != "" && w.cfg.Compress {
+			if err := gzipFile(backup); err != nil {
+				fmt.Fprintln(os.Stderr, "log compression error:", err)
+			}
+		}
+		w.cleanup()
+	}()
+}
+
+// cleanup deletes the backups beyond MaxBackups or older than MaxAge.
+func (w *rotatingFileWriter) cleanup() {
+	ext := filepath.Ext(w.cfg.Path)
+	pattern := strings.TrimSuffix(w.cfg.Path, ext) + "-*" + ext + "*"
+
+	backups, err := filepath.Glob(pattern)
+	if err != nil {
+		return
+	}
+	// the timestamp in the name sorts chronologically
+	sort.Sort(sort.Reverse(sort.StringSlice(backups)))
+
+	for i, backup := range backups {
+		expired := false
+		if w.cfg.MaxAge > 0 {
+			if info, err := os.Stat(backup); err == nil && time.Since(info.ModTime()) > w.cfg.MaxAge {
+				expired = true
+			}
+		}
+
+		if (w.cfg.MaxBackups > 0 && i >= w.cfg.MaxBackups) || expired {
+			if err := os.Remove(backup); err != nil {
+				fmt.Fprintln(os.Stderr, "log retention error:", err)
+			}
+		}
+	}
+}
+
+func gzipFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+
+	zw := gzip.NewWriter(dst)
+	if _, err := io.Copy(zw, src); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+
+	return os.Remove(path)
+}
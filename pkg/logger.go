@@ -0,0 +1,179 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math/rand/v2"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var logFile *rotatingFileWriter
+
+const (
+	RequestIDHeader = fiber.HeaderXRequestID
+	RequestIDKey    = "request_id"
+)
+
+// InitLogger replaces the default logger, the standard log package included, with a leveled slog logger.
+// Production (application.env = production) logs JSON, every other env logs readable text.
+func InitLogger() {
+	opts := &slog.HandlerOptions{Level: parseLogLevel(Cfg.Application.LogLevel)}
+
+	var handler slog.Handler
+	if logFormat() == "json" {
+		handler = slog.NewJSONHandler(os.Stdout, opts)
+	} else {
+		handler = slog.NewTextHandler(os.Stdout, opts)
+	}
+
+	if Cfg.Application.EnableLogToFile && Cfg.Application.LogPath != "" {
+		w, err := newRotatingFileWriter(logFileConfig())
+		if err != nil {
+			slog.New(handler).Error("log file disabled", "path", Cfg.Application.LogPath, "error", err)
+		} else {
+			logFile = w
+			handler = fanoutHandler{handler, slog.NewJSONHandler(w, opts)}
+		}
+	}
+
+	slog.SetDefault(slog.New(handler))
+}
+
+// CloseLogger flushes the lines still queued for the log file, call it before the process exits.
+func CloseLogger() {
+	if logFile != nil {
+		logFile.Close()
+	}
+}
+
+// DroppedLogLines returns how many log file lines were dropped because the write queue was full.
+func DroppedLogLines() uint64 {
+	if logFile == nil {
+		return 0
+	}
+	return logFile.Dropped()
+}
+
+func logFileConfig() LogFileConfig {
+	app := Cfg.Application
+	cfg := LogFileConfig{
+		Path:        app.LogPath,
+		MaxSizeMB:   app.LogMaxSizeMB,
+		MaxBackups:  app.LogMaxBackups,
+		MaxAge:      time.Duration(app.LogMaxAgeDays) * 24 * time.Hour,
+		Compress:    app.LogCompress,
+		BufferLines: app.LogBufferLines,
+	}
+
+	if app.LogRotateInterval != "" {
+		interval, err := time.ParseDuration(app.LogRotateInterval)
+		if err != nil {
+			slog.Warn("invalid application.log_rotate_interval, time based rotation disabled", "value", app.LogRotateInterval, "error", err)
+		}
+		cfg.RotateEvery = interval
+	}
+	return cfg
+}
+
+// Logger returns the default logger tagged with the request id and the trace of c.
+func Logger(c *fiber.Ctx) *slog.Logger {
+	var attrs []any
+	if id := RequestID(c); id != "" {
+		attrs = append(attrs, slog.String(RequestIDKey, id))
+	}
+	if c != nil {
+		if sc := trace.SpanContextFromContext(c.UserContext()); sc.IsValid() {
+			attrs = append(attrs, slog.String(TraceIDKey, sc.TraceID().String()), slog.String(SpanIDKey, sc.SpanID().String()))
+		}
+	}
+
+	if len(attrs) == 0 {
+		return slog.Default()
+	}
+	return slog.Default().With(attrs...)
+}
+
+// RequestID returns the id set by the request id middleware, empty outside of a request.
+func RequestID(c *fiber.Ctx) string {
+	if c == nil {
+		return ""
+	}
+	id, _ := c.Locals(RequestIDKey).(string)
+	return id
+}
+
+// Fatal logs msg at error level and exits, use it instead of log.Fatalf once the logger is initialized.
+func Fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	ShutdownTracer()
+	CloseLogger()
+	os.Exit(1)
+}
+
+// sampled reports whether a successful access log line should be written, see application.log_sample_rate.
+func sampled() bool {
+	rate := Cfg.Application.LogSampleRate
+	return rate <= 0 || rate >= 1 || rand.Float64() < rate
+}
+
+func logFormat() string {
+	if format := strings.ToLower(Cfg.Application.LogFormat); format != "" {
+		return format
+	}
+	if strings.EqualFold(Cfg.Application.Env, "production") {
+		return "json"
+	}
+	return "text"
+}
+
+func parseLogLevel(level string) slog.Level {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return slog.LevelInfo
+	}
+	return l
+}
+
+// fanoutHandler sends every record to all of its handlers.
+type fanoutHandler []slog.Handler
+
+func (h fanoutHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, handler := range h {
+		if handler.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h fanoutHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, handler := range h {
+		if handler.Enabled(ctx, r.Level) {
+			errs = append(errs, handler.Handle(ctx, r.Clone()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (h fanoutHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make(fanoutHandler, len(h))
+	for i, handler := range h {
+		handlers[i] = handler.WithAttrs(attrs)
+	}
+	return handlers
+}
+
+func (h fanoutHandler) WithGroup(name string) slog.Handler {
+	handlers := make(fanoutHandler, len(h))
+	for i, handler := range h {
+		handlers[i] = handler.WithGroup(name)
+	}
+	return handlers
+}
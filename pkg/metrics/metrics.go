@@ -0,0 +1,128 @@
+package metrics
+
+import (
+	"database/sql"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+const namespace = "goride"
+
+// Registry holds every GoRide metric, it is served on /metrics.
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route template and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method, route template and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	redisDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "redis_command_duration_seconds",
+		Help:      "Redis command latency by command and result.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"command", "result"})
+
+	rateLimitRejections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limit_rejections_total",
+		Help:      "Requests rejected by a rate limiter.",
+	}, []string{"limiter", "route"})
+
+	logins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "auth_logins_total",
+		Help:      "Login attempts by provider and result.",
+	}, []string{"provider", "result"})
+
+	sessionDenylistErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "session_denylist_errors_total",
+		Help:      "Failed reads and writes of the revoked sessions denylist in redis.",
+	}, []string{"operation"})
+
+	onlineDrivers = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "online_drivers",
+		Help:      "Drivers currently online.",
+	})
+
+	activeRides = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "active_rides",
+		Help:      "Rides currently in progress.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpDuration,
+		redisDuration,
+		rateLimitRejections,
+		logins,
+		sessionDenylistErrors,
+		onlineDrivers,
+		activeRides,
+	)
+}
+
+// RegisterDB exposes the connection pool stats of db (open, in use, idle, wait count and duration).
+func RegisterDB(db *sql.DB, name string) {
+	Registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+func ObserveHTTPRequest(method, route string, status int, duration time.Duration) {
+	code := strconv.Itoa(status)
+	httpRequests.WithLabelValues(method, route, code).Inc()
+	httpDuration.WithLabelValues(method, route, code).Observe(duration.Seconds())
+}
+
+func ObserveRedisCommand(command string, err error, duration time.Duration) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	redisDuration.WithLabelValues(command, result).Observe(duration.Seconds())
+}
+
+// RateLimitRejected counts a request rejected by limiter ("route", "api_key" or the name of a rate_limit policy).
+func RateLimitRejected(limiter, route string) {
+	rateLimitRejections.WithLabelValues(limiter, route).Inc()
+}
+
+func LoginSucceeded(provider string) {
+	logins.WithLabelValues(provider, "success").Inc()
+}
+
+func LoginFailed(provider string) {
+	logins.WithLabelValues(provider, "failure").Inc()
+}
+
+// SessionDenylistFailed counts a failed "read" or "write" of the session denylist. A failed write leaves
+// the revoked sessions usable on the other instances until their access tokens expire, alert on it.
+func SessionDenylistFailed(operation string) {
+	sessionDenylistErrors.WithLabelValues(operation).Inc()
+}
+
+func SetOnlineDrivers(n int) {
+	onlineDrivers.Set(float64(n))
+}
+
+func SetActiveRides(n int) {
+	activeRides.Set(float64(n))
+}
@@ -0,0 +1,44 @@
+package metrics
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisHook measures the latency of every Redis command, pipelines are reported as "pipeline".
+type RedisHook struct{}
+
+func (RedisHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+func (RedisHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmd)
+		ObserveRedisCommand(cmd.Name(), redisError(err), time.Since(start))
+		return err
+	}
+}
+
+func (RedisHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmds)
+		ObserveRedisCommand("pipeline", redisError(err), time.Since(start))
+		return err
+	}
+}
+
+// redisError ignores redis.Nil, a missing key is a normal result.
+func redisError(err error) error {
+	if err == redis.Nil {
+		return nil
+	}
+	return err
+}
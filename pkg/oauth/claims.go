@@ -0,0 +1,105 @@
+package oauth
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/DiansSopandi/goride_be/dto"
+	"github.com/DiansSopandi/goride_be/pkg"
+)
+
+// MapClaims converts raw provider claims into dto.OAuthUserInfo using the mapping of the provider.
+func MapClaims(provider string, raw map[string]interface{}, m pkg.OAuthClaimsMapping) (dto.OAuthUserInfo, error) {
+	info := dto.OAuthUserInfo{
+		Provider:      provider,
+		Subject:       claimString(raw, m.Subject),
+		Email:         strings.ToLower(claimString(raw, m.Email)),
+		EmailVerified: claimBool(raw, m.EmailVerified),
+		Name:          claimString(raw, m.Name),
+		Picture:       claimString(raw, m.Picture),
+		Raw:           raw,
+	}
+
+	if info.Subject == "" {
+		return dto.OAuthUserInfo{}, fmt.Errorf("missing %q claim from %s", m.Subject, provider)
+	}
+	if info.Email == "" {
+		return dto.OAuthUserInfo{}, fmt.Errorf("missing %q claim from %s", m.Email, provider)
+	}
+
+	return info, nil
+}
+
+// lookup resolves a dotted path like "picture.data.url".
+func lookup(raw map[string]interface{}, path string) (interface{}, bool) {
+	if path == "" {
+		return nil, false
+	}
+
+	var cur interface{} = raw
+	for _, key := range strings.Split(path, ".") {
+		obj, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = obj[key]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// setClaim sets a dotted path like lookup, creating the missing objects on the way.
+func setClaim(raw map[string]interface{}, path string, value interface{}) {
+	if path == "" {
+		return
+	}
+
+	keys := strings.Split(path, ".")
+	obj := raw
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := obj[key].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			obj[key] = next
+		}
+		obj = next
+	}
+	obj[keys[len(keys)-1]] = value
+}
+
+func claimString(raw map[string]interface{}, path string) string {
+	v, ok := lookup(raw, path)
+	if !ok || v == nil {
+		return ""
+	}
+
+	switch val := v.(type) {
+	case string:
+		return val
+	case float64:
+		// numeric ids (github, facebook) are decoded as float64
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	default:
+		return fmt.Sprintf("%v", val)
+	}
+}
+
+func claimBool(raw map[string]interface{}, path string) bool {
+	v, ok := lookup(raw, path)
+	if !ok {
+		return false
+	}
+
+	switch val := v.(type) {
+	case bool:
+		return val
+	case string:
+		// apple sends email_verified as "true"
+		b, _ := strconv.ParseBool(val)
+		return b
+	default:
+		return false
+	}
+}
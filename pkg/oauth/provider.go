@@ -0,0 +1,235 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/DiansSopandi/goride_be/dto"
+	"github.com/DiansSopandi/goride_be/pkg"
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// Provider is a single configured OAuth2 / OIDC login provider.
+type Provider struct {
+	Name string
+	Type string
+
+	config      *oauth2.Config
+	issuer      string
+	userInfoURL string
+	emailsURL   string
+	// verifiedEmails providers only return confirmed addresses
+	verifiedEmails bool
+	claims         pkg.OAuthClaimsMapping
+	pkce           bool
+
+	mu       sync.Mutex
+	verifier *oidc.IDTokenVerifier
+}
+
+func newProvider(name string, cfg pkg.OAuthProviderConfig) *Provider {
+	return &Provider{
+		Name: name,
+		Type: cfg.Type,
+		config: &oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURI,
+			Scopes:       cfg.Scopes,
+			Endpoint: oauth2.Endpoint{
+				AuthURL:  cfg.AuthURL,
+				TokenURL: cfg.TokenURL,
+			},
+		},
+		issuer:         cfg.Issuer,
+		userInfoURL:    cfg.UserInfoURL,
+		emailsURL:      cfg.EmailsURL,
+		verifiedEmails: cfg.VerifiedEmails,
+		claims:         cfg.Claims,
+		pkce:           !cfg.DisablePKCE,
+	}
+}
+
+// discover resolves the OIDC endpoints and id_token verifier once, lazily,
+// so a provider that is down at boot does not prevent the server from starting.
+func (p *Provider) discover() error {
+	if p.Type != TypeOIDC {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.verifier != nil {
+		return nil
+	}
+
+	if p.issuer == "" {
+		return fmt.Errorf("oauth provider %s: issuer is required for oidc", p.Name)
+	}
+
+	provider, err := oidc.NewProvider(context.Background(), p.issuer)
+	if err != nil {
+		return fmt.Errorf("oauth provider %s: discovery failed: %w", p.Name, err)
+	}
+
+	endpoint := provider.Endpoint()
+	if p.config.Endpoint.AuthURL == "" {
+		p.config.Endpoint.AuthURL = endpoint.AuthURL
+	}
+	if p.config.Endpoint.TokenURL == "" {
+		p.config.Endpoint.TokenURL = endpoint.TokenURL
+	}
+	if p.userInfoURL == "" {
+		p.userInfoURL = provider.UserInfoEndpoint()
+	}
+
+	p.verifier = provider.Verifier(&oidc.Config{ClientID: p.config.ClientID})
+	return nil
+}
+
+// AuthCodeURL builds the redirect url to the provider consent page.
+func (p *Provider) AuthCodeURL(state, nonce, verifier string) (string, error) {
+	if err := p.discover(); err != nil {
+		return "", err
+	}
+
+	opts := []oauth2.AuthCodeOption{oauth2.AccessTypeOffline}
+	if p.Type == TypeOIDC {
+		opts = append(opts, oidc.Nonce(nonce))
+	}
+	if p.pkce {
+		opts = append(opts, oauth2.S256ChallengeOption(verifier))
+	}
+
+	return p.config.AuthCodeURL(state, opts...), nil
+}
+
+// Exchange trades the authorization code for provider tokens.
+func (p *Provider) Exchange(ctx context.Context, code, verifier string) (*oauth2.Token, error) {
+	if err := p.discover(); err != nil {
+		return nil, err
+	}
+
+	var opts []oauth2.AuthCodeOption
+	if p.pkce {
+		opts = append(opts, oauth2.VerifierOption(verifier))
+	}
+
+	return p.config.Exchange(ctx, code, opts...)
+}
+
+// UserInfo returns the mapped identity: from the verified id_token for OIDC providers,
+// or from the userinfo endpoint for plain OAuth2 providers.
+func (p *Provider) UserInfo(ctx context.Context, tok *oauth2.Token, nonce string) (dto.OAuthUserInfo, error) {
+	var (
+		raw map[string]interface{}
+		err error
+	)
+
+	if p.Type == TypeOIDC {
+		raw, err = p.idTokenClaims(ctx, tok, nonce)
+	} else {
+		raw, err = p.fetchUserInfo(ctx, tok)
+	}
+	if err != nil {
+		return dto.OAuthUserInfo{}, err
+	}
+
+	switch {
+	case p.verifiedEmails:
+		setClaim(raw, p.claims.EmailVerified, true)
+	case p.emailsURL != "" && !claimBool(raw, p.claims.EmailVerified):
+		// the email of the userinfo may be unverified, use the primary verified one of the list
+		email, err := p.fetchPrimaryEmail(ctx, tok)
+		if err != nil {
+			return dto.OAuthUserInfo{}, err
+		}
+		setClaim(raw, p.claims.Email, email)
+		setClaim(raw, p.claims.EmailVerified, true)
+	}
+
+	return MapClaims(p.Name, raw, p.claims)
+}
+
+func (p *Provider) idTokenClaims(ctx context.Context, tok *oauth2.Token, nonce string) (map[string]interface{}, error) {
+	rawIDToken, _ := tok.Extra("id_token").(string)
+	if rawIDToken == "" {
+		return nil, fmt.Errorf("id_token not found in token response")
+	}
+
+	idToken, err := p.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id_token: %w", err)
+	}
+
+	if idToken.Nonce != nonce {
+		return nil, fmt.Errorf("id_token nonce mismatch")
+	}
+
+	var raw map[string]interface{}
+	if err := idToken.Claims(&raw); err != nil {
+		return nil, fmt.Errorf("failed to decode id_token claims: %w", err)
+	}
+	return raw, nil
+}
+
+func (p *Provider) fetchUserInfo(ctx context.Context, tok *oauth2.Token) (map[string]interface{}, error) {
+	if p.userInfoURL == "" {
+		return nil, fmt.Errorf("oauth provider %s: userinfo_url is not configured", p.Name)
+	}
+
+	var raw map[string]interface{}
+	if err := p.getJSON(ctx, tok, "userinfo", p.userInfoURL, &raw); err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
+
+// fetchPrimaryEmail returns the primary verified address of the emails_url list, in the format of
+// GitHub /user/emails.
+func (p *Provider) fetchPrimaryEmail(ctx context.Context, tok *oauth2.Token) (string, error) {
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := p.getJSON(ctx, tok, "emails", p.emailsURL, &emails); err != nil {
+		return "", err
+	}
+
+	for _, e := range emails {
+		if e.Primary && e.Verified && e.Email != "" {
+			return e.Email, nil
+		}
+	}
+	return "", fmt.Errorf("no primary verified email from %s", p.Name)
+}
+
+// getJSON decodes the response of an authenticated GET to the provider api into v.
+func (p *Provider) getJSON(ctx context.Context, tok *oauth2.Token, name, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.config.Client(ctx, tok).Do(req)
+	if err != nil {
+		return fmt.Errorf("%s request failed: %w", name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s request failed with status %d", name, resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package oauth
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/DiansSopandi/goride_be/pkg"
+)
+
+const (
+	TypeOIDC   = "oidc"
+	TypeOAuth2 = "oauth2"
+)
+
+var (
+	registryOnce sync.Once
+	registry     map[string]*Provider
+)
+
+// wellKnownProviders holds the defaults for providers allowed by the user_providers table,
+// values from env.conf always take precedence.
+var wellKnownProviders = map[string]pkg.OAuthProviderConfig{
+	"google": {
+		Type:   TypeOIDC,
+		Issuer: "https://accounts.google.com",
+		Scopes: []string{"openid", "email", "profile"},
+	},
+	"apple": {
+		Type:   TypeOIDC,
+		Issuer: "https://appleid.apple.com",
+		Scopes: []string{"openid", "email", "name"},
+	},
+	// github /user has no email_verified claim and no email when the address is private,
+	// user:email grants /user/emails
+	"github": {
+		Type:        TypeOAuth2,
+		AuthURL:     "https://github.com/login/oauth/authorize",
+		TokenURL:    "https://github.com/login/oauth/access_token",
+		UserInfoURL: "https://api.github.com/user",
+		EmailsURL:   "https://api.github.com/user/emails",
+		Scopes:      []string{"read:user", "user:email"},
+		Claims: pkg.OAuthClaimsMapping{
+			Subject: "id",
+			Picture: "avatar_url",
+		},
+	},
+	"facebook": {
+		Type:        TypeOAuth2,
+		AuthURL:     "https://www.facebook.com/v19.0/dialog/oauth",
+		TokenURL:    "https://graph.facebook.com/v19.0/oauth/access_token",
+		UserInfoURL: "https://graph.facebook.com/me?fields=id,name,email,picture",
+		Scopes:      []string{"email", "public_profile"},
+		// the graph api only returns a confirmed email
+		VerifiedEmails: true,
+		Claims: pkg.OAuthClaimsMapping{
+			Subject: "id",
+			Picture: "picture.data.url",
+		},
+	},
+}
+
+// GetProvider returns the configured provider by name (case insensitive).
+func GetProvider(name string) (*Provider, bool) {
+	registryOnce.Do(loadProviders)
+	p, ok := registry[strings.ToLower(name)]
+	return p, ok
+}
+
+// ProviderNames returns the names of every configured provider.
+func ProviderNames() []string {
+	registryOnce.Do(loadProviders)
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	return names
+}
+
+func loadProviders() {
+	registry = make(map[string]*Provider)
+
+	configs := make(map[string]pkg.OAuthProviderConfig, len(pkg.Cfg.OAuthProviders)+1)
+	for name, cfg := range pkg.Cfg.OAuthProviders {
+		configs[strings.ToLower(name)] = cfg
+	}
+
+	// keep the legacy google_* keys of [application] working
+	app := pkg.Cfg.Application
+	if _, ok := configs["google"]; !ok && app.GoogleClientID != "" {
+		configs["google"] = pkg.OAuthProviderConfig{
+			ClientID:     app.GoogleClientID,
+			ClientSecret: app.GoogleClientSecret,
+			RedirectURI:  app.GoogleRedirectURI,
+		}
+	}
+
+	for name, cfg := range configs {
+		if cfg.ClientID == "" {
+			continue
+		}
+		registry[name] = newProvider(name, mergeDefaults(cfg, wellKnownProviders[name]))
+	}
+}
+
+func mergeDefaults(cfg, def pkg.OAuthProviderConfig) pkg.OAuthProviderConfig {
+	if cfg.Type == "" {
+		cfg.Type = def.Type
+	}
+	if cfg.Type == "" {
+		cfg.Type = TypeOIDC
+	}
+	if cfg.Issuer == "" {
+		cfg.Issuer = def.Issuer
+	}
+	if cfg.AuthURL == "" {
+		cfg.AuthURL = def.AuthURL
+	}
+	if cfg.TokenURL == "" {
+		cfg.TokenURL = def.TokenURL
+	}
+	if cfg.UserInfoURL == "" {
+		cfg.UserInfoURL = def.UserInfoURL
+	}
+	if cfg.EmailsURL == "" {
+		cfg.EmailsURL = def.EmailsURL
+	}
+	cfg.VerifiedEmails = cfg.VerifiedEmails || def.VerifiedEmails
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = def.Scopes
+	}
+	if len(cfg.Scopes) == 0 && cfg.Type == TypeOIDC {
+		cfg.Scopes = []string{"openid", "email", "profile"}
+	}
+
+	cfg.Claims = mergeClaims(cfg.Claims, def.Claims)
+	return cfg
+}
+
+func mergeClaims(m, def pkg.OAuthClaimsMapping) pkg.OAuthClaimsMapping {
+	pick := func(values ...string) string {
+		for _, v := range values {
+			if v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+
+	return pkg.OAuthClaimsMapping{
+		Subject:       pick(m.Subject, def.Subject, "sub"),
+		Email:         pick(m.Email, def.Email, "email"),
+		EmailVerified: pick(m.EmailVerified, def.EmailVerified, "email_verified"),
+		Name:          pick(m.Name, def.Name, "name"),
+		Picture:       pick(m.Picture, def.Picture, "picture"),
+	}
+}
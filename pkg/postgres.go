@@ -1,87 +0,0 @@
-package pkg
-
-import (
-	"database/sql"
-	"fmt"
-	"log"
-
-	_ "github.com/lib/pq"
-)
-
-func Connect() *sql.DB {
-	// Baca dari environment variables
-	// host := os.Getenv("DB_HOST")
-	// port := os.Getenv("DB_PORT")
-	// user := os.Getenv("DB_USER")
-	// password := os.Getenv("DB_PASSWORD")
-	// dbname := os.Getenv("DB_NAME")
-
-	host := Cfg.Database.Host
-	port := Cfg.Database.Port
-	user := Cfg.Database.User
-	password := Cfg.Database.Password
-	dbname := Cfg.Database.DBName
-
-	// Buat connection string
-	connStr := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
-		host, port, user, password, dbname)
-
-	db, err := sql.Open("postgres", connStr)
-	if err != nil {
-		log.Fatalf("Error connecting to the database: %v", err)
-	}
-
-	err = db.Ping()
-	if err != nil {
-		log.Fatalf("Error pinging the database: %v", err)
-	}
-
-	return db
-}
-
-func CreateDatabaseIfNotExists() {
-	host := GetEnv("DB_HOST")
-	port := GetEnv("DB_PORT")
-	user := GetEnv("DB_USER")
-	password := GetEnv("DB_PASSWORD")
-	dbname := GetEnv("DB_NAME")
-
-	// Connect to postgres database (default database)
-	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=postgres sslmode=disable",
-		host, port, user, password)
-
-	db, err := sql.Open("postgres", connStr)
-	if err != nil {
-		log.Fatalf("Error connecting to postgres database: %v", err)
-	}
-
-	defer db.Close()
-
-	// Check if database exists
-	var exists bool
-	query := `SELECT EXISTS(SELECT datname FROM pg_catalog.pg_database WHERE datname = $1)`
-
-	err = db.QueryRow(query, dbname).Scan(&exists)
-	if err != nil {
-		log.Fatalf("Error checking if database exists postgres: %v", err)
-	}
-
-	if !exists {
-		// Create database
-		createQuery := fmt.Sprintf("CREATE DATABASE %s", dbname)
-		_, err = db.Exec(createQuery)
-		if err != nil {
-			log.Fatalf("Error creating database: %v", err)
-		}
-		log.Printf("Database '%s' created successfully", dbname)
-	}
-}
-
-// InitDatabase initializes database (create if not exists and connect)
-func InitDatabase() *sql.DB {
-	// First, create database if it doesn't exist
-	CreateDatabaseIfNotExists()
-
-	// Then connect to the database
-	return Connect()
-}
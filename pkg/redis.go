@@ -2,10 +2,13 @@ package pkg
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"sync"
 
+	"github.com/DiansSopandi/goride_be/pkg/metrics"
+	"github.com/DiansSopandi/goride_be/pkg/tracing"
 	"github.com/go-redis/redis_rate/v10"
 	"github.com/redis/go-redis/v9"
 )
@@ -14,7 +17,6 @@ var (
 	once         sync.Once
 	redisClient  *redis.Client
 	redisLimiter *redis_rate.Limiter
-	rdb          *redis.Client
 )
 
 // InitRedis untuk inisialisasi Redis client & limiter sekali saja
@@ -26,10 +28,12 @@ func InitRedis() {
 
 		_, err := redisClient.Ping(context.Background()).Result()
 		if err != nil {
-			log.Fatalf("❌ Redis connection failed: %v", err)
+			Fatal("❌ redis connection failed", "error", err)
 		}
-		log.Println("✅ Redis connected")
+		slog.Info("✅ redis connected")
 
+		redisClient.AddHook(metrics.RedisHook{})
+		redisClient.AddHook(tracing.RedisHook{})
 		redisLimiter = redis_rate.NewLimiter(redisClient)
 	})
 }
@@ -50,9 +54,18 @@ func GetLimiter() *redis_rate.Limiter {
 	return redisLimiter
 }
 
+// CloseRedis closes the shared client, the limiter goes down with it.
 func CloseRedis() error {
-	if rdb != nil {
-		return rdb.Close()
+	if redisClient != nil {
+		return redisClient.Close()
 	}
 	return nil
 }
+
+// PingRedis checks the connection of the shared client, used by the readiness probe.
+func PingRedis(ctx context.Context) error {
+	if redisClient == nil {
+		return errors.New("redis client not initialized")
+	}
+	return redisClient.Ping(ctx).Err()
+}
@@ -0,0 +1,74 @@
+package pkg
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// RuntimeSettings are the settings applied without a restart when the configuration files change,
+// see WatchConfig. Read them with Runtime(), Cfg keeps the values of the startup.
+type RuntimeSettings struct {
+	DefaultMaxRequestPerMinute int               `json:"default_max_requests_per_minute"`
+	PublicRoutes               []string          `json:"public_routes"`
+	CorsOrigins                string            `json:"cors_origins"`
+	RateLimitPolicies          []RateLimitPolicy `json:"rate_limit_policies"`
+}
+
+// RuntimeSettingKeys are the configuration keys of RuntimeSettings, changes of any other key need a restart.
+var RuntimeSettingKeys = []string{
+	"application.default_max_requests_per_minute",
+	"application.public_routes",
+	"application.cors_origins",
+	"rate_limit.policies",
+}
+
+type runtimeState struct {
+	settings  RuntimeSettings
+	appliedAt time.Time
+}
+
+var runtimeSettings atomic.Pointer[runtimeState]
+
+// RuntimeSettings returns the runtime settings of the configuration.
+func (c Config) RuntimeSettings() RuntimeSettings {
+	return RuntimeSettings{
+		DefaultMaxRequestPerMinute: c.Application.DefaultMaxRequestPerMinute,
+		PublicRoutes:               c.Application.PublicRoutes,
+		CorsOrigins:                c.Application.CorsOrigins,
+		RateLimitPolicies:          c.RateLimit.Policies,
+	}
+}
+
+// Runtime returns the runtime settings in effect, the ones of Cfg until SetRuntime is called.
+// The returned settings are shared, do not modify them.
+func Runtime() *RuntimeSettings {
+	if state := runtimeSettings.Load(); state != nil {
+		return &state.settings
+	}
+	settings := Cfg.RuntimeSettings()
+	return &settings
+}
+
+// SetRuntime atomically replaces the runtime settings, requests already running keep the ones they read.
+func SetRuntime(settings RuntimeSettings) {
+	runtimeSettings.Store(&runtimeState{settings: settings, appliedAt: time.Now()})
+}
+
+// RuntimeAppliedAt returns when the runtime settings in effect were applied, at startup or by a reload.
+func RuntimeAppliedAt() time.Time {
+	if state := runtimeSettings.Load(); state != nil {
+		return state.appliedAt
+	}
+	return time.Time{}
+}
+
+// EffectiveConfig returns Cfg with the runtime settings in effect.
+func EffectiveConfig() Config {
+	cfg := Cfg
+	settings := Runtime()
+	cfg.Application.DefaultMaxRequestPerMinute = settings.DefaultMaxRequestPerMinute
+	cfg.Application.PublicRoutes = settings.PublicRoutes
+	cfg.Application.CorsOrigins = settings.CorsOrigins
+	cfg.RateLimit.Policies = settings.RateLimitPolicies
+	return cfg
+}
@@ -0,0 +1,60 @@
+package pkg
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/DiansSopandi/goride_be/pkg/metrics"
+)
+
+const (
+	revokedSessionPrefix = "session:revoked:"
+	seenSessionPrefix    = "session:seen:"
+)
+
+// denylistStaleUntil is the unix nano time until which the denylist may miss a revocation of this
+// instance, see SessionDenylistStale.
+var denylistStaleUntil atomic.Int64
+
+// DenySessions puts revoked session ids on the denylist checked by the JWT guard.
+// Entries only have to outlive the access tokens issued for the session, hence the ttl.
+func DenySessions(ctx context.Context, ttl time.Duration, sessionIDs ...string) error {
+	if len(sessionIDs) == 0 {
+		return nil
+	}
+
+	pipe := GetRedisClient().Pipeline()
+	for _, id := range sessionIDs {
+		pipe.Set(ctx, revokedSessionPrefix+id, 1, ttl)
+	}
+
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		metrics.SessionDenylistFailed("write")
+		denylistStaleUntil.Store(time.Now().Add(ttl).UnixNano())
+	}
+	return err
+}
+
+// IsSessionDenied reports whether the session id has been revoked.
+func IsSessionDenied(ctx context.Context, sessionID string) (bool, error) {
+	n, err := GetRedisClient().Exists(ctx, revokedSessionPrefix+sessionID).Result()
+	if err != nil {
+		metrics.SessionDenylistFailed("read")
+	}
+	return n > 0, err
+}
+
+// SessionDenylistStale reports whether a revocation of this instance failed to reach the denylist
+// while access tokens of the session may still be valid. The database has to be asked meanwhile,
+// the other instances cannot know and rely on the session_denylist_errors_total alert.
+func SessionDenylistStale() bool {
+	return time.Now().UnixNano() < denylistStaleUntil.Load()
+}
+
+// MarkSessionSeen returns true at most once per interval for a session,
+// so last_seen_at is not written to postgres on every request.
+func MarkSessionSeen(ctx context.Context, sessionID string, interval time.Duration) (bool, error) {
+	return GetRedisClient().SetNX(ctx, seenSessionPrefix+sessionID, 1, interval).Result()
+}
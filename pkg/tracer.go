@@ -0,0 +1,142 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	TraceIDKey = "trace_id"
+	SpanIDKey  = "span_id"
+)
+
+var tracerProvider *sdktrace.TracerProvider
+
+// InitTracer installs the global tracer provider and the W3C trace context propagator.
+// Spans are always created, so trace ids reach logs and error responses, but they are only
+// exported when tracing.exporter = "otlp".
+func InitTracer() {
+	cfg := Cfg.Tracing
+
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	ratio := cfg.SampleRatio
+	if ratio <= 0 || ratio > 1 {
+		ratio = 1
+	}
+
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(tracerResource(cfg)),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	}
+
+	switch strings.ToLower(cfg.Exporter) {
+	case "", "none", "noop":
+	case "otlp":
+		exporter, err := newOTLPExporter(cfg)
+		if err != nil {
+			slog.Error("tracing exporter disabled", "endpoint", cfg.Endpoint, "error", err)
+			break
+		}
+		opts = append(opts, sdktrace.WithBatcher(exporter))
+		slog.Info("tracing enabled", "exporter", "otlp", "protocol", otlpProtocol(cfg), "endpoint", cfg.Endpoint)
+	default:
+		slog.Warn("unknown tracing.exporter, spans are not exported", "exporter", cfg.Exporter)
+	}
+
+	tracerProvider = sdktrace.NewTracerProvider(opts...)
+	otel.SetTracerProvider(tracerProvider)
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		slog.Warn("opentelemetry error", "error", err)
+	}))
+}
+
+// ShutdownTracer exports the spans still buffered, call it before the process exits.
+func ShutdownTracer() {
+	if tracerProvider == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := tracerProvider.Shutdown(ctx); err != nil {
+		slog.Warn("failed to flush spans", "error", err)
+	}
+}
+
+// TraceID returns the trace id of the request span, empty outside of a traced request.
+func TraceID(c *fiber.Ctx) string {
+	if c == nil {
+		return ""
+	}
+	sc := trace.SpanContextFromContext(c.UserContext())
+	if !sc.HasTraceID() {
+		return ""
+	}
+	return sc.TraceID().String()
+}
+
+func tracerResource(cfg TracingConfig) *resource.Resource {
+	name := cfg.ServiceName
+	if name == "" {
+		name = "goride-api"
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(name),
+		semconv.DeploymentEnvironment(Cfg.Application.Env),
+	))
+	if err != nil {
+		slog.Warn("failed to build tracing resource", "error", err)
+		return resource.Default()
+	}
+	return res
+}
+
+func newOTLPExporter(cfg TracingConfig) (sdktrace.SpanExporter, error) {
+	ctx := context.Background()
+
+	switch otlpProtocol(cfg) {
+	case "grpc":
+		opts := []otlptracegrpc.Option{otlptracegrpc.WithHeaders(cfg.Headers)}
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracegrpc.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		return otlptracegrpc.New(ctx, opts...)
+	case "http":
+		opts := []otlptracehttp.Option{otlptracehttp.WithHeaders(cfg.Headers)}
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		return otlptracehttp.New(ctx, opts...)
+	default:
+		return nil, fmt.Errorf("unsupported tracing.protocol %q, use http or grpc", cfg.Protocol)
+	}
+}
+
+func otlpProtocol(cfg TracingConfig) string {
+	if cfg.Protocol == "" {
+		return "http"
+	}
+	return strings.ToLower(cfg.Protocol)
+}
@@ -0,0 +1,13 @@
+package tracing
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/DiansSopandi/goride_be"
+
+// Tracer returns the GoRide tracer of the global provider installed by pkg.InitTracer.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
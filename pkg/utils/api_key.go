@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"strings"
+)
+
+// API keys look like grk_<prefix>_<secret>, only the prefix and the hash of the secret are stored.
+const (
+	apiKeyTag       = "grk"
+	apiKeyPrefixLen = 12
+	apiKeySecretLen = 32
+)
+
+// GenerateApiKey returns the full key to hand out once, its lookup prefix and the hash of its secret.
+func GenerateApiKey() (key string, prefix string, secretHash string, err error) {
+	prefixBytes := make([]byte, apiKeyPrefixLen/2)
+	if _, err = rand.Read(prefixBytes); err != nil {
+		return "", "", "", err
+	}
+
+	secretBytes := make([]byte, apiKeySecretLen)
+	if _, err = rand.Read(secretBytes); err != nil {
+		return "", "", "", err
+	}
+
+	prefix = hex.EncodeToString(prefixBytes)
+	secret := hex.EncodeToString(secretBytes)
+	return apiKeyTag + "_" + prefix + "_" + secret, prefix, HashToken(secret), nil
+}
+
+// ParseApiKey splits a key into its prefix and secret.
+func ParseApiKey(key string) (prefix string, secret string, ok bool) {
+	parts := strings.Split(key, "_")
+	if len(parts) != 3 || parts[0] != apiKeyTag || len(parts[1]) != apiKeyPrefixLen || parts[2] == "" {
+		return "", "", false
+	}
+	return parts[1], parts[2], true
+}
+
+// VerifyApiKeySecret compares the secret against the stored hash in constant time.
+func VerifyApiKeySecret(secret, secretHash string) bool {
+	return subtle.ConstantTimeCompare([]byte(HashToken(secret)), []byte(secretHash)) == 1
+}
@@ -1,18 +1,28 @@
 package utils
 
 import (
+	"fmt"
+	"slices"
 	"time"
 
 	"github.com/DiansSopandi/goride_be/pkg"
+	"github.com/DiansSopandi/goride_be/pkg/jwks"
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func GenerateJWT(userID int, email string) (string, string, error) {
+const (
+	AccessTokenTTL  = 15 * time.Minute
+	RefreshTokenTTL = 7 * time.Hour
+)
+
+// GenerateJWT issues the access and refresh token of a session, sessionID is set as the sid claim.
+func GenerateJWT(userID int, email string, sessionID string) (string, string, error) {
 	atClaims := jwt.MapClaims{
 		"sub":   userID,
 		"email": email,
+		"sid":   sessionID,
 		// "exp":   time.Now().Add(7 * time.Hour).Unix(), // Token expires in 7 hours
-		"exp": time.Now().Add(15 * time.Minute).Unix(), // Token expires in 15 minutes
+		"exp": time.Now().Add(AccessTokenTTL).Unix(), // Token expires in 15 minutes
 		// "exp":  time.Now().Add(60 * time.Second).Unix(), // Token expires in 60 seconds
 		// "exp":  time.Now().Add(15 * 60 * time.Second).Unix(), // Token expires in 15 minutes
 		"type": "access_token",
@@ -20,28 +30,110 @@ func GenerateJWT(userID int, email string) (string, string, error) {
 	rtClaims := jwt.MapClaims{
 		"user_id": userID,
 		"email":   email,
+		"sid":     sessionID,
 		// "exp":     time.Now().Add(7 * 24 * time.Hour).Unix(), // Token expires in 7 * 24 hours jwt.TimeFunc().Add(time.Hour * 24).Unix(),
-		"exp":  time.Now().Add(7 * time.Hour).Unix(), // Token expires in 7 hours
+		"exp":  time.Now().Add(RefreshTokenTTL).Unix(), // Token expires in 7 hours
 		"type": "refresh_token",
 		// "exp":     time.Now().Add(time.Hour * 24).Unix(), // Token expires in 24 hours jwt.TimeFunc().Add(time.Hour * 24).Unix(),
 	}
 
-	jwtSecret := pkg.Cfg.Application.JwtSecretKey
-	atToken := jwt.NewWithClaims(jwt.SigningMethodHS256, atClaims)
-	rtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, rtClaims)
-
-	// jwtSecret := pkg.Cfg.Application.JwtSecret
-	// SigningKey: jwtware.SigningKey{Key: []byte(pkg.Cfg.Application.SsoJwtSecret)},
-
-	accessToken, err := atToken.SignedString([]byte(jwtSecret))
+	accessToken, err := signToken(atClaims)
 	if err != nil {
 		return "", "", err
 	}
 
-	refreshToken, err := rtToken.SignedString([]byte(jwtSecret))
+	refreshToken, err := signToken(rtClaims)
 	if err != nil {
 		return "", "", err
 	}
 
 	return accessToken, refreshToken, nil
 }
+
+// signToken signs with the active asymmetric key (kid header) or the shared secret in HS256 mode.
+func signToken(claims jwt.MapClaims) (string, error) {
+	claims["iat"] = time.Now().Unix()
+	if iss := pkg.Cfg.Jwt.Issuer; iss != "" {
+		claims["iss"] = iss
+	}
+	if aud := pkg.Cfg.Jwt.Audience; len(aud) > 0 {
+		claims["aud"] = aud
+	}
+
+	if jwks.UsesHMAC() {
+		jwtSecret := pkg.Cfg.Application.JwtSecretKey
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(jwtSecret))
+	}
+
+	key, err := jwks.SigningKey()
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.Algorithm), claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.Private)
+}
+
+// ParseAccessToken verifies signature, algorithm, expiry, iss and aud of an access token.
+func ParseAccessToken(tokenString string) (jwt.MapClaims, error) {
+	opts := []jwt.ParserOption{
+		// only the configured algorithm is accepted, never "none" or another HMAC variant
+		jwt.WithValidMethods([]string{jwks.Algorithm()}),
+		jwt.WithExpirationRequired(),
+	}
+	if iss := pkg.Cfg.Jwt.Issuer; iss != "" {
+		opts = append(opts, jwt.WithIssuer(iss))
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, keyFunc, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	// one of the configured audiences is enough, jwt.WithAudience only checks a single one
+	if audiences := pkg.Cfg.Jwt.Audience; len(audiences) > 0 && !hasAudience(claims, audiences) {
+		return nil, fmt.Errorf("token has invalid audience")
+	}
+
+	if claims["type"] != "access_token" {
+		return nil, fmt.Errorf("not an access token")
+	}
+
+	return claims, nil
+}
+
+// hasAudience reports whether the aud claim contains one of audiences.
+func hasAudience(claims jwt.MapClaims, audiences []string) bool {
+	tokenAudiences, err := claims.GetAudience()
+	if err != nil {
+		return false
+	}
+	for _, aud := range tokenAudiences {
+		if slices.Contains(audiences, aud) {
+			return true
+		}
+	}
+	return false
+}
+
+func keyFunc(token *jwt.Token) (interface{}, error) {
+	if jwks.UsesHMAC() {
+		return []byte(pkg.Cfg.Application.JwtSecretKey), nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return nil, fmt.Errorf("missing kid header")
+	}
+
+	key, err := jwks.VerificationKey(kid)
+	if err != nil {
+		return nil, err
+	}
+	if key.Algorithm != token.Method.Alg() {
+		return nil, fmt.Errorf("kid %s is not a %s key", kid, token.Method.Alg())
+	}
+	return key.Public, nil
+}
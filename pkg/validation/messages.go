@@ -0,0 +1,59 @@
+package validation
+
+import (
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/DiansSopandi/goride_be/pkg/i18n"
+	"github.com/go-playground/validator/v10"
+)
+
+// message returns the message of the failed validation in lang, from the "validation.<tag>" entries of
+// the catalogs. The entries suffixed by .string and .list are about the length of texts and lists.
+func message(lang string, e validator.FieldError) string {
+	template, ok := i18n.Lookup(lang, "validation."+e.Tag()+lengthSuffix(e))
+	if !ok {
+		template, ok = i18n.Lookup(lang, "validation."+e.Tag())
+	}
+	if !ok {
+		template, _ = i18n.Lookup(lang, "validation.default")
+	}
+	return strings.ReplaceAll(template, "{param}", messageParam(e))
+}
+
+// lengthSuffix selects the message about the length of a text or a list.
+func lengthSuffix(e validator.FieldError) string {
+	switch e.Kind() {
+	case reflect.String:
+		return ".string"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return ".list"
+	}
+	return ""
+}
+
+func messageParam(e validator.FieldError) string {
+	switch e.Tag() {
+	case "eqfield":
+		// the parameter is the go name of the other field, show its json name
+		return snakeCase(e.Param())
+	case "oneof":
+		return strings.ReplaceAll(e.Param(), " ", ", ")
+	}
+	return e.Param()
+}
+
+func snakeCase(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,97 @@
+package validation
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// rules are the validations added to the ones of go-playground/validator.
+var rules = map[string]validator.Func{
+	// strong_password: 8 to 128 characters mixing upper and lower case letters, numbers and
+	// special characters, without sequences, keyboard patterns, repeats or common passwords
+	"strong_password": func(fl validator.FieldLevel) bool {
+		return IsStrongPassword(fl.Field().String())
+	},
+	// phone: E.164 number, e.g. +6281234567890
+	"phone": func(fl validator.FieldLevel) bool {
+		return phonePattern.MatchString(fl.Field().String())
+	},
+	// plate_number: Indonesian vehicle registration, e.g. "B 1234 XYZ"
+	"plate_number": func(fl validator.FieldLevel) bool {
+		return platePattern.MatchString(strings.ToUpper(strings.TrimSpace(fl.Field().String())))
+	},
+}
+
+var (
+	phonePattern = regexp.MustCompile(`^\+[1-9][0-9]{7,14}$`)
+	platePattern = regexp.MustCompile(`^[A-Z]{1,2} ?[0-9]{1,4} ?[A-Z]{0,3}$`)
+
+	sequentialNumbers = regexp.MustCompile(`(012|123|234|345|456|567|678|789|890|901)`)
+	sequentialLetters = regexp.MustCompile(`(?i)(abc|bcd|cde|def|efg|fgh|ghi|hij|ijk|jkl|klm|lmn|mno|nop|opq|pqr|qrs|rst|stu|tuv|uvw|vwx|wxy|xyz)`)
+	keyboardPatterns  = regexp.MustCompile(`(?i)(qwerty|asdf|zxcv|qwer|1234|4321)`)
+)
+
+var commonPasswords = []string{
+	"password", "12345678", "qwerty", "abc123", "letmein",
+	"password123", "admin123", "welcome123", "monkey",
+	"dragon", "sunshine", "iloveyou", "trustno1",
+}
+
+// IsStrongPassword applies the strong_password rule.
+func IsStrongPassword(password string) bool {
+	if len(password) < 8 || len(password) > 128 {
+		return false
+	}
+
+	var hasUpper, hasLower, hasNumber, hasSpecial bool
+	for _, char := range password {
+		switch {
+		case unicode.IsUpper(char):
+			hasUpper = true
+		case unicode.IsLower(char):
+			hasLower = true
+		case unicode.IsNumber(char):
+			hasNumber = true
+		case unicode.IsPunct(char) || unicode.IsSymbol(char):
+			hasSpecial = true
+		}
+	}
+	if !hasUpper || !hasLower || !hasNumber || !hasSpecial {
+		return false
+	}
+
+	if sequentialNumbers.MatchString(password) || sequentialLetters.MatchString(password) || keyboardPatterns.MatchString(password) {
+		return false
+	}
+	if hasRepeatedChars(password, 3) {
+		return false
+	}
+
+	lower := strings.ToLower(password)
+	for _, common := range commonPasswords {
+		if strings.Contains(lower, common) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasRepeatedChars reports whether a character repeats maxRepeat times in a row.
+func hasRepeatedChars(s string, maxRepeat int) bool {
+	runes := []rune(s)
+	count := 1
+	for i := 1; i < len(runes); i++ {
+		if runes[i] == runes[i-1] {
+			count++
+			if count >= maxRepeat {
+				return true
+			}
+		} else {
+			count = 1
+		}
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+// Package validation validates the request bodies with the validate tags of the dto structs and
+// reports every invalid field, with its message in the language of the client.
+package validation
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/DiansSopandi/goride_be/errors"
+	"github.com/DiansSopandi/goride_be/pkg/i18n"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	// report the fields by their json names, the ones the client sent
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+	for tag, rule := range rules {
+		if err := v.RegisterValidation(tag, rule); err != nil {
+			panic(fmt.Sprintf("registering validation %s: %v", tag, err))
+		}
+	}
+	return v
+}
+
+// BindAndValidate parses the body of the request into a T and validates it. A body that cannot be parsed
+// is an invalid input, invalid fields are a validation error listing every one of them.
+func BindAndValidate[T any](c *fiber.Ctx) (T, error) {
+	var req T
+	if err := c.BodyParser(&req); err != nil {
+		return req, errors.InvalidInput(fmt.Sprintf("Failed to parse request body: %v", err))
+	}
+	return req, Validate(c, &req)
+}
+
+// Validate checks the validate tags of v, the messages are in the language of the Accept-Language of c.
+func Validate(c *fiber.Ctx, v any) error {
+	fieldErrors := Struct(v, i18n.Negotiate(c.Get(fiber.HeaderAcceptLanguage)))
+	if len(fieldErrors) == 0 {
+		return nil
+	}
+
+	fields := make([]string, len(fieldErrors))
+	for i, e := range fieldErrors {
+		fields[i] = e.Field + " " + e.Code
+	}
+	return errors.ValidationFailed("Validation failed: "+strings.Join(fields, ", "), fieldErrors)
+}
+
+// Struct returns the invalid fields of v with their messages in lang, none when v is valid.
+func Struct(v any, lang string) []errors.FieldError {
+	err := validate.Struct(v)
+	if err == nil {
+		return nil
+	}
+
+	validationErrors, ok := err.(validator.ValidationErrors)
+	if !ok {
+		// only returned for a nil or non struct v, a programming error
+		panic(fmt.Sprintf("validating %T: %v", v, err))
+	}
+
+	fieldErrors := make([]errors.FieldError, len(validationErrors))
+	for i, e := range validationErrors {
+		fieldErrors[i] = errors.FieldError{
+			Field:   fieldPath(e.Namespace()),
+			Code:    e.Tag(),
+			Message: message(lang, e),
+		}
+	}
+	return fieldErrors
+}
+
+// fieldPath drops the struct name of a namespace, "UserRegisterRequest.roles[0]" gives "roles[0]".
+func fieldPath(namespace string) string {
+	_, path, _ := strings.Cut(namespace, ".")
+	return path
+}
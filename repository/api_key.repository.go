@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/DiansSopandi/goride_be/models"
+)
+
+type ApiKeyRepository struct {
+	conn
+}
+
+const apiKeyColumns = `id, name, prefix, secret_hash, owner_user_id, scopes, rate_limit_per_minute,
+	expires_at, last_used_at, created_by, created_at, revoked_at`
+
+func NewApiKeyRepository(db, replica DBTX) *ApiKeyRepository {
+	return &ApiKeyRepository{conn{DB: db, Replica: replica}}
+}
+
+func scanApiKey(row interface{ Scan(...any) error }) (*models.ApiKey, error) {
+	var key models.ApiKey
+	err := row.Scan(
+		&key.ID,
+		&key.Name,
+		&key.Prefix,
+		&key.SecretHash,
+		&key.OwnerUserID,
+		&key.Scopes,
+		&key.RateLimitPerMinute,
+		&key.ExpiresAt,
+		&key.LastUsedAt,
+		&key.CreatedBy,
+		&key.CreatedAt,
+		&key.RevokedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *ApiKeyRepository) CreateApiKey(ctx context.Context, key *models.ApiKey) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `INSERT INTO api_keys (name, prefix, secret_hash, owner_user_id, scopes, rate_limit_per_minute, expires_at, created_by) 
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8) 
+	RETURNING id, created_at`
+
+	return r.DB.QueryRowContext(ctx, query,
+		key.Name,
+		key.Prefix,
+		key.SecretHash,
+		key.OwnerUserID,
+		key.Scopes,
+		key.RateLimitPerMinute,
+		key.ExpiresAt,
+		key.CreatedBy,
+	).Scan(&key.ID, &key.CreatedAt)
+}
+
+// GetApiKeyByPrefix returns nil when no key has the prefix, revoked and expired keys included.
+func (r *ApiKeyRepository) GetApiKeyByPrefix(ctx context.Context, prefix string) (*models.ApiKey, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE prefix = $1`
+
+	key, err := scanApiKey(r.DB.QueryRowContext(ctx, query, prefix))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return key, err
+}
+
+// GetApiKeys lists every key, or only the keys of ownerUserID when it is not zero.
+func (r *ApiKeyRepository) GetApiKeys(ctx context.Context, ownerUserID uint) ([]models.ApiKey, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + apiKeyColumns + `
+			  FROM api_keys 
+			  WHERE ($1 = 0 OR owner_user_id = $1)
+			  ORDER BY created_at DESC`
+
+	rows, err := r.reader().QueryContext(ctx, query, ownerUserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []models.ApiKey{}
+	for rows.Next() {
+		key, err := scanApiKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *key)
+	}
+
+	return keys, rows.Err()
+}
+
+// RevokeApiKey returns the revoked key, nil when it does not exist or is already revoked.
+func (r *ApiKeyRepository) RevokeApiKey(ctx context.Context, id uint) (*models.ApiKey, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `UPDATE api_keys SET revoked_at = NOW() 
+	WHERE id = $1 AND revoked_at IS NULL 
+	RETURNING ` + apiKeyColumns
+
+	key, err := scanApiKey(r.DB.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return key, err
+}
+
+func (r *ApiKeyRepository) TouchLastUsed(ctx context.Context, id uint) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `UPDATE api_keys SET last_used_at = NOW() WHERE id = $1`
+	_, err := r.DB.ExecContext(ctx, query, id)
+	return err
+}
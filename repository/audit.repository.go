@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/DiansSopandi/goride_be/dto"
+	"github.com/DiansSopandi/goride_be/models"
+)
+
+type AuditRepository struct {
+	conn
+}
+
+func NewAuditRepository(db, replica DBTX) *AuditRepository {
+	return &AuditRepository{conn{DB: db, Replica: replica}}
+}
+
+// CreateAuditEvent must run in the transaction of the audited change, so both commit or roll back together.
+func (r *AuditRepository) CreateAuditEvent(ctx context.Context, event *models.AuditEvent) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `INSERT INTO audit_events (actor_user_id, actor_type, action, target_type, target_id, before_data, after_data, ip_address, request_id) 
+	VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), NULLIF($9, '')) 
+	RETURNING id, created_at`
+
+	return r.DB.QueryRowContext(ctx, query,
+		event.ActorUserID,
+		event.ActorType,
+		event.Action,
+		event.TargetType,
+		event.TargetID,
+		nullableJSON(event.Before),
+		nullableJSON(event.After),
+		event.IPAddress,
+		event.RequestID,
+	).Scan(&event.ID, &event.CreatedAt)
+}
+
+func nullableJSON(data []byte) interface{} {
+	if len(data) == 0 {
+		return nil
+	}
+	return data
+}
+
+// GetAuditEvents lists events matching the filter, newest first.
+func (r *AuditRepository) GetAuditEvents(ctx context.Context, filter dto.AuditEventFilter) ([]models.AuditEvent, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var (
+		conditions []string
+		args       []interface{}
+	)
+	where := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.ActorUserID != 0 {
+		where("actor_user_id = $%d", filter.ActorUserID)
+	}
+	if filter.Action != "" {
+		where("action = $%d", filter.Action)
+	}
+	if filter.TargetType != "" {
+		where("target_type = $%d", filter.TargetType)
+	}
+	if filter.TargetID != "" {
+		where("target_id = $%d", filter.TargetID)
+	}
+	if filter.From != nil {
+		where("created_at >= $%d", *filter.From)
+	}
+	if filter.To != nil {
+		where("created_at < $%d", *filter.To)
+	}
+	if filter.BeforeID != 0 {
+		where("id < $%d", filter.BeforeID)
+	}
+
+	query := `SELECT id, actor_user_id, actor_type, action, target_type, target_id, before_data, after_data, 
+	COALESCE(ip_address, ''), COALESCE(request_id, ''), created_at 
+	FROM audit_events`
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, ` AND `)
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(` ORDER BY id DESC LIMIT $%d`, len(args))
+
+	rows, err := r.reader().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []models.AuditEvent{}
+	for rows.Next() {
+		var (
+			event       models.AuditEvent
+			actorUserID sql.NullInt64
+			before      []byte
+			after       []byte
+		)
+		err := rows.Scan(
+			&event.ID,
+			&actorUserID,
+			&event.ActorType,
+			&event.Action,
+			&event.TargetType,
+			&event.TargetID,
+			&before,
+			&after,
+			&event.IPAddress,
+			&event.RequestID,
+			&event.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if actorUserID.Valid {
+			id := uint(actorUserID.Int64)
+			event.ActorUserID = &id
+		}
+		event.Before = before
+		event.After = after
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
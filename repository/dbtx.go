@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// DBTX is satisfied by both *sql.DB and *sql.Tx, so a repository built on the pool and one built
+// on a transaction run the same queries. Only the context variants are exposed, every query is
+// bound to the context of the request.
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+var (
+	_ DBTX = (*sql.DB)(nil)
+	_ DBTX = (*sql.Tx)(nil)
+)
+
+// queryTimeout bounds a single repository call, 0 leaves only the deadline of the caller.
+var queryTimeout time.Duration
+
+// SetQueryTimeout sets the deadline of every repository call, see database.query_timeout.
+func SetQueryTimeout(timeout time.Duration) {
+	queryTimeout = timeout
+}
+
+// withQueryTimeout derives the context of one repository call, the caller must defer cancel once
+// the rows are read.
+func withQueryTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if queryTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, queryTimeout)
+}
+
+// IsQueryTimeout reports whether err comes from a query that ran out of time, Postgres answers a
+// cancelled statement with query_canceled instead of the context error.
+func IsQueryTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "57014"
+}
+
+// conn is embedded by every repository. DB runs the writes and the reads that must see them,
+// Replica the read-only listings that tolerate replication lag, see database.replica_dsn.
+type conn struct {
+	DB      DBTX
+	Replica DBTX
+}
+
+// reader returns the read replica, DB when none is configured or the repository runs in a transaction.
+func (c conn) reader() DBTX {
+	if c.Replica != nil {
+		return c.Replica
+	}
+	return c.DB
+}
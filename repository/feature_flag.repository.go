@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/DiansSopandi/goride_be/models"
+)
+
+type FeatureFlagRepository struct {
+	conn
+}
+
+const featureFlagColumns = `id, key, COALESCE(description, ''), enabled, rollout_percentage, roles, cities,
+	created_by, created_at, updated_at`
+
+func NewFeatureFlagRepository(db, replica DBTX) *FeatureFlagRepository {
+	return &FeatureFlagRepository{conn{DB: db, Replica: replica}}
+}
+
+func scanFeatureFlag(row interface{ Scan(...any) error }) (*models.FeatureFlag, error) {
+	var flag models.FeatureFlag
+	err := row.Scan(
+		&flag.ID,
+		&flag.Key,
+		&flag.Description,
+		&flag.Enabled,
+		&flag.RolloutPercentage,
+		&flag.Roles,
+		&flag.Cities,
+		&flag.CreatedBy,
+		&flag.CreatedAt,
+		&flag.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &flag, nil
+}
+
+func (r *FeatureFlagRepository) CreateFeatureFlag(ctx context.Context, flag *models.FeatureFlag) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `INSERT INTO feature_flags (key, description, enabled, rollout_percentage, roles, cities, created_by)
+	VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6, $7)
+	RETURNING id, created_at, updated_at`
+
+	return r.DB.QueryRowContext(ctx, query,
+		flag.Key,
+		flag.Description,
+		flag.Enabled,
+		flag.RolloutPercentage,
+		flag.Roles,
+		flag.Cities,
+		flag.CreatedBy,
+	).Scan(&flag.ID, &flag.CreatedAt, &flag.UpdatedAt)
+}
+
+// GetFeatureFlagByKey returns nil when no flag has the key.
+func (r *FeatureFlagRepository) GetFeatureFlagByKey(ctx context.Context, key string) (*models.FeatureFlag, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + featureFlagColumns + ` FROM feature_flags WHERE key = $1`
+
+	flag, err := scanFeatureFlag(r.DB.QueryRowContext(ctx, query, key))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return flag, err
+}
+
+// GetFeatureFlags lists every flag ordered by key. It reads the primary, flag changes must show up
+// right after the cache was invalidated.
+func (r *FeatureFlagRepository) GetFeatureFlags(ctx context.Context) ([]models.FeatureFlag, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + featureFlagColumns + ` FROM feature_flags ORDER BY key`
+
+	rows, err := r.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	flags := []models.FeatureFlag{}
+	for rows.Next() {
+		flag, err := scanFeatureFlag(rows)
+		if err != nil {
+			return nil, err
+		}
+		flags = append(flags, *flag)
+	}
+
+	return flags, rows.Err()
+}
+
+// UpdateFeatureFlag saves every field of flag but the key, it returns false when the flag does not exist.
+func (r *FeatureFlagRepository) UpdateFeatureFlag(ctx context.Context, flag *models.FeatureFlag) (bool, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `UPDATE feature_flags
+	SET description = NULLIF($2, ''), enabled = $3, rollout_percentage = $4, roles = $5, cities = $6, updated_at = NOW()
+	WHERE key = $1
+	RETURNING updated_at`
+
+	err := r.DB.QueryRowContext(ctx, query,
+		flag.Key,
+		flag.Description,
+		flag.Enabled,
+		flag.RolloutPercentage,
+		flag.Roles,
+		flag.Cities,
+	).Scan(&flag.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// DeleteFeatureFlag returns the deleted flag, nil when it does not exist.
+func (r *FeatureFlagRepository) DeleteFeatureFlag(ctx context.Context, key string) (*models.FeatureFlag, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `DELETE FROM feature_flags WHERE key = $1 RETURNING ` + featureFlagColumns
+
+	flag, err := scanFeatureFlag(r.DB.QueryRowContext(ctx, query, key))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return flag, err
+}
@@ -0,0 +1,274 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"time"
+
+	"github.com/DiansSopandi/goride_be/models"
+)
+
+type OutboxRepository struct {
+	conn
+}
+
+const outboxEventColumns = `id, event_id, event_type, aggregate_type, aggregate_id, payload, COALESCE(request_id, ''),
+	attempts, delivered_to, next_attempt_at, COALESCE(last_error, ''), published_at, created_at`
+
+const outboxDeadLetterColumns = `id, event_id, event_type, aggregate_type, aggregate_id, payload, COALESCE(request_id, ''),
+	attempts, delivered_to, COALESCE(last_error, ''), created_at, failed_at`
+
+func NewOutboxRepository(db, replica DBTX) *OutboxRepository {
+	return &OutboxRepository{conn{DB: db, Replica: replica}}
+}
+
+func scanOutboxEvent(row interface{ Scan(...any) error }) (*models.OutboxEvent, error) {
+	var (
+		event   models.OutboxEvent
+		payload []byte
+	)
+	err := row.Scan(
+		&event.ID,
+		&event.EventID,
+		&event.EventType,
+		&event.AggregateType,
+		&event.AggregateID,
+		&payload,
+		&event.RequestID,
+		&event.Attempts,
+		&event.DeliveredTo,
+		&event.NextAttemptAt,
+		&event.LastError,
+		&event.PublishedAt,
+		&event.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	event.Payload = payload
+	return &event, nil
+}
+
+func scanOutboxDeadLetter(row interface{ Scan(...any) error }) (*models.OutboxDeadLetter, error) {
+	var (
+		deadLetter models.OutboxDeadLetter
+		payload    []byte
+	)
+	err := row.Scan(
+		&deadLetter.ID,
+		&deadLetter.EventID,
+		&deadLetter.EventType,
+		&deadLetter.AggregateType,
+		&deadLetter.AggregateID,
+		&payload,
+		&deadLetter.RequestID,
+		&deadLetter.Attempts,
+		&deadLetter.DeliveredTo,
+		&deadLetter.LastError,
+		&deadLetter.CreatedAt,
+		&deadLetter.FailedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	deadLetter.Payload = payload
+	return &deadLetter, nil
+}
+
+// CreateOutboxEvent must run in the transaction of the change the event is about, so the event is only
+// published when the change is committed.
+func (r *OutboxRepository) CreateOutboxEvent(ctx context.Context, event *models.OutboxEvent) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `INSERT INTO outbox_events (event_id, event_type, aggregate_type, aggregate_id, payload, request_id)
+	VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''))
+	RETURNING id, next_attempt_at, created_at`
+
+	return r.DB.QueryRowContext(ctx, query,
+		event.EventID,
+		event.EventType,
+		event.AggregateType,
+		event.AggregateID,
+		event.Payload,
+		event.RequestID,
+	).Scan(&event.ID, &event.NextAttemptAt, &event.CreatedAt)
+}
+
+// ClaimOutboxEvents returns up to limit due events, oldest first, and counts the attempt. The claimed events
+// are not due again before lease, another relay skips them until then and picks them up when this one
+// stopped before marking them.
+func (r *OutboxRepository) ClaimOutboxEvents(ctx context.Context, limit int, lease time.Duration) ([]models.OutboxEvent, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `UPDATE outbox_events
+	SET attempts = attempts + 1, next_attempt_at = NOW() + $2 * INTERVAL '1 millisecond'
+	WHERE id IN (
+		SELECT id FROM outbox_events
+		WHERE published_at IS NULL AND next_attempt_at <= NOW()
+		ORDER BY next_attempt_at, id
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	)
+	RETURNING ` + outboxEventColumns
+
+	rows, err := r.DB.QueryContext(ctx, query, limit, lease.Milliseconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []models.OutboxEvent{}
+	for rows.Next() {
+		event, err := scanOutboxEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, *event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// RETURNING does not keep the order of the subquery
+	sort.Slice(events, func(i, j int) bool { return events[i].ID < events[j].ID })
+	return events, nil
+}
+
+func (r *OutboxRepository) MarkOutboxEventPublished(ctx context.Context, id uint64) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `UPDATE outbox_events SET published_at = NOW(), last_error = NULL WHERE id = $1`
+
+	_, err := r.DB.ExecContext(ctx, query, id)
+	return err
+}
+
+// MarkOutboxEventDelivered records that publisher took the event, a retry skips it.
+func (r *OutboxRepository) MarkOutboxEventDelivered(ctx context.Context, id uint64, publisher string) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `UPDATE outbox_events SET delivered_to = array_append(delivered_to, $2)
+	WHERE id = $1 AND NOT ($2 = ANY(delivered_to))`
+
+	_, err := r.DB.ExecContext(ctx, query, id, publisher)
+	return err
+}
+
+// RetryOutboxEvent makes the event due again after backoff.
+func (r *OutboxRepository) RetryOutboxEvent(ctx context.Context, id uint64, backoff time.Duration, lastError string) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `UPDATE outbox_events
+	SET next_attempt_at = NOW() + $2 * INTERVAL '1 millisecond', last_error = $3
+	WHERE id = $1`
+
+	_, err := r.DB.ExecContext(ctx, query, id, backoff.Milliseconds(), lastError)
+	return err
+}
+
+// MoveOutboxEventToDeadLetters moves the event to outbox_dead_letters in one statement.
+func (r *OutboxRepository) MoveOutboxEventToDeadLetters(ctx context.Context, id uint64, lastError string) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `WITH moved AS (
+		DELETE FROM outbox_events WHERE id = $1
+		RETURNING event_id, event_type, aggregate_type, aggregate_id, payload, request_id, attempts, delivered_to, created_at
+	)
+	INSERT INTO outbox_dead_letters (event_id, event_type, aggregate_type, aggregate_id, payload, request_id, attempts, delivered_to, last_error, created_at)
+	SELECT event_id, event_type, aggregate_type, aggregate_id, payload, request_id, attempts, delivered_to, $2, created_at FROM moved`
+
+	_, err := r.DB.ExecContext(ctx, query, id, lastError)
+	return err
+}
+
+// DeletePublishedOutboxEvents deletes the events published more than olderThan ago, it returns how many.
+func (r *OutboxRepository) DeletePublishedOutboxEvents(ctx context.Context, olderThan time.Duration) (int64, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `DELETE FROM outbox_events WHERE published_at < NOW() - $1 * INTERVAL '1 millisecond'`
+
+	res, err := r.DB.ExecContext(ctx, query, olderThan.Milliseconds())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// GetOutboxDeadLetters lists the dead letters, newest first, of eventType or of every type when empty.
+func (r *OutboxRepository) GetOutboxDeadLetters(ctx context.Context, eventType string, limit int) ([]models.OutboxDeadLetter, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + outboxDeadLetterColumns + ` FROM outbox_dead_letters
+	WHERE $1 = '' OR event_type = $1
+	ORDER BY failed_at DESC, id DESC
+	LIMIT $2`
+
+	rows, err := r.DB.QueryContext(ctx, query, eventType, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deadLetters := []models.OutboxDeadLetter{}
+	for rows.Next() {
+		deadLetter, err := scanOutboxDeadLetter(rows)
+		if err != nil {
+			return nil, err
+		}
+		deadLetters = append(deadLetters, *deadLetter)
+	}
+
+	return deadLetters, rows.Err()
+}
+
+// ReplayOutboxDeadLetter moves a dead letter back to outbox_events as a due event with no attempt,
+// it returns false when the dead letter does not exist. The event keeps its event_id and is only
+// published to the publishers it was not delivered to.
+func (r *OutboxRepository) ReplayOutboxDeadLetter(ctx context.Context, id uint64) (bool, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `WITH moved AS (
+		DELETE FROM outbox_dead_letters WHERE id = $1
+		RETURNING event_id, event_type, aggregate_type, aggregate_id, payload, request_id, delivered_to, created_at
+	)
+	INSERT INTO outbox_events (event_id, event_type, aggregate_type, aggregate_id, payload, request_id, delivered_to, created_at)
+	SELECT event_id, event_type, aggregate_type, aggregate_id, payload, request_id, delivered_to, created_at FROM moved
+	RETURNING id`
+
+	var eventID uint64
+	err := r.DB.QueryRowContext(ctx, query, id).Scan(&eventID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// ReplayOutboxDeadLetters replays the dead letters of eventType, or all of them when empty, it returns how many.
+func (r *OutboxRepository) ReplayOutboxDeadLetters(ctx context.Context, eventType string) (int64, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `WITH moved AS (
+		DELETE FROM outbox_dead_letters WHERE $1 = '' OR event_type = $1
+		RETURNING event_id, event_type, aggregate_type, aggregate_id, payload, request_id, delivered_to, created_at
+	), replayed AS (
+		INSERT INTO outbox_events (event_id, event_type, aggregate_type, aggregate_id, payload, request_id, delivered_to, created_at)
+		SELECT event_id, event_type, aggregate_type, aggregate_id, payload, request_id, delivered_to, created_at FROM moved
+		RETURNING id
+	)
+	SELECT COUNT(*) FROM replayed`
+
+	var count int64
+	err := r.DB.QueryRowContext(ctx, query, eventType).Scan(&count)
+	return count, err
+}
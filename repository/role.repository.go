@@ -1,37 +1,23 @@
 package repository
 
 import (
-	"database/sql"
+	"context"
 
-	"github.com/DiansSopandi/goride_be/db"
 	"github.com/DiansSopandi/goride_be/models"
 )
 
 type RoleRepository struct {
-	DB *sql.DB
-	TX *sql.Tx
+	conn
 }
 
-// func NewRoleRepository(withTransaction bool) (*RoleRepository, error) {
-func NewRoleRepository(tx *sql.Tx) (*RoleRepository, error) {
-	// dbConn := db.InitDatabase()
-	// var trx *sql.Tx
-
-	// if withTransaction {
-	// 	var err error
-	// 	trx, err = dbConn.Begin()
-	// 	if err != nil {
-	// 		return nil, fmt.Errorf("failed to begin transaction: %w", err)
-	// 	}
-	// }
-
-	return &RoleRepository{
-		DB: db.InitDatabase(),
-		TX: tx,
-	}, nil
+func NewRoleRepository(db, replica DBTX) *RoleRepository {
+	return &RoleRepository{conn{DB: db, Replica: replica}}
 }
 
-func (r *RoleRepository) GetAllRoles() ([]models.Role, error) {
+func (r *RoleRepository) GetAllRoles(ctx context.Context) ([]models.Role, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	var roles []models.Role
 
 	query := `SELECT id, name, description, created_at, updated_at 
@@ -39,7 +25,7 @@ func (r *RoleRepository) GetAllRoles() ([]models.Role, error) {
 			  WHERE deleted_at IS NULL
 			  ORDER BY id DESC`
 
-	rows, err := r.DB.Query(query)
+	rows, err := r.reader().QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -57,15 +43,21 @@ func (r *RoleRepository) GetAllRoles() ([]models.Role, error) {
 	return roles, nil
 }
 
-func (r *RoleRepository) CreateRoles(tx *sql.Tx, role *models.Role) (models.Role, error) {
+func (r *RoleRepository) CreateRoles(ctx context.Context, role *models.Role) (models.Role, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	query := `INSERT INTO roles (name, description) VALUES ($1, $2) 
 	RETURNING id, name, description, created_at, updated_at`
 
-	err := tx.QueryRow(query, role.Name, role.Description).Scan(&role.ID, &role.Name, &role.Description, &role.CreatedAt, &role.UpdatedAt)
+	err := r.DB.QueryRowContext(ctx, query, role.Name, role.Description).Scan(&role.ID, &role.Name, &role.Description, &role.CreatedAt, &role.UpdatedAt)
 	return *role, err
 }
 
-func (r *RoleRepository) GetRoleByUserID(userID int) ([]models.Role, error) {
+func (r *RoleRepository) GetRoleByUserID(ctx context.Context, userID int) ([]models.Role, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	var roles []models.Role
 
 	query := `SELECT r.id, r.name, r.description, r.created_at, r.updated_at 
@@ -73,7 +65,7 @@ func (r *RoleRepository) GetRoleByUserID(userID int) ([]models.Role, error) {
 			  JOIN user_roles ur ON r.id = ur.role_id 
 			  WHERE ur.user_id = $1 AND r.deleted_at IS NULL`
 
-	rows, err := r.DB.Query(query, userID)
+	rows, err := r.DB.QueryContext(ctx, query, userID)
 	if err != nil {
 		return nil, err
 	}
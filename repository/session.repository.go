@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/DiansSopandi/goride_be/models"
+)
+
+type SessionRepository struct {
+	conn
+}
+
+const sessionColumns = `id, user_id, COALESCE(refresh_token_hash, ''), COALESCE(device_name, ''), COALESCE(user_agent, ''),
+	COALESCE(ip_address, ''), created_at, last_seen_at, expires_at, revoked_at`
+
+func NewSessionRepository(db, replica DBTX) *SessionRepository {
+	return &SessionRepository{conn{DB: db, Replica: replica}}
+}
+
+func scanSession(row interface{ Scan(...any) error }) (*models.UserSession, error) {
+	var session models.UserSession
+	err := row.Scan(
+		&session.ID,
+		&session.UserID,
+		&session.RefreshTokenHash,
+		&session.DeviceName,
+		&session.UserAgent,
+		&session.IPAddress,
+		&session.CreatedAt,
+		&session.LastSeenAt,
+		&session.ExpiresAt,
+		&session.RevokedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *SessionRepository) CreateSession(ctx context.Context, session *models.UserSession) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `INSERT INTO user_sessions (id, user_id, refresh_token_hash, device_name, user_agent, ip_address, expires_at) 
+	VALUES ($1, $2, $3, $4, $5, $6, $7) 
+	RETURNING created_at, last_seen_at`
+
+	return r.DB.QueryRowContext(ctx, query,
+		session.ID,
+		session.UserID,
+		session.RefreshTokenHash,
+		session.DeviceName,
+		session.UserAgent,
+		session.IPAddress,
+		session.ExpiresAt,
+	).Scan(&session.CreatedAt, &session.LastSeenAt)
+}
+
+// GetActiveSessionsByUserID lists sessions that are neither revoked nor expired.
+func (r *SessionRepository) GetActiveSessionsByUserID(ctx context.Context, userID uint) ([]models.UserSession, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + sessionColumns + `
+			  FROM user_sessions 
+			  WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+			  ORDER BY last_seen_at DESC`
+
+	rows, err := r.reader().QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []models.UserSession{}
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, *session)
+	}
+
+	return sessions, rows.Err()
+}
+
+// RevokeSession revokes one session of the user, returning false when it does not exist or is already revoked.
+func (r *SessionRepository) RevokeSession(ctx context.Context, userID uint, sessionID string) (bool, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `UPDATE user_sessions SET revoked_at = NOW() 
+	WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL`
+
+	res, err := r.DB.ExecContext(ctx, query, sessionID, userID)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	return affected > 0, err
+}
+
+// IsSessionRevoked reports whether the session was revoked, a session that no longer exists counts as
+// revoked. Read on the primary, a replica lagging behind could miss a revocation.
+func (r *SessionRepository) IsSessionRevoked(ctx context.Context, sessionID string) (bool, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var revoked bool
+	query := `SELECT revoked_at IS NOT NULL FROM user_sessions WHERE id = $1`
+
+	err := r.DB.QueryRowContext(ctx, query, sessionID).Scan(&revoked)
+	if err == sql.ErrNoRows {
+		return true, nil
+	}
+	return revoked, err
+}
+
+// RevokeAllSessions revokes every active session of the user and returns their ids.
+func (r *SessionRepository) RevokeAllSessions(ctx context.Context, userID uint) ([]string, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `UPDATE user_sessions SET revoked_at = NOW() 
+	WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW() 
+	RETURNING id`
+
+	rows, err := r.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+func (r *SessionRepository) TouchLastSeen(ctx context.Context, sessionID string) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `UPDATE user_sessions SET last_seen_at = NOW() WHERE id = $1 AND revoked_at IS NULL`
+	_, err := r.DB.ExecContext(ctx, query, sessionID)
+	return err
+}
@@ -1,57 +1,34 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"strings"
 
-	"github.com/DiansSopandi/goride_be/db"
 	"github.com/DiansSopandi/goride_be/dto"
 	"github.com/DiansSopandi/goride_be/models"
 	"github.com/lib/pq"
 )
 
 type UserRepository struct {
-	DB *sql.DB
-	TX *sql.Tx
+	conn
 }
 
-// NewUserRepository creates a new UserRepository instance.
-// func NewUserRepository(tx *sql.Tx, withTransaction bool) (*UserRepository, error) {
-func NewUserRepository(tx *sql.Tx) (*UserRepository, error) {
-	// dbConn := db.InitDatabase()
-
-	// var trx *sql.Tx
-	// if withTransaction {
-	// 	var err error
-	// 	trx, err = dbConn.Begin()
-	// 	if err != nil {
-	// 		return nil, fmt.Errorf("failed to begin transaction: %w", err)
-	// 	}
-	// }
-
-	// isTx := tx != nil
-	// if isTx {
-	// 	trx = tx
-	// }
-
-	return &UserRepository{
-		DB: db.InitDatabase(),
-		TX: tx,
-	}, nil
+func NewUserRepository(db, replica DBTX) *UserRepository {
+	return &UserRepository{conn{DB: db, Replica: replica}}
 }
 
-func (r *UserRepository) BeginTransaction() (*sql.Tx, error) {
-	return r.DB.Begin()
-}
+func (r *UserRepository) GetUserByID(ctx context.Context, id int) (*models.User, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
 
-func (r *UserRepository) GetUserByID(id int) (*models.User, error) {
 	var user models.User
 
-	query := `SELECT id, username, email, password,  created_at, updated_at, deleted_at 
+	query := `SELECT id, username, email, COALESCE(password, ''),  created_at, updated_at, deleted_at 
 	FROM users WHERE id = $1`
 
-	err := r.DB.QueryRow(query, id).Scan(
+	err := r.DB.QueryRowContext(ctx, query, id).Scan(
 		&user.ID,
 		&user.Username,
 		&user.Email,
@@ -72,12 +49,15 @@ func (r *UserRepository) GetUserByID(id int) (*models.User, error) {
 	return &user, nil
 }
 
-func (r *UserRepository) GetUserByEmail(email string) (*models.User, error) {
-	query := `SELECT id, username, email, password,  created_at, updated_at, deleted_at 
+func (r *UserRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT id, username, email, COALESCE(password, ''),  created_at, updated_at, deleted_at 
 	FROM users WHERE email = $1`
 
 	var user models.User
-	err := r.DB.QueryRow(query, email).Scan(
+	err := r.DB.QueryRowContext(ctx, query, email).Scan(
 		&user.ID,
 		&user.Username,
 		&user.Email,
@@ -97,13 +77,16 @@ func (r *UserRepository) GetUserByEmail(email string) (*models.User, error) {
 	return &user, nil
 }
 
-func (r *UserRepository) CreateUser(tx *sql.Tx, user *models.User) (models.User, error) {
+func (r *UserRepository) CreateUser(ctx context.Context, user *models.User) (models.User, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	// query := `INSERT INTO users (username, email, password, avatar_url, avatar_name, first_name, last_name, phone, address, role) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
 	query := `INSERT INTO users (username, email, password, provider, provider_id, picture) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, username, email, created_at, updated_at`
 
 	// err := r.DB.QueryRow(query,
 	// err := tx.QueryRow(query,
-	err := tx.QueryRow(query,
+	err := r.DB.QueryRowContext(ctx, query,
 		user.Username,
 		user.Email,
 		user.Password,
@@ -114,11 +97,14 @@ func (r *UserRepository) CreateUser(tx *sql.Tx, user *models.User) (models.User,
 
 	return *user, err
 }
-func (r *UserRepository) UpdateUser(tx *sql.Tx, user *models.User) error {
+func (r *UserRepository) UpdateUser(ctx context.Context, user *models.User) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	// query := `UPDATE users SET username = $1, email = $2, password = $3, avatar_url = $4, avatar_name = $5, first_name = $6, last_name = $7, phone = $8, address = $9, role = $10, updated_at = NOW() WHERE id = $11`
 	query := `UPDATE users SET username = $1, email = $2, password = $3, picture = $4, updated_at = NOW() WHERE id = $5`
 
-	_, err := tx.Exec(query,
+	_, err := r.DB.ExecContext(ctx, query,
 		user.Username,
 		user.Email,
 		user.Password,
@@ -133,12 +119,18 @@ func (r *UserRepository) UpdateUser(tx *sql.Tx, user *models.User) error {
 	)
 	return err
 }
-func (r *UserRepository) DeleteUser(tx *sql.Tx, id int) error {
+func (r *UserRepository) DeleteUser(ctx context.Context, id int) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	query := `UPDATE users SET deleted_at = NOW() WHERE id = $1`
-	_, err := tx.Exec(query, id)
+	_, err := r.DB.ExecContext(ctx, query, id)
 	return err
 }
-func (r *UserRepository) GetAllUsers() ([]dto.UserResponse, error) {
+func (r *UserRepository) GetAllUsers(ctx context.Context) ([]dto.UserResponse, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	// query := `SELECT id, username, email, password, avatar_url, avatar_name, first_name, last_name, phone, address, role, created_at, updated_at, deleted_at FROM users WHERE deleted_at IS NULL`
 	query := `SELECT u.id,  u.email, COALESCE(ARRAY_AGG(r.name), '{}') as roles 
 	FROM users u
@@ -147,7 +139,7 @@ func (r *UserRepository) GetAllUsers() ([]dto.UserResponse, error) {
 	WHERE u.deleted_at IS NULL
 	GROUP BY u.id, u.username, u.email, u.created_at, u.updated_at `
 
-	rows, err := r.DB.Query(query)
+	rows, err := r.reader().QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -169,7 +161,6 @@ func (r *UserRepository) GetAllUsers() ([]dto.UserResponse, error) {
 			// &user.Address,
 		)
 		if err != nil {
-			fmt.Println(err)
 			return nil, err
 		}
 		user.Roles = roles
@@ -177,17 +168,23 @@ func (r *UserRepository) GetAllUsers() ([]dto.UserResponse, error) {
 	}
 	return users, nil
 }
-func (r *UserRepository) CountUsers() (int, error) {
+func (r *UserRepository) CountUsers(ctx context.Context) (int, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	query := `SELECT COUNT(*) FROM users WHERE deleted_at IS NULL`
 	var count int
-	err := r.DB.QueryRow(query).Scan(&count)
+	err := r.reader().QueryRowContext(ctx, query).Scan(&count)
 	if err != nil {
 		return 0, err
 	}
 	return count, nil
 }
 
-func (r *UserRepository) ValidateRolesExist(tx *sql.Tx, roleNames []string) ([]int64, error) {
+func (r *UserRepository) ValidateRolesExist(ctx context.Context, roleNames []string) ([]int64, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	if len(roleNames) == 0 {
 		return nil, fmt.Errorf("at least one role is required")
 	}
@@ -219,7 +216,7 @@ func (r *UserRepository) ValidateRolesExist(tx *sql.Tx, roleNames []string) ([]i
 		// AND is_active = true`,
 		strings.Join(placeholders, ","))
 
-	rows, err := tx.Query(query, args...)
+	rows, err := r.DB.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query roles: %w", err)
 	}
@@ -259,25 +256,34 @@ func (r *UserRepository) ValidateRolesExist(tx *sql.Tx, roleNames []string) ([]i
 	return roleIDs, nil
 }
 
-func (r *UserRepository) CheckUsernameExistsWithTx(tx *sql.Tx, username string) (bool, error) {
+func (r *UserRepository) CheckUsernameExists(ctx context.Context, username string) (bool, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	var count int
 	query := `SELECT COUNT(*) FROM users WHERE username = $1 AND deleted_at IS NULL`
 
 	// err := tx.QueryRow(query, username).Scan(&count)
-	err := tx.QueryRow(query, username).Scan(&count)
+	err := r.DB.QueryRowContext(ctx, query, username).Scan(&count)
 	return count > 0, err
 }
 
-func (r *UserRepository) CheckEmailExistsWithTx(tx *sql.Tx, email string) (bool, error) {
+func (r *UserRepository) CheckEmailExists(ctx context.Context, email string) (bool, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	var count int
 	query := `SELECT COUNT(*) FROM users WHERE email = $1 AND deleted_at IS NULL`
 
 	// err := tx.QueryRow(query, email).Scan(&count)
-	err := tx.QueryRow(query, email).Scan(&count)
+	err := r.DB.QueryRowContext(ctx, query, email).Scan(&count)
 	return count > 0, err
 }
 
-func (r *UserRepository) AssignRolesToUserWithTx(tx *sql.Tx, userID uint, roleIDs []int64) error {
+func (r *UserRepository) AssignRolesToUser(ctx context.Context, userID uint, roleIDs []int64) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	if len(roleIDs) == 0 {
 		return nil
 	}
@@ -298,21 +304,23 @@ func (r *UserRepository) AssignRolesToUserWithTx(tx *sql.Tx, userID uint, roleID
         ON CONFLICT (user_id, role_id) DO NOTHING`,
 		strings.Join(valueStrings, ","))
 
-	_, err := tx.Exec(query, args...)
+	_, err := r.DB.ExecContext(ctx, query, args...)
 	if err != nil {
-		fmt.Println("error assign to roles user", err)
 		return fmt.Errorf("failed to assign roles: %w", err)
 	}
 
 	return nil
 }
 
-func (r *UserRepository) FindByGoogleID(googleID string) (*models.User, error) {
+func (r *UserRepository) FindByGoogleID(ctx context.Context, googleID string) (*models.User, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	var user models.User
 	query := `SELECT id, username, email, provider, provider_id, picture
 			  FROM users 
 			  WHERE provider = 'google' AND provider_id = $1 AND deleted_at IS NULL`
-	err := r.DB.QueryRow(query, googleID).Scan(
+	err := r.DB.QueryRowContext(ctx, query, googleID).Scan(
 		&user.ID,
 		&user.Username,
 		&user.Email,
@@ -328,3 +336,13 @@ func (r *UserRepository) FindByGoogleID(googleID string) (*models.User, error) {
 	}
 	return &user, nil
 }
+
+// ClearPassword removes the local password once the local login method is unlinked.
+func (r *UserRepository) ClearPassword(ctx context.Context, userID uint) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `UPDATE users SET password = NULL, updated_at = NOW() WHERE id = $1`
+	_, err := r.DB.ExecContext(ctx, query, userID)
+	return err
+}
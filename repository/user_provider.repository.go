@@ -1,60 +1,169 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 
-	"github.com/DiansSopandi/goride_be/db"
 	"github.com/DiansSopandi/goride_be/models"
 )
 
 type UserProviderRepository struct {
-	DB *sql.DB
-	TX *sql.Tx
+	conn
 }
 
-func NewUserProviderRepository(tx *sql.Tx) (*UserProviderRepository, error) {
-	// dbConn := db.InitDatabase()
+const userProviderColumns = `id, user_id, provider, provider_id, COALESCE(provider_email, ''), provider_data,
+	COALESCE(is_active, true), last_login_at, created_at, updated_at`
 
-	// var trx *sql.Tx
-	// if withTransaction {
-	// 	var err error
-	// 	trx, err = dbConn.Begin()
-	// 	if err != nil {
-	// 		return nil, fmt.Errorf("failed to begin transaction: %w", err)
-	// 	}
-	// }
+func NewUserProviderRepository(db, replica DBTX) *UserProviderRepository {
+	return &UserProviderRepository{conn{DB: db, Replica: replica}}
+}
 
-	// isTx := tx != nil
-	// if isTx {
-	// 	trx = tx
-	// }
+func scanUserProvider(row interface{ Scan(...any) error }) (*models.UserProvider, error) {
+	var (
+		userProvider models.UserProvider
+		providerData []byte
+	)
 
-	return &UserProviderRepository{
-		DB: db.InitDatabase(),
-		TX: tx,
-	}, nil
+	err := row.Scan(
+		&userProvider.ID,
+		&userProvider.UserID,
+		&userProvider.Provider,
+		&userProvider.ProviderID,
+		&userProvider.ProviderEmail,
+		&providerData,
+		&userProvider.IsActive,
+		&userProvider.LastLoginAt,
+		&userProvider.CreatedAt,
+		&userProvider.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
 
+	userProvider.ProviderData = providerData
+	return &userProvider, nil
 }
 
-func (r *UserProviderRepository) BeginUserProviderTransaction() (*sql.Tx, error) {
-	return r.DB.Begin()
-}
+func (r *UserProviderRepository) GetUserByProviderID(ctx context.Context, providerID string) (*models.UserProvider, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
 
-func (r *UserProviderRepository) GetUserByProviderID(providerID string) (*models.UserProvider, error) {
 	var userProvider models.UserProvider
 
 	query := `SELECT id,user_id, provider,provider_id 
 			  FROM user_providers 
 			  WHERE provider_id = $1`
-	err := r.DB.QueryRow(query, providerID).Scan(&userProvider.ID, &userProvider.UserID, &userProvider.Provider, &userProvider.ProviderID)
+	err := r.DB.QueryRowContext(ctx, query, providerID).Scan(&userProvider.ID, &userProvider.UserID, &userProvider.Provider, &userProvider.ProviderID)
 	if err != nil {
 		return nil, err
 	}
 	return &userProvider, nil
 }
 
-func (r *UserProviderRepository) CreateUserProvider(tx *sql.Tx, userProvider *models.UserProvider) error {
-	query := "INSERT INTO user_providers (user_id, provider, provider_id, provider_email) VALUES ($1, $2, $3, $4)"
-	_, err := tx.Exec(query, userProvider.UserID, userProvider.Provider, userProvider.ProviderID, userProvider.ProviderEmail)
+func (r *UserProviderRepository) CreateUserProvider(ctx context.Context, userProvider *models.UserProvider) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `INSERT INTO user_providers (user_id, provider, provider_id, provider_email, provider_data, is_active, last_login_at) 
+	VALUES ($1, $2, $3, $4, $5, true, $6) 
+	RETURNING id, is_active, created_at, updated_at`
+
+	var providerData interface{}
+	if len(userProvider.ProviderData) > 0 {
+		providerData = []byte(userProvider.ProviderData)
+	}
+
+	return r.DB.QueryRowContext(ctx, query,
+		userProvider.UserID,
+		userProvider.Provider,
+		userProvider.ProviderID,
+		userProvider.ProviderEmail,
+		providerData,
+		userProvider.LastLoginAt,
+	).Scan(&userProvider.ID, &userProvider.IsActive, &userProvider.CreatedAt, &userProvider.UpdatedAt)
+}
+
+// GetUserProvider finds a linked identity by provider name and the subject id issued by that provider.
+func (r *UserProviderRepository) GetUserProvider(ctx context.Context, provider, providerID string) (*models.UserProvider, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + userProviderColumns + `
+			  FROM user_providers 
+			  WHERE provider = $1 AND provider_id = $2`
+
+	userProvider, err := scanUserProvider(r.DB.QueryRowContext(ctx, query, provider, providerID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return userProvider, nil
+}
+
+// GetUserProvidersByUserID lists every login method linked to a user.
+func (r *UserProviderRepository) GetUserProvidersByUserID(ctx context.Context, userID uint) ([]models.UserProvider, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + userProviderColumns + `
+			  FROM user_providers 
+			  WHERE user_id = $1
+			  ORDER BY created_at ASC`
+
+	rows, err := r.reader().QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	userProviders := []models.UserProvider{}
+	for rows.Next() {
+		userProvider, err := scanUserProvider(rows)
+		if err != nil {
+			return nil, err
+		}
+		userProviders = append(userProviders, *userProvider)
+	}
+
+	return userProviders, rows.Err()
+}
+
+// TouchLastLogin records a successful login, refreshing provider_data when the provider sent new claims.
+func (r *UserProviderRepository) TouchLastLogin(ctx context.Context, id uint, providerData []byte) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `UPDATE user_providers 
+	SET last_login_at = NOW(), provider_data = COALESCE($2, provider_data), updated_at = NOW() 
+	WHERE id = $1`
+
+	var data interface{}
+	if len(providerData) > 0 {
+		data = providerData
+	}
+
+	_, err := r.DB.ExecContext(ctx, query, id, data)
 	return err
 }
+
+// DeleteUserProviderUnlessLast unlinks a provider from a user unless no other active login method would
+// be left. The provider rows of the user are locked first, so concurrent unlinks of two methods cannot
+// both see the other one and remove every way to sign in. linked is false when the provider was not linked.
+func (r *UserProviderRepository) DeleteUserProviderUnlessLast(ctx context.Context, userID uint, provider string) (linked, deleted bool, err error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `WITH locked AS (
+		SELECT id, provider, COALESCE(is_active, true) AS active FROM user_providers WHERE user_id = $1 ORDER BY id FOR UPDATE
+	), removed AS (
+		DELETE FROM user_providers WHERE user_id = $1 AND provider = $2
+		AND EXISTS (SELECT 1 FROM locked WHERE provider <> $2 AND active)
+		RETURNING id
+	)
+	SELECT EXISTS (SELECT 1 FROM locked WHERE provider = $2), EXISTS (SELECT 1 FROM removed)`
+
+	err = r.DB.QueryRowContext(ctx, query, userID, provider).Scan(&linked, &deleted)
+	return linked, deleted, err
+}
@@ -2,25 +2,35 @@ package routes
 
 import (
 	"github.com/DiansSopandi/goride_be/http/handler/v1"
+	"github.com/DiansSopandi/goride_be/middlewares"
 	"github.com/DiansSopandi/goride_be/pkg"
 	"github.com/gofiber/fiber/v2"
 )
 
-func SetupRoutes(app *fiber.App) {
+func SetupRoutes(app *fiber.App, cfg pkg.Config, handlers *handler.Handlers, mw *middlewares.Middlewares) {
 	// appPath := pkg.GetEnv("APP_PATH")
-	appPath := pkg.Cfg.Application.AppPath
+	appPath := cfg.Application.AppPath
 	api := app.Group(appPath)
-	auth := api.Group("/auth")
+	// API keys act for their owner, never on the owner's sessions, providers or login
+	auth := api.Group("/auth", middlewares.DenyApiKeys())
 	health := api.Group("/health")
+	me := api.Group("/me", middlewares.DenyApiKeys())
+	admin := api.Group("/admin", mw.RequireRoles("admin"))
 
-	health.Get("/", func(c *fiber.Ctx) error {
-		return c.Status(200).JSON(fiber.Map{"ok": true})
-	})
-
+	handler.JwksRoutes(app)
+	handler.MetricsRoutes(app, mw)
 	handler.RootHandler(api)
-	handler.RolesRoutes(api)
-	handler.UserRoutes(api)
-	handler.AuthRoutes(auth)
+	handler.HealthRoutes(health, handlers.Health)
+	handler.RolesRoutes(api, handlers.Role)
+	handler.UserRoutes(api, handlers.User)
+	handler.AuthRoutes(auth, handlers.Auth)
+	handler.UserProviderRoutes(me, handlers.UserProvider)
+	handler.SessionRoutes(me, handlers.Session)
+	handler.FeatureFlagRoutes(me, handlers.FeatureFlag)
+	handler.ApiKeyRoutes(admin, handlers.ApiKey)
+	handler.AuditRoutes(admin, handlers.Audit)
+	handler.SettingsRoutes(admin, handlers.Settings)
+	handler.FeatureFlagAdminRoutes(admin, handlers.FeatureFlag)
 
 	// Route untuk favicon.ico
 	// app.Static("/favicon.ico", "./public/favicon.ico")
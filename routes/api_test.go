@@ -0,0 +1,58 @@
+package routes
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	handler "github.com/DiansSopandi/goride_be/http/handler/v1"
+	"github.com/DiansSopandi/goride_be/internal/redistest"
+	"github.com/DiansSopandi/goride_be/middlewares"
+	"github.com/DiansSopandi/goride_be/models"
+	"github.com/DiansSopandi/goride_be/pkg"
+	service "github.com/DiansSopandi/goride_be/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+// newApiKeyApp serves the routes to a caller authenticated with an API key granted every scope.
+func newApiKeyApp(t *testing.T) *fiber.App {
+	t.Helper()
+	redistest.Start(t)
+
+	var cfg pkg.Config
+	cfg.Application.AppPath = "/v1"
+	services := &service.Services{}
+	mw := middlewares.NewMiddlewares(cfg, nil, services)
+
+	app := fiber.New(fiber.Config{ErrorHandler: mw.ErrorHandler})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals(middlewares.ApiKeyContextKey, &models.ApiKey{ID: 1, Prefix: "test", OwnerUserID: 1, Scopes: []string{"*"}})
+		return c.Next()
+	})
+	SetupRoutes(app, cfg, handler.NewHandlers(cfg, nil, nil, services, mw), mw)
+	return app
+}
+
+func TestApiKeyDeniedOnPersonRoutes(t *testing.T) {
+	app := newApiKeyApp(t)
+
+	routes := []struct{ method, path string }{
+		{"GET", "/v1/me/sessions"},
+		{"DELETE", "/v1/me/sessions"},
+		{"DELETE", "/v1/me/sessions/abc"},
+		{"GET", "/v1/me/providers"},
+		{"POST", "/v1/me/providers/github/link"},
+		{"DELETE", "/v1/me/providers/github"},
+		{"GET", "/v1/me/flags"},
+		{"POST", "/v1/auth/logout"},
+		{"POST", "/v1/auth/login"},
+	}
+	for _, route := range routes {
+		res, err := app.Test(httptest.NewRequest(route.method, route.path, nil))
+		if err != nil {
+			t.Fatalf("%s %s: %v", route.method, route.path, err)
+		}
+		if res.StatusCode != fiber.StatusForbidden {
+			t.Errorf("%s %s: got status %d, want %d", route.method, route.path, res.StatusCode, fiber.StatusForbidden)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DiansSopandi/goride_be/dto"
+	"github.com/DiansSopandi/goride_be/errors"
+	"github.com/DiansSopandi/goride_be/models"
+	"github.com/DiansSopandi/goride_be/pkg/utils"
+	"github.com/DiansSopandi/goride_be/repository"
+)
+
+// ApiKeyScopes are the scopes an API key can be granted, "*" grants all of them.
+var ApiKeyScopes = []string{"*", "users:read", "users:write", "roles:read", "roles:write"}
+
+type ApiKeyService struct {
+	Repo         *repository.ApiKeyRepository
+	UserRepo     *repository.UserRepository
+	AuditService *AuditService
+}
+
+func NewApiKeyService(apiKeyRepo *repository.ApiKeyRepository, userRepo *repository.UserRepository, auditService *AuditService) *ApiKeyService {
+	return &ApiKeyService{
+		Repo:         apiKeyRepo,
+		UserRepo:     userRepo,
+		AuditService: auditService,
+	}
+}
+
+// CreateApiKey stores a new key and returns it in full, this is the only time the secret is available.
+func (s *ApiKeyService) CreateApiKey(ctx context.Context, actor dto.AuditActor, req dto.ApiKeyCreateRequest) (dto.ApiKeyCreateResponse, error) {
+	if err := validateApiKeyRequest(req); err != nil {
+		return dto.ApiKeyCreateResponse{}, err
+	}
+
+	ownerID := req.OwnerUserID
+	if ownerID == 0 {
+		ownerID = actor.UserID
+	}
+
+	if _, err := s.UserRepo.GetUserByID(ctx, int(ownerID)); err != nil {
+		if err == sql.ErrNoRows {
+			return dto.ApiKeyCreateResponse{}, errors.UserNotFound(fmt.Sprintf("api key owner %d not found", ownerID))
+		}
+		return dto.ApiKeyCreateResponse{}, errors.DatabaseError(fmt.Sprintf("failed to get owner %d: %v", ownerID, err)).WithCause(err)
+	}
+
+	rawKey, prefix, secretHash, err := utils.GenerateApiKey()
+	if err != nil {
+		return dto.ApiKeyCreateResponse{}, errors.InternalError(fmt.Sprintf("failed to generate api key: %v", err)).WithCause(err)
+	}
+
+	createdBy := actor.UserID
+	key := &models.ApiKey{
+		Name:               strings.TrimSpace(req.Name),
+		Prefix:             prefix,
+		SecretHash:         secretHash,
+		OwnerUserID:        ownerID,
+		Scopes:             req.Scopes,
+		RateLimitPerMinute: req.RateLimitPerMinute,
+		ExpiresAt:          req.ExpiresAt,
+		CreatedBy:          &createdBy,
+	}
+
+	if err := s.Repo.CreateApiKey(ctx, key); err != nil {
+		return dto.ApiKeyCreateResponse{}, errors.DatabaseError(fmt.Sprintf("failed to create api key: %v", err)).WithCause(err)
+	}
+
+	if err := s.AuditService.Record(ctx, actor, "api_key.create", "api_key", strconv.Itoa(int(key.ID)), nil, key); err != nil {
+		return dto.ApiKeyCreateResponse{}, err
+	}
+
+	return dto.ApiKeyCreateResponse{ApiKey: *key, Key: rawKey}, nil
+}
+
+// GetApiKeys lists keys without their secrets, filtered by owner when ownerUserID is not zero.
+func (s *ApiKeyService) GetApiKeys(ctx context.Context, ownerUserID uint) ([]models.ApiKey, error) {
+	keys, err := s.Repo.GetApiKeys(ctx, ownerUserID)
+	if err != nil {
+		return nil, errors.DatabaseError(fmt.Sprintf("failed to get api keys: %v", err)).WithCause(err)
+	}
+	return keys, nil
+}
+
+// RevokeApiKey disables a key immediately, requests using it are rejected from then on.
+func (s *ApiKeyService) RevokeApiKey(ctx context.Context, actor dto.AuditActor, id uint) error {
+	key, err := s.Repo.RevokeApiKey(ctx, id)
+	if err != nil {
+		return errors.DatabaseError(fmt.Sprintf("failed to revoke api key %d: %v", id, err)).WithCause(err)
+	}
+	if key == nil {
+		return errors.ResourceNotFound(fmt.Sprintf("active api key %d not found", id))
+	}
+
+	before := *key
+	before.RevokedAt = nil
+	return s.AuditService.Record(ctx, actor, "api_key.revoke", "api_key", strconv.Itoa(int(id)), before, key)
+}
+
+func validateApiKeyRequest(req dto.ApiKeyCreateRequest) error {
+	if strings.TrimSpace(req.Name) == "" || len(req.Name) > 100 {
+		return errors.InvalidInput("name is required and must be at most 100 characters")
+	}
+
+	if len(req.Scopes) == 0 {
+		return errors.InvalidInput("at least one scope is required")
+	}
+	for _, scope := range req.Scopes {
+		if !isKnownApiKeyScope(scope) {
+			return errors.InvalidInput(fmt.Sprintf("unknown scope %q, allowed: %s", scope, strings.Join(ApiKeyScopes, ", ")))
+		}
+	}
+
+	if req.RateLimitPerMinute != nil && *req.RateLimitPerMinute <= 0 {
+		return errors.InvalidInput("rate_limit_per_minute must be positive")
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return errors.InvalidInput("expires_at must be in the future")
+	}
+
+	return nil
+}
+
+func isKnownApiKeyScope(scope string) bool {
+	for _, s := range ApiKeyScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/DiansSopandi/goride_be/dto"
+	"github.com/DiansSopandi/goride_be/errors"
+	"github.com/DiansSopandi/goride_be/models"
+	"github.com/DiansSopandi/goride_be/repository"
+)
+
+const (
+	defaultAuditPageSize = 50
+	maxAuditPageSize     = 200
+)
+
+// auditRedactedFields never end up in an audit snapshot, whatever the json tags of the model say.
+var auditRedactedFields = []string{"password", "secret_hash", "refresh_token_hash", "access_token_hash", "key"}
+
+type AuditService struct {
+	Repo *repository.AuditRepository
+}
+
+func NewAuditService(auditRepo *repository.AuditRepository) *AuditService {
+	return &AuditService{
+		Repo: auditRepo,
+	}
+}
+
+// Record appends an audit event. Use the services of the request transaction (Services.WithTx),
+// so the event is committed or rolled back together with the change.
+// When both before and after are given only the fields that changed are stored.
+func (s *AuditService) Record(ctx context.Context, actor dto.AuditActor, action, targetType, targetID string, before, after interface{}) error {
+	beforeData, err := auditSnapshot(before)
+	if err != nil {
+		return errors.InternalError(fmt.Sprintf("failed to encode audit before data: %v", err)).WithCause(err)
+	}
+
+	afterData, err := auditSnapshot(after)
+	if err != nil {
+		return errors.InternalError(fmt.Sprintf("failed to encode audit after data: %v", err)).WithCause(err)
+	}
+
+	if beforeData != nil && afterData != nil {
+		beforeData, afterData = auditDiff(beforeData, afterData)
+	}
+
+	event := &models.AuditEvent{
+		ActorType:  actor.Type,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		IPAddress:  truncate(actor.IPAddress, 64),
+		RequestID:  truncate(actor.RequestID, 100),
+	}
+	if event.ActorType == "" {
+		event.ActorType = models.AuditActorUser
+	}
+	if actor.UserID != 0 {
+		userID := actor.UserID
+		event.ActorUserID = &userID
+	}
+	if event.Before, err = marshalAuditData(beforeData); err != nil {
+		return errors.InternalError(fmt.Sprintf("failed to encode audit before data: %v", err)).WithCause(err)
+	}
+	if event.After, err = marshalAuditData(afterData); err != nil {
+		return errors.InternalError(fmt.Sprintf("failed to encode audit after data: %v", err)).WithCause(err)
+	}
+
+	if err := s.Repo.CreateAuditEvent(ctx, event); err != nil {
+		return errors.InternalError(fmt.Sprintf("failed to record audit event %s: %v", action, err)).WithCause(err)
+	}
+	return nil
+}
+
+// GetAuditEvents returns one page of events, newest first. Pass NextBeforeID of the page as
+// before_id to get the next one.
+func (s *AuditService) GetAuditEvents(ctx context.Context, filter dto.AuditEventFilter) (dto.AuditEventPage, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultAuditPageSize
+	}
+	if filter.Limit > maxAuditPageSize {
+		filter.Limit = maxAuditPageSize
+	}
+	if filter.From != nil && filter.To != nil && filter.To.Before(*filter.From) {
+		return dto.AuditEventPage{}, errors.InvalidInput("to must be after from")
+	}
+
+	events, err := s.Repo.GetAuditEvents(ctx, filter)
+	if err != nil {
+		return dto.AuditEventPage{}, errors.DatabaseError(fmt.Sprintf("failed to get audit events: %v", err)).WithCause(err)
+	}
+
+	page := dto.AuditEventPage{Events: events}
+	if len(events) == filter.Limit {
+		page.NextBeforeID = events[len(events)-1].ID
+	}
+	return page, nil
+}
+
+// auditSnapshot converts a value to its JSON fields, without the redacted ones.
+func auditSnapshot(data interface{}) (map[string]interface{}, error) {
+	if data == nil {
+		return nil, nil
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := map[string]interface{}{}
+	if err := json.Unmarshal(raw, &snapshot); err != nil {
+		// not an object, keep the value as is
+		return map[string]interface{}{"value": data}, nil
+	}
+
+	for _, field := range auditRedactedFields {
+		delete(snapshot, field)
+	}
+	return snapshot, nil
+}
+
+// auditDiff drops the fields that are equal in both snapshots.
+func auditDiff(before, after map[string]interface{}) (map[string]interface{}, map[string]interface{}) {
+	changedBefore := map[string]interface{}{}
+	changedAfter := map[string]interface{}{}
+
+	for field, value := range before {
+		if other, ok := after[field]; !ok || !sameJSON(value, other) {
+			changedBefore[field] = value
+		}
+	}
+	for field, value := range after {
+		if other, ok := before[field]; !ok || !sameJSON(value, other) {
+			changedAfter[field] = value
+		}
+	}
+
+	return changedBefore, changedAfter
+}
+
+func sameJSON(a, b interface{}) bool {
+	rawA, errA := json.Marshal(a)
+	rawB, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(rawA, rawB)
+}
+
+func marshalAuditData(data map[string]interface{}) ([]byte, error) {
+	if data == nil {
+		return nil, nil
+	}
+	return json.Marshal(data)
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAuditSnapshot(t *testing.T) {
+	type user struct {
+		ID       int    `json:"id"`
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+
+	tests := []struct {
+		name string
+		data interface{}
+		want map[string]interface{}
+	}{
+		{name: "nil", data: nil, want: nil},
+		{name: "struct", data: user{ID: 7, Email: "rider@example.com"}, want: map[string]interface{}{"id": float64(7), "email": "rider@example.com"}},
+		{name: "password redacted", data: user{ID: 7, Password: "hash"}, want: map[string]interface{}{"id": float64(7), "email": ""}},
+		{
+			name: "every redacted field",
+			data: map[string]interface{}{"name": "partner", "secret_hash": "a", "refresh_token_hash": "b", "access_token_hash": "c", "key": "d"},
+			want: map[string]interface{}{"name": "partner"},
+		},
+		{name: "not an object", data: "user.deleted", want: map[string]interface{}{"value": "user.deleted"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := auditSnapshot(tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("auditSnapshot(%v) = %v, want %v", tt.data, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuditDiff(t *testing.T) {
+	tests := []struct {
+		name       string
+		before     map[string]interface{}
+		after      map[string]interface{}
+		wantBefore map[string]interface{}
+		wantAfter  map[string]interface{}
+	}{
+		{
+			name:       "unchanged",
+			before:     map[string]interface{}{"email": "a@example.com", "active": true},
+			after:      map[string]interface{}{"email": "a@example.com", "active": true},
+			wantBefore: map[string]interface{}{},
+			wantAfter:  map[string]interface{}{},
+		},
+		{
+			name:       "changed field",
+			before:     map[string]interface{}{"email": "a@example.com", "active": true},
+			after:      map[string]interface{}{"email": "b@example.com", "active": true},
+			wantBefore: map[string]interface{}{"email": "a@example.com"},
+			wantAfter:  map[string]interface{}{"email": "b@example.com"},
+		},
+		{
+			name:       "added and removed fields",
+			before:     map[string]interface{}{"id": float64(1), "old": "x"},
+			after:      map[string]interface{}{"id": float64(1), "new": "y"},
+			wantBefore: map[string]interface{}{"old": "x"},
+			wantAfter:  map[string]interface{}{"new": "y"},
+		},
+		{
+			name:       "nested values compared as json",
+			before:     map[string]interface{}{"scopes": []interface{}{"users:read"}, "meta": map[string]interface{}{"a": float64(1)}},
+			after:      map[string]interface{}{"scopes": []interface{}{"users:read", "users:write"}, "meta": map[string]interface{}{"a": float64(1)}},
+			wantBefore: map[string]interface{}{"scopes": []interface{}{"users:read"}},
+			wantAfter:  map[string]interface{}{"scopes": []interface{}{"users:read", "users:write"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotBefore, gotAfter := auditDiff(tt.before, tt.after)
+			if !reflect.DeepEqual(gotBefore, tt.wantBefore) || !reflect.DeepEqual(gotAfter, tt.wantAfter) {
+				t.Errorf("auditDiff() = %v, %v, want %v, %v", gotBefore, gotAfter, tt.wantBefore, tt.wantAfter)
+			}
+		})
+	}
+}
@@ -0,0 +1,236 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/DiansSopandi/goride_be/dto"
+	"github.com/DiansSopandi/goride_be/errors"
+	"github.com/DiansSopandi/goride_be/models"
+	"github.com/DiansSopandi/goride_be/pkg"
+	"github.com/DiansSopandi/goride_be/repository"
+)
+
+// featureFlagCacheTTL bounds how long a flag change can stay unseen when the invalidation failed.
+const featureFlagCacheTTL = time.Minute
+
+var featureFlagKeyPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]{0,99}$`)
+
+type FeatureFlagService struct {
+	Repo         *repository.FeatureFlagRepository
+	RoleRepo     *repository.RoleRepository
+	AuditService *AuditService
+}
+
+func NewFeatureFlagService(flagRepo *repository.FeatureFlagRepository, roleRepo *repository.RoleRepository, auditService *AuditService) *FeatureFlagService {
+	return &FeatureFlagService{
+		Repo:         flagRepo,
+		RoleRepo:     roleRepo,
+		AuditService: auditService,
+	}
+}
+
+func (s *FeatureFlagService) CreateFeatureFlag(ctx context.Context, actor dto.AuditActor, req dto.FeatureFlagCreateRequest) (*models.FeatureFlag, error) {
+	createdBy := actor.UserID
+	flag := &models.FeatureFlag{
+		Key:               strings.TrimSpace(req.Key),
+		Description:       strings.TrimSpace(req.Description),
+		Enabled:           req.Enabled,
+		RolloutPercentage: 100,
+		Roles:             trimValues(req.Roles),
+		Cities:            trimValues(req.Cities),
+		CreatedBy:         &createdBy,
+	}
+	if req.RolloutPercentage != nil {
+		flag.RolloutPercentage = *req.RolloutPercentage
+	}
+
+	if !featureFlagKeyPattern.MatchString(flag.Key) {
+		return nil, errors.InvalidInput("key must be 1 to 100 lowercase letters, digits, '.', '_' or '-', e.g. ride.scheduled_booking")
+	}
+	if err := s.validateFeatureFlag(ctx, flag); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.Repo.GetFeatureFlagByKey(ctx, flag.Key)
+	if err != nil {
+		return nil, errors.DatabaseError(fmt.Sprintf("failed to get feature flag %s: %v", flag.Key, err)).WithCause(err)
+	}
+	if existing != nil {
+		return nil, errors.ResourceConflict(fmt.Sprintf("feature flag %s already exists", flag.Key))
+	}
+
+	if err := s.Repo.CreateFeatureFlag(ctx, flag); err != nil {
+		return nil, errors.DatabaseError(fmt.Sprintf("failed to create feature flag %s: %v", flag.Key, err)).WithCause(err)
+	}
+
+	if err := s.AuditService.Record(ctx, actor, "feature_flag.create", "feature_flag", flag.Key, nil, flag); err != nil {
+		return nil, err
+	}
+	return flag, nil
+}
+
+// UpdateFeatureFlag applies the fields set in req, turning a flag off is the kill switch of a feature.
+func (s *FeatureFlagService) UpdateFeatureFlag(ctx context.Context, actor dto.AuditActor, key string, req dto.FeatureFlagUpdateRequest) (*models.FeatureFlag, error) {
+	flag, err := s.Repo.GetFeatureFlagByKey(ctx, key)
+	if err != nil {
+		return nil, errors.DatabaseError(fmt.Sprintf("failed to get feature flag %s: %v", key, err)).WithCause(err)
+	}
+	if flag == nil {
+		return nil, errors.ResourceNotFound(fmt.Sprintf("feature flag %s not found", key))
+	}
+
+	before := *flag
+	if req.Description != nil {
+		flag.Description = strings.TrimSpace(*req.Description)
+	}
+	if req.Enabled != nil {
+		flag.Enabled = *req.Enabled
+	}
+	if req.RolloutPercentage != nil {
+		flag.RolloutPercentage = *req.RolloutPercentage
+	}
+	if req.Roles != nil {
+		flag.Roles = trimValues(*req.Roles)
+	}
+	if req.Cities != nil {
+		flag.Cities = trimValues(*req.Cities)
+	}
+
+	if err := s.validateFeatureFlag(ctx, flag); err != nil {
+		return nil, err
+	}
+
+	updated, err := s.Repo.UpdateFeatureFlag(ctx, flag)
+	if err != nil {
+		return nil, errors.DatabaseError(fmt.Sprintf("failed to update feature flag %s: %v", key, err)).WithCause(err)
+	}
+	if !updated {
+		return nil, errors.ResourceNotFound(fmt.Sprintf("feature flag %s not found", key))
+	}
+
+	if err := s.AuditService.Record(ctx, actor, "feature_flag.update", "feature_flag", key, before, flag); err != nil {
+		return nil, err
+	}
+	return flag, nil
+}
+
+func (s *FeatureFlagService) DeleteFeatureFlag(ctx context.Context, actor dto.AuditActor, key string) error {
+	flag, err := s.Repo.DeleteFeatureFlag(ctx, key)
+	if err != nil {
+		return errors.DatabaseError(fmt.Sprintf("failed to delete feature flag %s: %v", key, err)).WithCause(err)
+	}
+	if flag == nil {
+		return errors.ResourceNotFound(fmt.Sprintf("feature flag %s not found", key))
+	}
+
+	return s.AuditService.Record(ctx, actor, "feature_flag.delete", "feature_flag", key, flag, nil)
+}
+
+// GetFeatureFlags lists the flags from Postgres, bypassing the cache.
+func (s *FeatureFlagService) GetFeatureFlags(ctx context.Context) ([]models.FeatureFlag, error) {
+	flags, err := s.Repo.GetFeatureFlags(ctx)
+	if err != nil {
+		return nil, errors.DatabaseError(fmt.Sprintf("failed to get feature flags: %v", err)).WithCause(err)
+	}
+	return flags, nil
+}
+
+// EvaluateFlags returns every flag by key, true when it is on for subject.
+func (s *FeatureFlagService) EvaluateFlags(ctx context.Context, subject dto.FlagSubject) (map[string]bool, error) {
+	flags, err := s.loadFlags(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]bool, len(flags))
+	for _, flag := range flags {
+		result[flag.Key] = flag.IsEnabledFor(subject.UserID, subject.Roles, subject.City)
+	}
+	return result, nil
+}
+
+// IsEnabled reports whether the flag is on for subject, an unknown flag is off.
+func (s *FeatureFlagService) IsEnabled(ctx context.Context, key string, subject dto.FlagSubject) (bool, error) {
+	flags, err := s.loadFlags(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	for _, flag := range flags {
+		if flag.Key == key {
+			return flag.IsEnabledFor(subject.UserID, subject.Roles, subject.City), nil
+		}
+	}
+	return false, nil
+}
+
+// loadFlags reads the flags from the Redis cache, filling it from Postgres on a miss.
+// An unavailable Redis only costs the query, flags keep being evaluated.
+func (s *FeatureFlagService) loadFlags(ctx context.Context) ([]models.FeatureFlag, error) {
+	var flags []models.FeatureFlag
+
+	data, err := pkg.CachedFeatureFlags(ctx)
+	if err != nil {
+		slog.Warn("feature flag cache unavailable", "error", err)
+	} else if data != nil {
+		err := json.Unmarshal(data, &flags)
+		if err == nil {
+			return flags, nil
+		}
+		slog.Warn("invalid feature flag cache entry, reloading", "error", err)
+	}
+
+	flags, err = s.Repo.GetFeatureFlags(ctx)
+	if err != nil {
+		return nil, errors.DatabaseError(fmt.Sprintf("failed to get feature flags: %v", err)).WithCause(err)
+	}
+
+	if data, err := json.Marshal(flags); err == nil {
+		if err := pkg.CacheFeatureFlags(ctx, data, featureFlagCacheTTL); err != nil {
+			slog.Warn("failed to cache feature flags", "error", err)
+		}
+	}
+	return flags, nil
+}
+
+func (s *FeatureFlagService) validateFeatureFlag(ctx context.Context, flag *models.FeatureFlag) error {
+	if len(flag.Description) > 1000 {
+		return errors.InvalidInput("description must be at most 1000 characters")
+	}
+	if flag.RolloutPercentage < 0 || flag.RolloutPercentage > 100 {
+		return errors.InvalidInput("rollout_percentage must be between 0 and 100")
+	}
+
+	if len(flag.Roles) == 0 {
+		return nil
+	}
+
+	roles, err := s.RoleRepo.GetAllRoles(ctx)
+	if err != nil {
+		return errors.DatabaseError(fmt.Sprintf("failed to get roles: %v", err)).WithCause(err)
+	}
+	for _, name := range flag.Roles {
+		if !slices.ContainsFunc(roles, func(role models.Role) bool { return role.Name == name }) {
+			return errors.InvalidInput(fmt.Sprintf("unknown role %q", name))
+		}
+	}
+	return nil
+}
+
+// trimValues drops the blank entries, the result is never nil so the column stays an empty array.
+func trimValues(values []string) []string {
+	trimmed := []string{}
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			trimmed = append(trimmed, value)
+		}
+	}
+	return trimmed
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/DiansSopandi/goride_be/pkg/events"
+	"github.com/DiansSopandi/goride_be/pkg/i18n"
+)
+
+// Notification is a message to a user, rendered in their language.
+type Notification struct {
+	To       string
+	Language string
+	Subject  string
+	Body     string
+}
+
+// NotificationService sends the notifications of the domain events. Send delivers them, no email or push
+// provider is wired yet and the default one logs the recipient and the subject.
+type NotificationService struct {
+	Send func(ctx context.Context, notification Notification) error
+}
+
+func NewNotificationService() *NotificationService {
+	return &NotificationService{
+		Send: logNotification,
+	}
+}
+
+// Subscribe registers the notifications on bus.
+func (s *NotificationService) Subscribe(bus *events.Bus) {
+	bus.Subscribe(events.UserRegistered, "notification.user_registered", s.onUserRegistered)
+}
+
+func (s *NotificationService) onUserRegistered(ctx context.Context, event events.Event) error {
+	var payload events.UserRegisteredPayload
+	if err := event.Decode(&payload); err != nil {
+		return err
+	}
+
+	subject, body := i18n.Notification(payload.Language, "user_registered", map[string]string{"name": payload.Username})
+	return s.Send(ctx, Notification{
+		To:       payload.Email,
+		Language: payload.Language,
+		Subject:  subject,
+		Body:     body,
+	})
+}
+
+func logNotification(ctx context.Context, notification Notification) error {
+	slog.Info("notification", "to", notification.To, "language", notification.Language, "subject", notification.Subject)
+	return nil
+}
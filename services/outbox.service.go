@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/DiansSopandi/goride_be/db"
+	"github.com/DiansSopandi/goride_be/errors"
+	"github.com/DiansSopandi/goride_be/models"
+	"github.com/DiansSopandi/goride_be/repository"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultDeadLetterPageSize = 50
+	maxDeadLetterPageSize     = 500
+)
+
+// outboxWakeup tells the relay of this process that an event was committed, so it does not wait for
+// the next poll.
+var outboxWakeup = make(chan struct{}, 1)
+
+type OutboxService struct {
+	Repo *repository.OutboxRepository
+}
+
+func NewOutboxService(outboxRepo *repository.OutboxRepository) *OutboxService {
+	return &OutboxService{
+		Repo: outboxRepo,
+	}
+}
+
+// Enqueue writes a domain event of the pkg/events types to the outbox. Use the services of the request
+// transaction (Services.WithTx), the event is only published once the change it is about is committed.
+func (s *OutboxService) Enqueue(ctx context.Context, requestID, eventType, aggregateType, aggregateID string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return errors.InternalError(fmt.Sprintf("failed to encode event %s: %v", eventType, err)).WithCause(err)
+	}
+
+	event := &models.OutboxEvent{
+		EventID:       uuid.NewString(),
+		EventType:     eventType,
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		Payload:       data,
+		RequestID:     truncate(requestID, 100),
+	}
+	if err := s.Repo.CreateOutboxEvent(ctx, event); err != nil {
+		return errors.InternalError(fmt.Sprintf("failed to enqueue event %s: %v", eventType, err)).WithCause(err)
+	}
+
+	db.AfterCommit(ctx, wakeOutboxRelay)
+	return nil
+}
+
+// GetDeadLetters lists the events the relay gave up on, newest first, of eventType or of every type when empty.
+func (s *OutboxService) GetDeadLetters(ctx context.Context, eventType string, limit int) ([]models.OutboxDeadLetter, error) {
+	if limit <= 0 {
+		limit = defaultDeadLetterPageSize
+	}
+	if limit > maxDeadLetterPageSize {
+		limit = maxDeadLetterPageSize
+	}
+
+	deadLetters, err := s.Repo.GetOutboxDeadLetters(ctx, eventType, limit)
+	if err != nil {
+		return nil, errors.InternalError(fmt.Sprintf("failed to get dead letters: %v", err)).WithCause(err)
+	}
+	return deadLetters, nil
+}
+
+// ReplayDeadLetter puts a dead letter back in the outbox, the relay publishes it again with a fresh
+// attempt count and the same event id.
+func (s *OutboxService) ReplayDeadLetter(ctx context.Context, id uint64) error {
+	replayed, err := s.Repo.ReplayOutboxDeadLetter(ctx, id)
+	if err != nil {
+		return errors.InternalError(fmt.Sprintf("failed to replay dead letter %d: %v", id, err)).WithCause(err)
+	}
+	if !replayed {
+		return errors.ResourceNotFound(fmt.Sprintf("dead letter %d not found", id))
+	}
+	return nil
+}
+
+// ReplayDeadLetters replays every dead letter of eventType, or all of them when empty, it returns how many.
+func (s *OutboxService) ReplayDeadLetters(ctx context.Context, eventType string) (int64, error) {
+	count, err := s.Repo.ReplayOutboxDeadLetters(ctx, eventType)
+	if err != nil {
+		return 0, errors.InternalError(fmt.Sprintf("failed to replay dead letters: %v", err)).WithCause(err)
+	}
+	return count, nil
+}
+
+func wakeOutboxRelay() {
+	select {
+	case outboxWakeup <- struct{}{}:
+	default:
+		// a wakeup is already pending
+	}
+}
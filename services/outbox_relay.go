@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/DiansSopandi/goride_be/models"
+	"github.com/DiansSopandi/goride_be/pkg/events"
+	"github.com/DiansSopandi/goride_be/repository"
+)
+
+const (
+	outboxPurgeInterval  = time.Hour
+	outboxPublishTimeout = 10 * time.Second
+	maxOutboxErrorLength = 1000
+)
+
+// OutboxRelayConfig tunes an OutboxRelay, see pkg.OutboxConfig. Lease must be longer than publishing a batch,
+// an event still being published when its lease expires can be published twice.
+type OutboxRelayConfig struct {
+	PollInterval    time.Duration
+	BatchSize       int
+	MaxAttempts     int
+	RetryBackoff    time.Duration
+	MaxRetryBackoff time.Duration
+	Lease           time.Duration
+	Retention       time.Duration
+}
+
+// OutboxRelay publishes the committed outbox events to its publishers, oldest first. The deliveries are
+// recorded per publisher and an event is marked published once every publisher took it. A failure retries
+// it to the publishers that did not take it yet, with a backoff doubled on each attempt, and the event goes
+// to the dead letters after MaxAttempts. Several relays may run on the same table.
+type OutboxRelay struct {
+	repo       *repository.OutboxRepository
+	publishers map[string]events.Publisher
+	names      []string // of publishers, sorted
+	cfg        OutboxRelayConfig
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewOutboxRelay publishes to publishers by name. The names are stored with the events they took,
+// renaming a publisher delivers the pending events to it again.
+func NewOutboxRelay(outboxRepo *repository.OutboxRepository, cfg OutboxRelayConfig, publishers map[string]events.Publisher) *OutboxRelay {
+	names := make([]string, 0, len(publishers))
+	for name := range publishers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	return &OutboxRelay{
+		repo:       outboxRepo,
+		publishers: publishers,
+		names:      names,
+		cfg:        cfg,
+	}
+}
+
+// Start publishes the due events in the background until Stop.
+func (r *OutboxRelay) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.done = make(chan struct{})
+	go r.run(ctx)
+}
+
+// Stop lets the batch being published finish, or until ctx is done. The claimed events it did not get to
+// are published again once their lease expired.
+func (r *OutboxRelay) Stop(ctx context.Context) error {
+	if r.cancel == nil {
+		return nil
+	}
+	r.cancel()
+
+	select {
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("outbox relay still publishing: %w", ctx.Err())
+	}
+}
+
+func (r *OutboxRelay) run(ctx context.Context) {
+	defer close(r.done)
+
+	poll := time.NewTicker(r.cfg.PollInterval)
+	defer poll.Stop()
+	purge := time.NewTicker(outboxPurgeInterval)
+	defer purge.Stop()
+
+	for {
+		r.relayDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-poll.C:
+		case <-outboxWakeup:
+		case <-purge.C:
+			r.purge(ctx)
+		}
+	}
+}
+
+// relayDue publishes batches until no event is due.
+func (r *OutboxRelay) relayDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		claimed, err := r.relayBatch(ctx)
+		if err != nil {
+			slog.Warn("outbox relay failed to claim events", "error", err)
+			return
+		}
+		if claimed < r.cfg.BatchSize {
+			return
+		}
+	}
+}
+
+func (r *OutboxRelay) relayBatch(ctx context.Context) (int, error) {
+	claimed, err := r.repo.ClaimOutboxEvents(ctx, r.cfg.BatchSize, r.cfg.Lease)
+	if err != nil {
+		return 0, err
+	}
+
+	// the events being published are finished on Stop, the rest of the batch waits for its lease
+	batchCtx := context.WithoutCancel(ctx)
+	for _, event := range claimed {
+		if ctx.Err() != nil {
+			break
+		}
+		r.relay(batchCtx, event)
+	}
+	return len(claimed), nil
+}
+
+func (r *OutboxRelay) relay(ctx context.Context, event models.OutboxEvent) {
+	err := r.publish(ctx, event)
+	if err == nil {
+		if err := r.repo.MarkOutboxEventPublished(ctx, event.ID); err != nil {
+			// published again once the lease expired
+			slog.Warn("failed to mark an outbox event published", "event_id", event.EventID, "error", err)
+		}
+		return
+	}
+
+	lastError := truncate(err.Error(), maxOutboxErrorLength)
+
+	if event.Attempts >= r.cfg.MaxAttempts {
+		slog.Error("outbox event moved to the dead letters", "event_id", event.EventID, "event_type", event.EventType, "attempts", event.Attempts, "error", err)
+		if err := r.repo.MoveOutboxEventToDeadLetters(ctx, event.ID, lastError); err != nil {
+			slog.Warn("failed to move an outbox event to the dead letters", "event_id", event.EventID, "error", err)
+		}
+		return
+	}
+
+	backoff := r.backoff(event.Attempts)
+	slog.Warn("outbox event delivery failed, retrying", "event_id", event.EventID, "event_type", event.EventType, "attempts", event.Attempts, "retry_in", backoff, "error", err)
+	if err := r.repo.RetryOutboxEvent(ctx, event.ID, backoff, lastError); err != nil {
+		slog.Warn("failed to schedule the retry of an outbox event", "event_id", event.EventID, "error", err)
+	}
+}
+
+// publish hands the event to every publisher it was not delivered to yet, also when one of them fails.
+// Each delivery is recorded right away, only the failed publishers get the event again on retry.
+func (r *OutboxRelay) publish(ctx context.Context, event models.OutboxEvent) error {
+	ctx, cancel := context.WithTimeout(ctx, outboxPublishTimeout)
+	defer cancel()
+
+	e := events.Event{
+		ID:            event.EventID,
+		Type:          event.EventType,
+		AggregateType: event.AggregateType,
+		AggregateID:   event.AggregateID,
+		Payload:       event.Payload,
+		RequestID:     event.RequestID,
+		OccurredAt:    event.CreatedAt,
+		Attempt:       event.Attempts,
+	}
+
+	var errs []error
+	for _, name := range r.names {
+		if slices.Contains(event.DeliveredTo, name) {
+			continue
+		}
+		if err := r.publishers[name].Publish(ctx, e); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		if err := r.repo.MarkOutboxEventDelivered(ctx, event.ID, name); err != nil {
+			// delivered again if another publisher fails
+			slog.Warn("failed to record an outbox event delivery", "event_id", event.EventID, "publisher", name, "error", err)
+		}
+	}
+	return stderrors.Join(errs...)
+}
+
+// backoff is RetryBackoff after the first attempt, doubled after each following one up to MaxRetryBackoff.
+func (r *OutboxRelay) backoff(attempts int) time.Duration {
+	backoff := r.cfg.RetryBackoff
+	for i := 1; i < attempts && backoff < r.cfg.MaxRetryBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, r.cfg.MaxRetryBackoff)
+}
+
+func (r *OutboxRelay) purge(ctx context.Context) {
+	if r.cfg.Retention <= 0 {
+		return
+	}
+	deleted, err := r.repo.DeletePublishedOutboxEvents(ctx, r.cfg.Retention)
+	if err != nil {
+		slog.Warn("failed to delete the published outbox events", "error", err)
+		return
+	}
+	if deleted > 0 {
+		slog.Info("deleted the published outbox events", "count", deleted, "retention", r.cfg.Retention)
+	}
+}
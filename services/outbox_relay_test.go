@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/DiansSopandi/goride_be/models"
+	"github.com/DiansSopandi/goride_be/pkg/events"
+	"github.com/DiansSopandi/goride_be/repository"
+)
+
+func TestOutboxRelayBackoff(t *testing.T) {
+	relay := NewOutboxRelay(nil, OutboxRelayConfig{RetryBackoff: 5 * time.Second, MaxRetryBackoff: time.Minute}, nil)
+
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 0, want: 5 * time.Second},
+		{attempts: 1, want: 5 * time.Second},
+		{attempts: 2, want: 10 * time.Second},
+		{attempts: 3, want: 20 * time.Second},
+		{attempts: 4, want: 40 * time.Second},
+		{attempts: 5, want: time.Minute},
+		{attempts: 1000, want: time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := relay.backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestOutboxRelayPublishesPerPublisher(t *testing.T) {
+	tests := []struct {
+		name          string
+		deliveredTo   []string
+		failing       string
+		wantPublished []string
+		wantRecorded  []string
+		wantErr       bool
+	}{
+		{
+			name:          "every publisher",
+			wantPublished: []string{"stream", "subscribers"},
+			wantRecorded:  []string{"stream", "subscribers"},
+		},
+		{
+			name:          "skips the publishers that took it",
+			deliveredTo:   []string{"subscribers"},
+			wantPublished: []string{"stream"},
+			wantRecorded:  []string{"stream"},
+		},
+		{
+			name:          "a failure does not stop the others",
+			failing:       "stream",
+			wantPublished: []string{"stream", "subscribers"},
+			wantRecorded:  []string{"subscribers"},
+			wantErr:       true,
+		},
+		{
+			name:        "delivered to all of them",
+			deliveredTo: []string{"stream", "subscribers"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var published []string
+			publishers := map[string]events.Publisher{}
+			for _, name := range []string{"subscribers", "stream"} {
+				publishers[name] = publisherFunc(func(context.Context, events.Event) error {
+					published = append(published, name)
+					if name == tt.failing {
+						return errors.New("unavailable")
+					}
+					return nil
+				})
+			}
+			db := &deliveryRecorder{}
+			relay := NewOutboxRelay(repository.NewOutboxRepository(db, nil), OutboxRelayConfig{}, publishers)
+
+			err := relay.publish(context.Background(), models.OutboxEvent{ID: 1, EventID: "evt-1", DeliveredTo: tt.deliveredTo})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if !slices.Equal(published, tt.wantPublished) {
+				t.Errorf("published to %v, want %v", published, tt.wantPublished)
+			}
+			if !slices.Equal(db.delivered, tt.wantRecorded) {
+				t.Errorf("recorded deliveries to %v, want %v", db.delivered, tt.wantRecorded)
+			}
+		})
+	}
+}
+
+type publisherFunc func(ctx context.Context, event events.Event) error
+
+func (f publisherFunc) Publish(ctx context.Context, event events.Event) error {
+	return f(ctx, event)
+}
+
+// deliveryRecorder keeps the publishers of the deliveries recorded with MarkOutboxEventDelivered.
+type deliveryRecorder struct {
+	delivered []string
+}
+
+func (r *deliveryRecorder) ExecContext(_ context.Context, _ string, args ...any) (sql.Result, error) {
+	r.delivered = append(r.delivered, args[1].(string))
+	return nil, nil
+}
+
+func (r *deliveryRecorder) QueryContext(context.Context, string, ...any) (*sql.Rows, error) {
+	return nil, errors.New("not supported")
+}
+
+func (r *deliveryRecorder) QueryRowContext(context.Context, string, ...any) *sql.Row {
+	return nil
+}
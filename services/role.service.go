@@ -1,9 +1,11 @@
 package service
 
 import (
-	"database/sql"
+	"context"
+	"time"
 
 	"github.com/DiansSopandi/goride_be/models"
+	"github.com/DiansSopandi/goride_be/pkg/cache"
 	"github.com/DiansSopandi/goride_be/repository"
 )
 
@@ -17,10 +19,22 @@ func NewRoleService(roleRepo *repository.RoleRepository) *RoleService {
 	}
 }
 
-func (s *RoleService) GetAllRoles() ([]models.Role, error) {
-	return s.Repo.GetAllRoles()
+// rolesCacheTTL bounds how long a role change made outside of CreateRoles takes to show up.
+const rolesCacheTTL = 10 * time.Minute
+
+// GetAllRoles lists the roles, cached under cache.RolesTag.
+func (s *RoleService) GetAllRoles(ctx context.Context) ([]models.Role, error) {
+	return cache.RememberJSON(ctx, "roles:all", rolesCacheTTL, []string{cache.RolesTag}, func() ([]models.Role, error) {
+		return s.Repo.GetAllRoles(ctx)
+	})
 }
 
-func (s *RoleService) CreateRoles(tx *sql.Tx, role *models.Role) (models.Role, error) {
-	return s.Repo.CreateRoles(tx, role)
+// CreateRoles adds a role, the cached roles are invalidated once it is committed.
+func (s *RoleService) CreateRoles(ctx context.Context, role *models.Role) (models.Role, error) {
+	res, err := s.Repo.CreateRoles(ctx, role)
+	if err != nil {
+		return models.Role{}, err
+	}
+	cache.InvalidateAfterCommit(ctx, cache.RolesTag)
+	return res, nil
 }
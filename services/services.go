@@ -0,0 +1,42 @@
+package service
+
+import (
+	"database/sql"
+
+	"github.com/DiansSopandi/goride_be/repository"
+)
+
+// Services wires every service and repository on one DBTX. The bootstrap container keeps the
+// instance built on the pool, WithTx builds the one of a request transaction. The read-only
+// listings of the repositories go to replica when it is not nil.
+type Services struct {
+	Users    *UserService
+	Roles    *RoleService
+	Sessions *SessionService
+	ApiKeys  *ApiKeyService
+	Audit    *AuditService
+	Flags    *FeatureFlagService
+	Outbox   *OutboxService
+}
+
+func NewServices(db, replica repository.DBTX) *Services {
+	userRepo := repository.NewUserRepository(db, replica)
+	roleRepo := repository.NewRoleRepository(db, replica)
+	userProviderRepo := repository.NewUserProviderRepository(db, replica)
+	auditService := NewAuditService(repository.NewAuditRepository(db, replica))
+
+	return &Services{
+		Users:    NewUserService(userRepo, roleRepo, userProviderRepo),
+		Roles:    NewRoleService(roleRepo),
+		Sessions: NewSessionService(repository.NewSessionRepository(db, replica)),
+		ApiKeys:  NewApiKeyService(repository.NewApiKeyRepository(db, replica), userRepo, auditService),
+		Audit:    auditService,
+		Flags:    NewFeatureFlagService(repository.NewFeatureFlagRepository(db, replica), roleRepo, auditService),
+		Outbox:   NewOutboxService(repository.NewOutboxRepository(db, replica)),
+	}
+}
+
+// WithTx returns the same services bound to tx, their reads stay in tx as well.
+func (s *Services) WithTx(tx *sql.Tx) *Services {
+	return NewServices(tx, nil)
+}
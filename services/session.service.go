@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+	"unicode/utf8"
+
+	"github.com/DiansSopandi/goride_be/db"
+	"github.com/DiansSopandi/goride_be/dto"
+	"github.com/DiansSopandi/goride_be/errors"
+	"github.com/DiansSopandi/goride_be/models"
+	"github.com/DiansSopandi/goride_be/pkg"
+	"github.com/DiansSopandi/goride_be/pkg/utils"
+	"github.com/DiansSopandi/goride_be/repository"
+	"github.com/google/uuid"
+)
+
+type SessionService struct {
+	Repo *repository.SessionRepository
+}
+
+func NewSessionService(sessionRepo *repository.SessionRepository) *SessionService {
+	return &SessionService{
+		Repo: sessionRepo,
+	}
+}
+
+// CreateSession records the device of a login and issues the tokens bound to it.
+func (s *SessionService) CreateSession(ctx context.Context, userID int, email string, meta dto.SessionMeta) (dto.SessionTokens, error) {
+	sessionID := uuid.NewString()
+
+	accessToken, refreshToken, err := utils.GenerateJWT(userID, email, sessionID)
+	if err != nil {
+		return dto.SessionTokens{}, errors.InternalError(fmt.Sprintf("failed to generate token: %v", err)).WithCause(err)
+	}
+
+	session := &models.UserSession{
+		ID:               sessionID,
+		UserID:           uint(userID),
+		RefreshTokenHash: utils.HashToken(refreshToken),
+		DeviceName:       truncate(meta.DeviceName, 255),
+		UserAgent:        meta.UserAgent,
+		IPAddress:        truncate(meta.IPAddress, 64),
+		ExpiresAt:        time.Now().Add(utils.RefreshTokenTTL),
+	}
+
+	if err := s.Repo.CreateSession(ctx, session); err != nil {
+		return dto.SessionTokens{}, errors.InternalError(fmt.Sprintf("failed to create session: %v", err)).WithCause(err)
+	}
+
+	return dto.SessionTokens{
+		SessionID:    sessionID,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+// GetSessions lists the active sessions of a user, flagging the one making the request.
+func (s *SessionService) GetSessions(ctx context.Context, userID uint, currentSessionID string) ([]dto.SessionResponse, error) {
+	sessions, err := s.Repo.GetActiveSessionsByUserID(ctx, userID)
+	if err != nil {
+		return nil, errors.InternalError(fmt.Sprintf("failed to get sessions: %v", err)).WithCause(err)
+	}
+
+	res := make([]dto.SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		res = append(res, dto.SessionResponse{
+			ID:         session.ID,
+			DeviceName: session.DeviceName,
+			UserAgent:  session.UserAgent,
+			IPAddress:  session.IPAddress,
+			CreatedAt:  session.CreatedAt,
+			LastSeenAt: session.LastSeenAt,
+			ExpiresAt:  session.ExpiresAt,
+			Current:    session.ID == currentSessionID,
+		})
+	}
+
+	return res, nil
+}
+
+// RevokeSession logs one device out, its access tokens are rejected right away through the denylist.
+func (s *SessionService) RevokeSession(ctx context.Context, userID uint, sessionID string) error {
+	if _, err := uuid.Parse(sessionID); err != nil {
+		return errors.ResourceNotFound(fmt.Sprintf("invalid session id %q", sessionID))
+	}
+
+	revoked, err := s.Repo.RevokeSession(ctx, userID, sessionID)
+	if err != nil {
+		return errors.InternalError(fmt.Sprintf("failed to revoke session: %v", err)).WithCause(err)
+	}
+
+	if !revoked {
+		return errors.ResourceNotFound(fmt.Sprintf("session %s not found for user %d", sessionID, userID))
+	}
+
+	denySessions(ctx, sessionID)
+	return nil
+}
+
+// RevokeAllSessions logs the user out everywhere and returns how many sessions were revoked.
+func (s *SessionService) RevokeAllSessions(ctx context.Context, userID uint) (int, error) {
+	ids, err := s.Repo.RevokeAllSessions(ctx, userID)
+	if err != nil {
+		return 0, errors.InternalError(fmt.Sprintf("failed to revoke sessions: %v", err)).WithCause(err)
+	}
+
+	denySessions(ctx, ids...)
+	return len(ids), nil
+}
+
+// denySessions puts the sessions on the denylist once the revocation is committed, a rolled back
+// revocation must not log the devices out.
+func denySessions(ctx context.Context, sessionIDs ...string) {
+	if len(sessionIDs) == 0 {
+		return
+	}
+	db.AfterCommit(ctx, func() {
+		if err := pkg.DenySessions(context.WithoutCancel(ctx), utils.AccessTokenTTL, sessionIDs...); err != nil {
+			// this instance checks postgres meanwhile, see pkg.SessionDenylistStale
+			slog.Error("failed to deny revoked sessions, other instances accept their access tokens until they expire", "session_ids", sessionIDs, "error", err)
+		}
+	})
+}
+
+// truncate keeps at most max bytes of value, cut on a rune boundary: a split character is invalid
+// UTF-8 and postgres rejects the whole row.
+func truncate(value string, max int) string {
+	if len(value) <= max {
+		return value
+	}
+	for max > 0 && !utf8.RuneStart(value[max]) {
+		max--
+	}
+	return value[:max]
+}
@@ -0,0 +1,35 @@
+package service
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		max   int
+		want  string
+	}{
+		{name: "shorter", value: "Chrome", max: 10, want: "Chrome"},
+		{name: "exact", value: "Chrome", max: 6, want: "Chrome"},
+		{name: "ascii", value: "Chrome on Linux", max: 6, want: "Chrome"},
+		{name: "multibyte boundary", value: "café crème", max: 5, want: "café"},
+		{name: "inside a multibyte rune", value: "café crème", max: 4, want: "caf"},
+		{name: "inside an emoji", value: "🚕🚕", max: 6, want: "🚕"},
+		{name: "zero", value: "🚕", max: 0, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := truncate(tt.value, tt.max)
+			if got != tt.want {
+				t.Errorf("truncate(%q, %d) = %q, want %q", tt.value, tt.max, got, tt.want)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("truncate(%q, %d) = %q is not valid UTF-8", tt.value, tt.max, got)
+			}
+		})
+	}
+}
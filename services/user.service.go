@@ -1,12 +1,16 @@
 package service
 
 import (
-	"database/sql"
+	"context"
+	"encoding/json"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/DiansSopandi/goride_be/dto"
 	"github.com/DiansSopandi/goride_be/errors"
 	"github.com/DiansSopandi/goride_be/models"
+	"github.com/DiansSopandi/goride_be/pkg/cache"
 	"github.com/DiansSopandi/goride_be/pkg/utils"
 	"github.com/DiansSopandi/goride_be/repository"
 )
@@ -25,20 +29,16 @@ func NewUserService(userRepo *repository.UserRepository, roleRepo *repository.Ro
 	}
 }
 
-func (s *UserService) BeginTransaction() (*sql.Tx, error) {
-	return s.UserRepo.BeginTransaction()
-}
-
-func (s *UserService) GetAllUsers() ([]dto.UserResponse, error) {
-	users, err := s.UserRepo.GetAllUsers()
+func (s *UserService) GetAllUsers(ctx context.Context) ([]dto.UserResponse, error) {
+	users, err := s.UserRepo.GetAllUsers(ctx)
 	if err != nil {
 		return nil, err
 	}
 	return users, nil
 }
 
-// func (s *UserService) CreateUser(tx *sql.Tx, user *models.User) (models.User, error) {
-func (s *UserService) CreateUser(tx *sql.Tx, createUserDto *dto.UserCreateRequest) (models.User, error) {
+// func (s *UserService) CreateUser(user *models.User) (models.User, error) {
+func (s *UserService) CreateUser(ctx context.Context, createUserDto *dto.UserCreateRequest) (models.User, error) {
 	password, _ := utils.HashPassword(createUserDto.Password)
 	user := models.User{
 		Username:   createUserDto.Username,
@@ -48,27 +48,27 @@ func (s *UserService) CreateUser(tx *sql.Tx, createUserDto *dto.UserCreateReques
 		ProviderID: nil,
 	}
 
-	exists, err := s.UserRepo.CheckUsernameExistsWithTx(tx, user.Username)
+	exists, err := s.UserRepo.CheckUsernameExists(ctx, user.Username)
 	if err != nil {
-		return models.User{}, errors.InternalError(fmt.Sprintf("failed to check username: %v", err))
+		return models.User{}, errors.InternalError(fmt.Sprintf("failed to check username: %v", err)).WithCause(err)
 	}
 
 	if exists {
 		return models.User{}, errors.UsernameAlreadyExists("username already exists")
 	}
 
-	exists, err = s.UserRepo.CheckEmailExistsWithTx(tx, user.Email)
+	exists, err = s.UserRepo.CheckEmailExists(ctx, user.Email)
 	if err != nil {
-		return models.User{}, errors.InternalError(fmt.Sprintf("failed to check email: %v", err))
+		return models.User{}, errors.InternalError(fmt.Sprintf("failed to check email: %v", err)).WithCause(err)
 	}
 
 	if exists {
 		return models.User{}, errors.EmailAlreadyExists("username already exists")
 	}
 
-	res, err := s.UserRepo.CreateUser(tx, &user)
+	res, err := s.UserRepo.CreateUser(ctx, &user)
 	if err != nil {
-		return models.User{}, errors.InternalError(fmt.Sprintf("failed to create user: %v", err))
+		return models.User{}, errors.InternalError(fmt.Sprintf("failed to create user: %v", err)).WithCause(err)
 	}
 
 	userProvider := &models.UserProvider{
@@ -78,16 +78,17 @@ func (s *UserService) CreateUser(tx *sql.Tx, createUserDto *dto.UserCreateReques
 		ProviderEmail: res.Email,
 	}
 
-	s.UserProviderRepo.CreateUserProvider(tx, userProvider)
+	s.UserProviderRepo.CreateUserProvider(ctx, userProvider)
+	cache.InvalidateAfterCommit(ctx, cache.UsersTag)
 	return res, nil
 }
 
-func (s *UserService) LoginUser(tx *sql.Tx, loginDto dto.UserLoginRequest) (dto.UserLoginResponse, error) {
+func (s *UserService) LoginUser(ctx context.Context, loginDto dto.UserLoginRequest) (dto.UserLoginResponse, error) {
 	var roleNames []string
 
-	user, errUser := s.UserRepo.GetUserByEmail(loginDto.Email)
+	user, errUser := s.UserRepo.GetUserByEmail(ctx, loginDto.Email)
 	if errUser != nil {
-		return dto.UserLoginResponse{}, errors.InternalError(fmt.Sprintf("failed to get user by email: %v", errUser))
+		return dto.UserLoginResponse{}, errors.InternalError(fmt.Sprintf("failed to get user by email: %v", errUser)).WithCause(errUser)
 	}
 
 	if user == nil {
@@ -98,9 +99,24 @@ func (s *UserService) LoginUser(tx *sql.Tx, loginDto dto.UserLoginRequest) (dto.
 		return dto.UserLoginResponse{}, errors.InvalidCredential("invalid email or password")
 	}
 
-	role, errRole := s.RoleRepo.GetRoleByUserID(int(user.ID))
+	localProvider, errProvider := s.UserProviderRepo.GetUserProvider(ctx, "local", fmt.Sprintf("%d", user.ID))
+	if errProvider != nil {
+		return dto.UserLoginResponse{}, errors.InternalError(fmt.Sprintf("failed to get local provider: %v", errProvider)).WithCause(errProvider)
+	}
+
+	if localProvider != nil {
+		if !localProvider.IsActive {
+			return dto.UserLoginResponse{}, errors.ProviderDisabled(fmt.Sprintf("local login disabled for user %d", user.ID))
+		}
+
+		if err := s.UserProviderRepo.TouchLastLogin(ctx, localProvider.ID, nil); err != nil {
+			return dto.UserLoginResponse{}, errors.InternalError(fmt.Sprintf("failed to update last login: %v", err)).WithCause(err)
+		}
+	}
+
+	role, errRole := s.RoleRepo.GetRoleByUserID(ctx, int(user.ID))
 	if errRole != nil {
-		return dto.UserLoginResponse{}, errors.InternalError(fmt.Sprintf("failed to get role by user id: %v", errRole))
+		return dto.UserLoginResponse{}, errors.InternalError(fmt.Sprintf("failed to get role by user id: %v", errRole)).WithCause(errRole)
 	}
 
 	if role == nil {
@@ -111,11 +127,6 @@ func (s *UserService) LoginUser(tx *sql.Tx, loginDto dto.UserLoginRequest) (dto.
 		roleNames = append(roleNames, r.Name)
 	}
 
-	accessToken, refreshToken, err := utils.GenerateJWT(user.ID, user.Email)
-	if err != nil {
-		return dto.UserLoginResponse{}, errors.InternalError(fmt.Sprintf("failed to generate token: %v", err))
-	}
-
 	// userMap := map[string]interface{}{}
 	// userBytes, _ := json.Marshal(user)
 	// json.Unmarshal(userBytes, &userMap)
@@ -128,94 +139,216 @@ func (s *UserService) LoginUser(tx *sql.Tx, loginDto dto.UserLoginRequest) (dto.
 		Roles:    roleNames,
 	}
 
+	// tokens are issued by SessionService.CreateSession once the device session is recorded
 	return dto.UserLoginResponse{
-		User:         userResponse,
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
+		User: userResponse,
 	}, nil
 }
 
-func (s *UserService) UpdateUser(tx *sql.Tx, user *models.User) error {
-	err := s.UserRepo.UpdateUser(tx, user)
+func (s *UserService) UpdateUser(ctx context.Context, user *models.User) error {
+	err := s.UserRepo.UpdateUser(ctx, user)
 	if err != nil {
 		return err
 	}
+	cache.InvalidateAfterCommit(ctx, cache.UsersTag, cache.UserTag(uint(user.ID)))
 	return nil
 }
 
-func (s *UserService) GetUserByEmail(email string) (*models.User, error) {
-	user, err := s.UserRepo.GetUserByEmail(email)
+func (s *UserService) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	user, err := s.UserRepo.GetUserByEmail(ctx, email)
 	if err != nil {
 		return nil, err
 	}
 	return user, nil
 }
 
-func (s *UserService) GetUserByID(id int) (*models.User, error) {
-	user, err := s.UserRepo.GetUserByID(id)
+func (s *UserService) GetUserByID(ctx context.Context, id int) (*models.User, error) {
+	user, err := s.UserRepo.GetUserByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 	return user, nil
 }
 
-func (s *UserService) ValidateRolesExist(tx *sql.Tx, roleNames []string) ([]int64, error) {
-	return s.UserRepo.ValidateRolesExist(tx, roleNames)
+func (s *UserService) ValidateRolesExist(ctx context.Context, roleNames []string) ([]int64, error) {
+	return s.UserRepo.ValidateRolesExist(ctx, roleNames)
+}
+
+func (s *UserService) AssignRolesToUser(ctx context.Context, userID uint, roleIDs []int64) error {
+	if err := s.UserRepo.AssignRolesToUser(ctx, userID, roleIDs); err != nil {
+		return err
+	}
+	cache.InvalidateAfterCommit(ctx, cache.UsersTag, cache.UserTag(userID))
+	return nil
 }
 
-func (s *UserService) AssignRolesToUserWithTx(tx *sql.Tx, userID uint, roleIDs []int64) error {
-	return s.UserRepo.AssignRolesToUserWithTx(tx, userID, roleIDs)
+// UpsertGoogleUser is kept for callers of the former google-only login.
+func (s *UserService) UpsertGoogleUser(ctx context.Context, googleID, email, name, picture string) (*models.User, error) {
+	return s.UpsertOAuthUser(ctx, dto.OAuthUserInfo{
+		Provider: "google",
+		Subject:  googleID,
+		Email:    email,
+		Name:     name,
+		Picture:  picture,
+	})
 }
 
-func (s *UserService) UpsertGoogleUser(tx *sql.Tx, googleID, email, name, picture string) (*models.User, error) {
-	user, errUser := s.UserRepo.GetUserByEmail(email)
-	if errUser != nil && errUser != sql.ErrNoRows {
-		return nil, errUser
-	}
+// UpsertOAuthUser resolves the local user of an OAuth/OIDC identity, creating the user
+// and the user_providers link when needed. It works the same for every provider.
+// An identity whose email matches an existing account is never attached silently,
+// the owner has to link it explicitly from an authenticated session (see LinkOAuthProvider).
+// A new account is only created for a verified email, anyone could claim an unverified address.
+func (s *UserService) UpsertOAuthUser(ctx context.Context, info dto.OAuthUserInfo) (*models.User, error) {
+	providerData := marshalProviderData(info)
 
-	userProvider, errUserProvider := s.UserProviderRepo.GetUserByProviderID(googleID)
-	if errUserProvider != nil && errUserProvider != sql.ErrNoRows {
-		return nil, errUserProvider
+	userProvider, err := s.UserProviderRepo.GetUserProvider(ctx, info.Provider, info.Subject)
+	if err != nil {
+		return nil, errors.InternalError(fmt.Sprintf("failed to get user provider: %v", err)).WithCause(err)
 	}
 
-	if user != nil {
-		if userProvider == nil {
-			userProvider = &models.UserProvider{
-				UserID:        uint(user.ID),
-				Provider:      "google",
-				ProviderID:    googleID,
-				ProviderEmail: email,
-			}
-
-			if errCreateUserProvider := s.UserProviderRepo.CreateUserProvider(tx, userProvider); errCreateUserProvider != nil {
-				return nil, errCreateUserProvider
-			}
+	if userProvider != nil {
+		if !userProvider.IsActive {
+			return nil, errors.ProviderDisabled(fmt.Sprintf("%s identity %s is disabled", info.Provider, info.Subject))
 		}
-	}
 
-	if user == nil {
-		user = &models.User{
-			Email:    email,
-			Username: name,
-			Picture:  picture,
+		if err := s.UserProviderRepo.TouchLastLogin(ctx, userProvider.ID, providerData); err != nil {
+			return nil, errors.InternalError(fmt.Sprintf("failed to update last login: %v", err)).WithCause(err)
 		}
 
-		user, err := s.UserRepo.CreateUser(tx, user)
+		user, err := s.UserRepo.GetUserByID(ctx, int(userProvider.UserID))
 		if err != nil {
-			return nil, err
+			return nil, errors.InternalError(fmt.Sprintf("failed to get user by id: %v", err)).WithCause(err)
 		}
+		return user, nil
+	}
+
+	if !info.EmailVerified {
+		return nil, errors.EmailNotVerified(fmt.Sprintf("%s identity %s has the unverified email %s", info.Provider, info.Subject, info.Email))
+	}
+
+	existing, err := s.UserRepo.GetUserByEmail(ctx, info.Email)
+	if err != nil {
+		return nil, errors.InternalError(fmt.Sprintf("failed to get user by email: %v", err)).WithCause(err)
+	}
+
+	if existing != nil {
+		return nil, errors.AccountLinkRequired(fmt.Sprintf("%s identity %s matches existing user %d by email", info.Provider, info.Subject, existing.ID))
+	}
+
+	username := info.Name
+	if username == "" {
+		username = strings.Split(info.Email, "@")[0]
+	}
+
+	subject := info.Subject
+	user := &models.User{
+		Email:      info.Email,
+		Username:   username,
+		Picture:    info.Picture,
+		Provider:   info.Provider,
+		ProviderID: &subject,
+	}
+
+	if _, err := s.UserRepo.CreateUser(ctx, user); err != nil {
+		return nil, errors.InternalError(fmt.Sprintf("failed to create user: %v", err)).WithCause(err)
+	}
+
+	if _, err := s.createOAuthUserProvider(ctx, uint(user.ID), info, providerData); err != nil {
+		return nil, err
+	}
+	cache.InvalidateAfterCommit(ctx, cache.UsersTag)
 
-		userProvider = &models.UserProvider{
-			UserID:        uint(user.ID),
-			Provider:      "google",
-			ProviderID:    googleID,
-			ProviderEmail: email,
+	return user, nil
+}
+
+// LinkOAuthProvider attaches an OAuth/OIDC identity to an already authenticated user.
+func (s *UserService) LinkOAuthProvider(ctx context.Context, userID uint, info dto.OAuthUserInfo) (*models.UserProvider, error) {
+	userProvider, err := s.UserProviderRepo.GetUserProvider(ctx, info.Provider, info.Subject)
+	if err != nil {
+		return nil, errors.InternalError(fmt.Sprintf("failed to get user provider: %v", err)).WithCause(err)
+	}
+
+	if userProvider != nil {
+		if userProvider.UserID == userID {
+			return userProvider, nil
 		}
+		return nil, errors.ProviderAlreadyLinked(fmt.Sprintf("%s identity %s belongs to user %d", info.Provider, info.Subject, userProvider.UserID))
+	}
+
+	linked, err := s.UserProviderRepo.GetUserProvidersByUserID(ctx, userID)
+	if err != nil {
+		return nil, errors.InternalError(fmt.Sprintf("failed to get user providers: %v", err)).WithCause(err)
+	}
 
-		if errCreateUserProvider := s.UserProviderRepo.CreateUserProvider(tx, userProvider); errCreateUserProvider != nil {
-			return nil, errCreateUserProvider
+	for _, p := range linked {
+		if p.Provider == info.Provider {
+			return nil, errors.ProviderAlreadyLinked(fmt.Sprintf("user %d already has a %s identity", userID, info.Provider))
 		}
 	}
 
-	return user, nil
+	providerData := marshalProviderData(info)
+	return s.createOAuthUserProvider(ctx, userID, info, providerData)
+}
+
+// GetUserProviders lists the login methods linked to a user.
+func (s *UserService) GetUserProviders(ctx context.Context, userID uint) ([]models.UserProvider, error) {
+	userProviders, err := s.UserProviderRepo.GetUserProvidersByUserID(ctx, userID)
+	if err != nil {
+		return nil, errors.InternalError(fmt.Sprintf("failed to get user providers: %v", err)).WithCause(err)
+	}
+	return userProviders, nil
+}
+
+// UnlinkProvider removes a login method, refusing to remove the last active one.
+// Unlinking "local" also clears the password so it can no longer be used.
+func (s *UserService) UnlinkProvider(ctx context.Context, userID uint, provider string) error {
+	provider = strings.ToLower(provider)
+
+	linked, deleted, err := s.UserProviderRepo.DeleteUserProviderUnlessLast(ctx, userID, provider)
+	if err != nil {
+		return errors.InternalError(fmt.Sprintf("failed to unlink provider: %v", err)).WithCause(err)
+	}
+
+	if !linked {
+		return errors.ProviderNotLinked(fmt.Sprintf("user %d has no %s identity", userID, provider))
+	}
+
+	if !deleted {
+		return errors.LastLoginMethod(fmt.Sprintf("user %d tried to unlink %s, the last login method", userID, provider))
+	}
+
+	if provider == "local" {
+		if err := s.UserRepo.ClearPassword(ctx, userID); err != nil {
+			return errors.InternalError(fmt.Sprintf("failed to clear password: %v", err)).WithCause(err)
+		}
+	}
+	cache.InvalidateAfterCommit(ctx, cache.UserTag(userID))
+
+	return nil
+}
+
+func (s *UserService) createOAuthUserProvider(ctx context.Context, userID uint, info dto.OAuthUserInfo, providerData []byte) (*models.UserProvider, error) {
+	now := time.Now()
+	userProvider := &models.UserProvider{
+		UserID:        userID,
+		Provider:      info.Provider,
+		ProviderID:    info.Subject,
+		ProviderEmail: info.Email,
+		ProviderData:  providerData,
+		LastLoginAt:   &now,
+	}
+
+	if err := s.UserProviderRepo.CreateUserProvider(ctx, userProvider); err != nil {
+		return nil, errors.InternalError(fmt.Sprintf("failed to create user provider: %v", err)).WithCause(err)
+	}
+	cache.InvalidateAfterCommit(ctx, cache.UserTag(userID))
+	return userProvider, nil
+}
+
+func marshalProviderData(info dto.OAuthUserInfo) []byte {
+	if len(info.Raw) == 0 {
+		return nil
+	}
+	data, _ := json.Marshal(info.Raw)
+	return data
 }